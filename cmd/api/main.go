@@ -12,8 +12,14 @@ import (
 	"syscall"
 
 	"github.com/spf13/viper"
+	"github.com/vbetsun/todo-app/internal/blob"
+	"github.com/vbetsun/todo-app/internal/mailer"
+	"github.com/vbetsun/todo-app/internal/oidc"
+	"github.com/vbetsun/todo-app/internal/ratelimit"
 	"github.com/vbetsun/todo-app/internal/service"
 	"github.com/vbetsun/todo-app/internal/storage/psql"
+	"github.com/vbetsun/todo-app/internal/transport/caldav"
+	"github.com/vbetsun/todo-app/internal/transport/graphql"
 	"github.com/vbetsun/todo-app/internal/transport/rest"
 	"github.com/vbetsun/todo-app/internal/transport/rest/handler"
 	"go.uber.org/zap"
@@ -45,17 +51,133 @@ func main() {
 		logger.Fatal(fmt.Sprintf("can't connect to the DB %v", err))
 	}
 	store := psql.NewStorage(db)
+	var oidcProvider service.OIDCProvider
+	if issuer := viper.GetString("oidc.issuer"); issuer != "" {
+		oidcProvider = oidc.NewProvider(oidc.Config{
+			Issuer:       issuer,
+			ClientID:     viper.GetString("oidc.client_id"),
+			ClientSecret: viper.GetString("OIDC_CLIENT_SECRET"),
+			RedirectURL:  viper.GetString("oidc.redirect_url"),
+		})
+	}
+	var mail mailer.Mailer
+	switch viper.GetString("mail.driver") {
+	case "smtp":
+		mail = mailer.NewSMTP(mailer.SMTPConfig{
+			Host:     viper.GetString("mail.smtp_host"),
+			Port:     viper.GetString("mail.smtp_port"),
+			Username: viper.GetString("mail.smtp_username"),
+			Password: viper.GetString("SMTP_PASSWORD"),
+			From:     viper.GetString("mail.from"),
+		})
+	default:
+		mail = mailer.NewLog(viper.GetString("mail.from"), viper.GetString("mail.dir"), logger)
+	}
+	var limiter ratelimit.Store
+	switch viper.GetString("rate_limit.backend") {
+	case "postgres":
+		limiter = store.RateLimit
+	case "memory":
+		limiter = ratelimit.NewMemory()
+	}
+	blobs, err := blobStore()
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("can't initialize blob storage: %v", err))
+	}
+	lockout := service.LockoutPolicy{
+		Threshold:   viper.GetInt("lockout.threshold"),
+		Duration:    viper.GetDuration("lockout.duration"),
+		MaxDuration: viper.GetDuration("lockout.max_duration"),
+	}
 	service := service.NewService(service.Deps{
-		AuthStorage:     store.Auth,
-		TodoListStorage: store.TodoList,
-		TodoItemStorage: store.TodoItem,
+		AuthStorage:         store.Auth,
+		TodoListStorage:     store.TodoList,
+		TodoItemStorage:     store.TodoItem,
+		EventStorage:        store.Event,
+		WebhookStorage:      store.Webhook,
+		OutboxStorage:       store.Outbox,
+		ImportStorage:       store.TodoList,
+		SyncStorage:         store.Sync,
+		TokenStorage:        store.Token,
+		IdentityStorage:     store.Identity,
+		AccountStorage:      store.Auth,
+		RecoveryStorage:     store.Auth,
+		AdminStorage:        store.Admin,
+		WorkspaceStorage:    store.Workspace,
+		CommentStorage:      store.Comment,
+		AttachmentStorage:   store.Attachment,
+		SettingsStorage:     store.Settings,
+		NotificationStorage: store.Notification,
+		TemplateStorage:     store.Template,
+		ShareLinkStorage:    store.ShareLink,
+		StatsStorage:        store.Stats,
+		StatusStorage:       store.Status,
+		Blobs:               blobs,
+		AttachmentLimits: service.AttachmentLimits{
+			MaxSize:   viper.GetInt64("attachments.max_size"),
+			UserQuota: viper.GetInt64("attachments.user_quota"),
+		},
+		Mailer:           mail,
+		AppURL:           viper.GetString("app_url"),
+		PasswordResetURL: viper.GetString("password_reset_url"),
+		Lockout:          lockout,
+		OIDCProvider:     oidcProvider,
+		Log:              logger,
 	})
+	var oidcService handler.OIDCService
+	if service.OIDC != nil {
+		oidcService = service.OIDC
+	}
 	h := handler.New(handler.Deps{
-		AuthService:     service.Auth,
-		TodoListService: service.TodoList,
-		TodoItemService: service.TodoItem,
-		Log:             logger,
+		AuthService:       service.Auth,
+		TodoListService:   service.TodoList,
+		TodoItemService:   service.TodoItem,
+		EventService:      service.Event,
+		WebhookService:    service.Webhook,
+		ImportService:     service.Import,
+		ExportService:     service.Export,
+		SyncService:       service.Sync,
+		TokenService:      service.Token,
+		OIDCService:       oidcService,
+		AccountService:    service.Account,
+		RecoveryService:   service.Recovery,
+		AdminService:      service.Admin,
+		WorkspaceService:  service.Workspace,
+		CommentService:    service.Comment,
+		AttachmentService: service.Attachment,
+		QuickAddService:   service.QuickAdd,
+		SettingsService:   service.Settings,
+		TemplateService:   service.Template,
+		ShareLinkService:  service.ShareLink,
+		StatsService:      service.Stats,
+		StatusService:     service.TodoItem,
+		RateLimiter:       limiter,
+		RateLimits: handler.RateLimits{
+			Auth:   rateLimitRule("rate_limit.auth"),
+			SignIn: rateLimitRule("rate_limit.sign_in"),
+			API:    rateLimitRule("rate_limit.api"),
+			DAV:    rateLimitRule("rate_limit.dav"),
+			Public: rateLimitRule("rate_limit.public"),
+		},
+		GraphQL: graphql.NewHandler(graphql.Deps{
+			TodoListService: service.TodoList,
+			TodoItemService: service.TodoItem,
+		}),
+		CalDAV: caldav.NewHandler(caldav.Deps{
+			AuthService:     service.Auth,
+			TodoListService: service.TodoList,
+			TodoItemService: service.TodoItem,
+			EventService:    service.Event,
+			Log:             logger,
+		}),
+		Log: logger,
 	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go service.Event.Listen(ctx)
+	go service.Webhook.Run(ctx)
+	go service.Outbox.Run(ctx)
+	go service.Attachment.Run(ctx)
 	srv := new(rest.Server)
 	port := viper.GetString("PORT")
 	if port == "" {
@@ -72,6 +194,7 @@ func main() {
 	exit := make(chan os.Signal, 1)
 	signal.Notify(exit, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	<-exit
+	cancel()
 	if err := srv.Shutdown(context.Background()); err != nil {
 		logger.Error("Error occurred while server is shutting down " + err.Error())
 	}
@@ -91,3 +214,30 @@ func LoadConfig(path string) error {
 	viper.SetConfigType("env")
 	return viper.MergeInConfig()
 }
+
+// rateLimitRule reads the rule of rate limiting from the config key
+func rateLimitRule(key string) ratelimit.Rule {
+	return ratelimit.Rule{
+		Limit:  viper.GetInt(key + ".limit"),
+		Period: viper.GetDuration(key + ".period"),
+	}
+}
+
+// blobStore returns storage of attachments which is selected by the config
+func blobStore() (blob.Store, error) {
+	switch viper.GetString("attachments.driver") {
+	case "s3":
+		return blob.NewS3(blob.S3Config{
+			Endpoint: viper.GetString("attachments.s3_endpoint"),
+			Region:   viper.GetString("attachments.s3_region"),
+			Bucket:   viper.GetString("attachments.s3_bucket"),
+			Credentials: blob.Credentials{
+				AccessKey: viper.GetString("attachments.s3_access_key"),
+				SecretKey: viper.GetString("S3_SECRET_KEY"),
+			},
+			PathStyle: viper.GetBool("attachments.s3_path_style"),
+		})
+	default:
+		return blob.NewLocal(viper.GetString("attachments.dir"))
+	}
+}
@@ -0,0 +1,26 @@
+// Local stand-in of OpenID provider for development, it signs in every user
+// without credentials
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/vbetsun/todo-app/internal/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL as it's seen by clients")
+	clientID := flag.String("client-id", "todo-app", "ID of the registered client")
+	secret := flag.String("client-secret", "", "secret of the registered client")
+	flag.Parse()
+	i, err := oidctest.NewIssuer(*issuer, *clientID)
+	if err != nil {
+		log.Fatalf("can't create issuer: %v", err)
+	}
+	i.ClientSecret = *secret
+	log.Printf("OpenID provider stand-in %s is listening on %s", *issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, i))
+}
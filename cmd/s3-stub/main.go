@@ -0,0 +1,23 @@
+// Local stand-in of S3-compatible storage for development, objects are kept in
+// memory and lost on exit
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/vbetsun/todo-app/internal/blob"
+	"github.com/vbetsun/todo-app/internal/blob/blobtest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9001", "address to listen on")
+	region := flag.String("region", "us-east-1", "region of the storage")
+	accessKey := flag.String("access-key", "todo-app", "access key of the client")
+	secretKey := flag.String("secret-key", "", "secret key of the client")
+	flag.Parse()
+	s := blobtest.NewS3(blob.Credentials{AccessKey: *accessKey, SecretKey: *secretKey}, *region)
+	log.Printf("S3 stand-in is listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
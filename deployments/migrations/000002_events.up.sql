@@ -0,0 +1,13 @@
+BEGIN;
+CREATE TABLE list_events (
+	id BIGSERIAL NOT NULL UNIQUE,
+	type VARCHAR(64) NOT NULL,
+	list_id INT NOT NULL,
+	todo_id INT,
+	user_id INT NOT NULL,
+	data JSONB,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX list_events_list_id_idx ON list_events (list_id, id);
+COMMIT;
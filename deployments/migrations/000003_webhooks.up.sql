@@ -0,0 +1,28 @@
+BEGIN;
+CREATE TABLE webhooks (
+	id SERIAL NOT NULL UNIQUE,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+	url VARCHAR(2048) NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	events VARCHAR(64)[] NOT NULL DEFAULT '{}',
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE webhook_deliveries (
+	id SERIAL NOT NULL UNIQUE,
+	webhook_id INT REFERENCES webhooks(id) ON DELETE CASCADE NOT NULL,
+	event_type VARCHAR(64) NOT NULL,
+	payload JSONB NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INT NOT NULL DEFAULT 0,
+	response_code INT,
+	last_error TEXT,
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id);
+COMMIT;
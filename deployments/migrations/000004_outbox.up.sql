@@ -0,0 +1,14 @@
+BEGIN;
+CREATE TABLE outbox (
+	id BIGSERIAL NOT NULL UNIQUE,
+	type VARCHAR(64) NOT NULL,
+	list_id INT NOT NULL,
+	todo_id INT,
+	user_id INT NOT NULL,
+	data JSONB,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE webhook_deliveries ADD COLUMN event_id BIGINT;
+CREATE UNIQUE INDEX webhook_deliveries_event_id_idx ON webhook_deliveries (webhook_id, event_id);
+COMMIT;
@@ -0,0 +1,3 @@
+BEGIN;
+ALTER TABLE todo_items DROP COLUMN IF EXISTS due;
+COMMIT;
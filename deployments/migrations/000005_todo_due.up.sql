@@ -0,0 +1,3 @@
+BEGIN;
+ALTER TABLE todo_items ADD COLUMN due TIMESTAMPTZ;
+COMMIT;
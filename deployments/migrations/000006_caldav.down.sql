@@ -0,0 +1,6 @@
+BEGIN;
+DROP INDEX IF EXISTS todo_items_uid_idx;
+ALTER TABLE todo_items
+	DROP COLUMN IF EXISTS updated_at,
+	DROP COLUMN IF EXISTS uid;
+COMMIT;
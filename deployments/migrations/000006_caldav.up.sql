@@ -0,0 +1,6 @@
+BEGIN;
+ALTER TABLE todo_items
+	ADD COLUMN uid TEXT,
+	ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+CREATE INDEX todo_items_uid_idx ON todo_items (uid);
+COMMIT;
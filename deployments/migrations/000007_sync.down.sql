@@ -0,0 +1,9 @@
+BEGIN;
+DROP TABLE IF EXISTS sync_tombstones;
+DROP INDEX IF EXISTS todo_items_change_xid_idx;
+DROP INDEX IF EXISTS todo_lists_change_xid_idx;
+ALTER TABLE todo_items DROP COLUMN IF EXISTS change_xid;
+ALTER TABLE todo_lists
+	DROP COLUMN IF EXISTS change_xid,
+	DROP COLUMN IF EXISTS updated_at;
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+ALTER TABLE todo_lists
+	ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	ADD COLUMN change_xid BIGINT NOT NULL DEFAULT txid_current();
+ALTER TABLE todo_items ADD COLUMN change_xid BIGINT NOT NULL DEFAULT txid_current();
+CREATE INDEX todo_lists_change_xid_idx ON todo_lists (change_xid);
+CREATE INDEX todo_items_change_xid_idx ON todo_items (change_xid);
+
+CREATE TABLE sync_tombstones (
+	id BIGSERIAL NOT NULL UNIQUE,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+	entity VARCHAR(16) NOT NULL,
+	entity_id INT NOT NULL,
+	list_id INT NOT NULL,
+	change_xid BIGINT NOT NULL DEFAULT txid_current(),
+	deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX sync_tombstones_user_id_idx ON sync_tombstones (user_id, change_xid);
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+CREATE TABLE access_tokens (
+	id SERIAL NOT NULL UNIQUE,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	prefix VARCHAR(16) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	scopes VARCHAR(64)[] NOT NULL DEFAULT '{}',
+	expires_at TIMESTAMPTZ,
+	last_used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX access_tokens_user_id_idx ON access_tokens (user_id);
+COMMIT;
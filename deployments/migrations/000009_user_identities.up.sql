@@ -0,0 +1,12 @@
+BEGIN;
+CREATE TABLE user_identities (
+	id SERIAL NOT NULL UNIQUE,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+	issuer VARCHAR(2048) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	email VARCHAR(255),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (issuer, subject)
+);
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+CREATE TABLE user_totp (
+	user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL PRIMARY KEY,
+	secret VARCHAR(64) NOT NULL,
+	enabled BOOLEAN NOT NULL DEFAULT FALSE,
+	last_used_step BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE user_recovery_codes (
+	id SERIAL NOT NULL UNIQUE,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+	code_hash CHAR(64) NOT NULL,
+	used_at TIMESTAMPTZ
+);
+CREATE INDEX user_recovery_codes_user_id_idx ON user_recovery_codes (user_id);
+COMMIT;
@@ -0,0 +1,3 @@
+BEGIN;
+ALTER TABLE users ADD COLUMN token_version INT NOT NULL DEFAULT 0;
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+DROP TABLE IF EXISTS one_time_tokens;
+DROP INDEX IF EXISTS users_email_idx;
+ALTER TABLE users
+	DROP COLUMN IF EXISTS email_verified_at,
+	DROP COLUMN IF EXISTS email;
+COMMIT;
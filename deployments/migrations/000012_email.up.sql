@@ -0,0 +1,18 @@
+BEGIN;
+ALTER TABLE users
+	ADD COLUMN email VARCHAR(255),
+	ADD COLUMN email_verified_at TIMESTAMPTZ;
+CREATE UNIQUE INDEX users_email_idx ON users (LOWER(email));
+
+CREATE TABLE one_time_tokens (
+	id SERIAL NOT NULL UNIQUE,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+	purpose VARCHAR(32) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	email VARCHAR(255) NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX one_time_tokens_user_id_idx ON one_time_tokens (user_id, purpose);
+COMMIT;
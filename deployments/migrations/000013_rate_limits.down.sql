@@ -0,0 +1,6 @@
+BEGIN;
+ALTER TABLE users
+	DROP COLUMN IF EXISTS failed_logins,
+	DROP COLUMN IF EXISTS locked_until;
+DROP TABLE IF EXISTS rate_limits;
+COMMIT;
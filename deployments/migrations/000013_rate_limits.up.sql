@@ -0,0 +1,14 @@
+BEGIN;
+CREATE TABLE rate_limits (
+	key VARCHAR(255) NOT NULL PRIMARY KEY,
+	tokens DOUBLE PRECISION NOT NULL,
+	allowed BOOLEAN NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX rate_limits_expires_at_idx ON rate_limits (expires_at);
+
+ALTER TABLE users
+	ADD COLUMN failed_logins INT NOT NULL DEFAULT 0,
+	ADD COLUMN locked_until TIMESTAMPTZ;
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+DROP TABLE IF EXISTS admin_audit_log;
+ALTER TABLE users
+	DROP COLUMN IF EXISTS created_at,
+	DROP COLUMN IF EXISTS disabled_at,
+	DROP COLUMN IF EXISTS role;
+COMMIT;
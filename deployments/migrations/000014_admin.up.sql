@@ -0,0 +1,16 @@
+BEGIN;
+ALTER TABLE users
+	ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user',
+	ADD COLUMN disabled_at TIMESTAMPTZ,
+	ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE TABLE admin_audit_log (
+	id SERIAL NOT NULL UNIQUE,
+	admin_id INT REFERENCES users(id) ON DELETE SET NULL,
+	action VARCHAR(64) NOT NULL,
+	target_user_id INT REFERENCES users(id) ON DELETE SET NULL,
+	details TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX admin_audit_log_target_user_id_idx ON admin_audit_log (target_user_id);
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+-- lists which existed before workspaces get back their original sharing, rows
+-- of deleted users and lists are already removed from the backup by cascades.
+-- Lists without users were deleted by the up migration and can't be restored
+DROP VIEW IF EXISTS users_lists;
+ALTER TABLE users_lists_backup RENAME TO users_lists;
+
+-- lists created in workspaces are shared by the members of their workspaces
+INSERT INTO users_lists (user_id, list_id)
+SELECT wm.user_id, tl.id
+FROM todo_lists AS tl
+INNER JOIN workspace_members AS wm ON wm.workspace_id = tl.workspace_id
+WHERE NOT EXISTS (SELECT 1 FROM users_lists WHERE list_id = tl.id);
+
+ALTER TABLE todo_lists DROP COLUMN IF EXISTS workspace_id;
+DROP TABLE IF EXISTS workspace_invites;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
+COMMIT;
@@ -0,0 +1,77 @@
+BEGIN;
+CREATE TABLE workspaces (
+	id SERIAL NOT NULL UNIQUE,
+	name VARCHAR(255) NOT NULL,
+	-- personal workspace is created for every user at sign up
+	personal_user_id INT UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	-- list which was shared by several users before workspaces, used only by the migration
+	migrated_list_id INT
+);
+
+CREATE TABLE workspace_members (
+	workspace_id INT REFERENCES workspaces(id) ON DELETE CASCADE NOT NULL,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+	role VARCHAR(16) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (workspace_id, user_id)
+);
+CREATE INDEX workspace_members_user_id_idx ON workspace_members (user_id);
+
+CREATE TABLE workspace_invites (
+	id SERIAL NOT NULL UNIQUE,
+	workspace_id INT REFERENCES workspaces(id) ON DELETE CASCADE NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	role VARCHAR(16) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	invited_by INT REFERENCES users(id) ON DELETE SET NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	accepted_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX workspace_invites_workspace_id_idx ON workspace_invites (workspace_id);
+
+INSERT INTO workspaces (name, personal_user_id)
+SELECT 'Personal', id FROM users;
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT id, personal_user_id, 'owner' FROM workspaces;
+
+ALTER TABLE todo_lists ADD COLUMN workspace_id INT REFERENCES workspaces(id) ON DELETE CASCADE;
+
+-- lists of a single user go to the user's personal workspace
+UPDATE todo_lists AS tl SET workspace_id = w.id
+FROM users_lists AS ul
+INNER JOIN workspaces AS w ON w.personal_user_id = ul.user_id
+WHERE ul.list_id = tl.id
+AND (SELECT COUNT(*) FROM users_lists WHERE list_id = tl.id) = 1;
+
+-- every list shared by several users gets its own workspace, so nobody gains
+-- access to lists which weren't shared with them
+INSERT INTO workspaces (name, migrated_list_id)
+SELECT tl.title, tl.id FROM todo_lists AS tl
+WHERE tl.workspace_id IS NULL
+AND EXISTS (SELECT 1 FROM users_lists WHERE list_id = tl.id);
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT w.id, ul.user_id,
+	CASE WHEN ul.user_id = (SELECT MIN(user_id) FROM users_lists WHERE list_id = w.migrated_list_id)
+	THEN 'owner' ELSE 'member' END
+FROM workspaces AS w
+INNER JOIN users_lists AS ul ON ul.list_id = w.migrated_list_id;
+UPDATE todo_lists AS tl SET workspace_id = w.id
+FROM workspaces AS w
+WHERE w.migrated_list_id = tl.id;
+ALTER TABLE workspaces DROP COLUMN migrated_list_id;
+
+-- lists without users aren't reachable anyway
+DELETE FROM todo_lists WHERE workspace_id IS NULL;
+ALTER TABLE todo_lists ALTER COLUMN workspace_id SET NOT NULL;
+CREATE INDEX todo_lists_workspace_id_idx ON todo_lists (workspace_id);
+
+-- users get access to lists through workspaces, the view keeps queries of
+-- per-user access working. The original sharing is kept for the down migration
+ALTER TABLE users_lists RENAME TO users_lists_backup;
+CREATE VIEW users_lists AS
+SELECT wm.user_id, tl.id AS list_id
+FROM todo_lists AS tl
+INNER JOIN workspace_members AS wm ON wm.workspace_id = tl.workspace_id;
+COMMIT;
@@ -0,0 +1,4 @@
+BEGIN;
+ALTER TABLE todo_items
+	DROP COLUMN IF EXISTS assignee_id;
+COMMIT;
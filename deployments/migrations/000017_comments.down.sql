@@ -0,0 +1,4 @@
+BEGIN;
+DROP TABLE IF EXISTS comment_mentions;
+DROP TABLE IF EXISTS todo_comments;
+COMMIT;
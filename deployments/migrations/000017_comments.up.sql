@@ -0,0 +1,18 @@
+BEGIN;
+CREATE TABLE todo_comments (
+	id SERIAL NOT NULL UNIQUE,
+	todo_id INT REFERENCES todo_items(id) ON DELETE CASCADE NOT NULL,
+	author_id INT REFERENCES users(id) ON DELETE SET NULL,
+	body TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX todo_comments_todo_id_idx ON todo_comments (todo_id, id);
+
+CREATE TABLE comment_mentions (
+	comment_id INT REFERENCES todo_comments(id) ON DELETE CASCADE NOT NULL,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+	PRIMARY KEY (comment_id, user_id)
+);
+CREATE INDEX comment_mentions_user_id_idx ON comment_mentions (user_id);
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+DROP TRIGGER IF EXISTS todo_attachments_delete ON todo_attachments;
+DROP FUNCTION IF EXISTS queue_blob_deletion();
+DROP TABLE IF EXISTS blob_deletions;
+DROP TABLE IF EXISTS todo_attachments;
+COMMIT;
@@ -0,0 +1,34 @@
+BEGIN;
+CREATE TABLE todo_attachments (
+	id SERIAL NOT NULL UNIQUE,
+	todo_id INT REFERENCES todo_items(id) ON DELETE CASCADE NOT NULL,
+	-- uploader, the attachment counts towards the uploader's quota
+	user_id INT REFERENCES users(id) ON DELETE SET NULL,
+	filename VARCHAR(255) NOT NULL,
+	content_type VARCHAR(255) NOT NULL,
+	size BIGINT NOT NULL,
+	storage_key VARCHAR(255) NOT NULL UNIQUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX todo_attachments_todo_id_idx ON todo_attachments (todo_id);
+CREATE INDEX todo_attachments_user_id_idx ON todo_attachments (user_id);
+
+-- blobs of the removed attachments, they're deleted from the blob store in background
+CREATE TABLE blob_deletions (
+	storage_key VARCHAR(255) PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- attachments are removed by cascade with todos, lists and users, so their
+-- blobs are queued by the trigger
+CREATE FUNCTION queue_blob_deletion() RETURNS TRIGGER AS $$
+BEGIN
+	INSERT INTO blob_deletions (storage_key) VALUES (OLD.storage_key) ON CONFLICT DO NOTHING;
+	RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER todo_attachments_delete
+	AFTER DELETE ON todo_attachments
+	FOR EACH ROW EXECUTE PROCEDURE queue_blob_deletion();
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+ALTER TABLE todo_items
+	DROP COLUMN IF EXISTS recurrence,
+	DROP COLUMN IF EXISTS labels,
+	DROP COLUMN IF EXISTS priority;
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+ALTER TABLE todo_items
+	ADD COLUMN priority SMALLINT NOT NULL DEFAULT 0,
+	ADD COLUMN labels TEXT[] NOT NULL DEFAULT '{}',
+	ADD COLUMN recurrence VARCHAR(255);
+CREATE INDEX todo_items_labels_idx ON todo_items USING GIN (labels);
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+CREATE TABLE user_settings (
+	user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+	-- first day of the week, 0 is Sunday
+	week_start SMALLINT NOT NULL DEFAULT 1,
+	default_list_id INT REFERENCES todo_lists(id) ON DELETE SET NULL,
+	locale VARCHAR(35) NOT NULL DEFAULT 'en',
+	notify_assigned BOOLEAN NOT NULL DEFAULT TRUE,
+	notify_mentioned BOOLEAN NOT NULL DEFAULT TRUE,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- settings are created together with users, existing users get the defaults
+INSERT INTO user_settings (user_id) SELECT id FROM users;
+COMMIT;
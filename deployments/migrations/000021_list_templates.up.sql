@@ -0,0 +1,31 @@
+BEGIN;
+CREATE TABLE list_templates (
+	id SERIAL NOT NULL UNIQUE,
+	workspace_id INT REFERENCES workspaces(id) ON DELETE CASCADE NOT NULL,
+	author_id INT REFERENCES users(id) ON DELETE SET NULL,
+	title VARCHAR(255) NOT NULL,
+	description VARCHAR(255) NOT NULL DEFAULT '',
+	-- private templates are visible only to their authors
+	visibility VARCHAR(16) NOT NULL DEFAULT 'private',
+	-- hash of the token of the public link, NULL when the template isn't shared
+	share_hash CHAR(64) UNIQUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX list_templates_workspace_id_idx ON list_templates (workspace_id);
+CREATE INDEX list_templates_author_id_idx ON list_templates (author_id);
+
+CREATE TABLE template_todos (
+	id SERIAL NOT NULL UNIQUE,
+	template_id INT REFERENCES list_templates(id) ON DELETE CASCADE NOT NULL,
+	position INT NOT NULL,
+	title VARCHAR(255) NOT NULL,
+	description VARCHAR(255) NOT NULL DEFAULT '',
+	priority SMALLINT NOT NULL DEFAULT 0,
+	labels TEXT[] NOT NULL DEFAULT '{}',
+	recurrence VARCHAR(255),
+	-- seconds since the start of the day the template is instantiated on
+	due_offset BIGINT
+);
+CREATE INDEX template_todos_template_id_idx ON template_todos (template_id, position);
+COMMIT;
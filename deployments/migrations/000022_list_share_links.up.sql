@@ -0,0 +1,13 @@
+BEGIN;
+CREATE TABLE list_share_links (
+	id SERIAL NOT NULL UNIQUE,
+	list_id INT REFERENCES todo_lists(id) ON DELETE CASCADE NOT NULL,
+	created_by INT REFERENCES users(id) ON DELETE SET NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	-- links without password are available to everyone who has the token
+	password_hash VARCHAR(255),
+	expires_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX list_share_links_list_id_idx ON list_share_links (list_id);
+COMMIT;
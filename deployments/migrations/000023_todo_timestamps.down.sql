@@ -0,0 +1,5 @@
+BEGIN;
+ALTER TABLE todo_items
+	DROP COLUMN IF EXISTS completed_at,
+	DROP COLUMN IF EXISTS created_at;
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+ALTER TABLE todo_items
+	ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	ADD COLUMN completed_at TIMESTAMPTZ;
+
+-- the last change is the best known creation time of existing todos, completion
+-- time of already done todos is unknown, so they stay out of completion stats
+UPDATE todo_items SET created_at = updated_at;
+
+CREATE INDEX todo_items_created_at_idx ON todo_items (created_at);
+CREATE INDEX todo_items_completed_at_idx ON todo_items (completed_at) WHERE completed_at IS NOT NULL;
+COMMIT;
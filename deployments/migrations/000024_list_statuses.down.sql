@@ -0,0 +1,5 @@
+BEGIN;
+ALTER TABLE todo_items
+	DROP COLUMN IF EXISTS status_id;
+DROP TABLE IF EXISTS list_statuses;
+COMMIT;
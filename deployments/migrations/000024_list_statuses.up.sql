@@ -0,0 +1,20 @@
+BEGIN;
+CREATE TABLE list_statuses (
+	id SERIAL PRIMARY KEY,
+	list_id INT NOT NULL REFERENCES todo_lists (id) ON DELETE CASCADE,
+	name VARCHAR(64) NOT NULL,
+	position INT NOT NULL DEFAULT 0,
+	terminal BOOLEAN NOT NULL DEFAULT FALSE,
+	wip_limit INT NOT NULL DEFAULT 0 CHECK (wip_limit >= 0),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX list_statuses_list_id_idx ON list_statuses (list_id, position);
+-- todos of the terminal status are done, so the board has only one of them
+CREATE UNIQUE INDEX list_statuses_terminal_idx ON list_statuses (list_id) WHERE terminal;
+
+ALTER TABLE todo_items
+	ADD COLUMN status_id INT REFERENCES list_statuses (id) ON DELETE SET NULL;
+
+CREATE INDEX todo_items_status_id_idx ON todo_items (status_id) WHERE status_id IS NOT NULL;
+COMMIT;
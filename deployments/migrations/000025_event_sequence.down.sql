@@ -0,0 +1,7 @@
+BEGIN;
+DROP TABLE IF EXISTS notified_events;
+DROP INDEX IF EXISTS list_events_list_id_seq_idx;
+CREATE INDEX IF NOT EXISTS list_events_list_id_idx ON list_events (list_id, id);
+ALTER TABLE list_events
+	DROP COLUMN IF EXISTS seq;
+COMMIT;
@@ -0,0 +1,26 @@
+BEGIN;
+-- outbox IDs are assigned before the changes are committed, so events are
+-- ordered by the sequence number assigned when the relay publishes them
+CREATE SEQUENCE list_events_seq;
+ALTER TABLE list_events ADD COLUMN seq BIGINT;
+
+-- existing events keep their IDs, so clients resume from the ones they have
+UPDATE list_events SET seq = id;
+SELECT setval('list_events_seq', COALESCE((SELECT MAX(id) FROM list_events), 0) + 1, false);
+
+ALTER TABLE list_events
+	ALTER COLUMN seq SET NOT NULL,
+	ALTER COLUMN seq SET DEFAULT nextval('list_events_seq');
+ALTER SEQUENCE list_events_seq OWNED BY list_events.seq;
+
+DROP INDEX list_events_list_id_idx;
+CREATE INDEX list_events_list_id_seq_idx ON list_events (list_id, seq);
+
+-- events which were emailed about, the outbox may deliver the event again
+CREATE TABLE notified_events (
+	event_id BIGINT PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX notified_events_created_at_idx ON notified_events (created_at);
+COMMIT;
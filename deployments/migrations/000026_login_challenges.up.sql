@@ -0,0 +1,12 @@
+BEGIN;
+-- challenges of the sign in with two-factor authentication, so every challenge
+-- token is used once and allows a few attempts
+CREATE TABLE login_challenges (
+	id VARCHAR(64) NOT NULL PRIMARY KEY,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	used_at TIMESTAMPTZ,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX login_challenges_expires_at_idx ON login_challenges (expires_at);
+COMMIT;
@@ -0,0 +1,55 @@
+// Package auth carries the authenticated caller through the request's context,
+// it's shared by all transports
+package auth
+
+import (
+	"context"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Key to use when setting the principal context.
+type ctxKeyPrincipal string
+
+const principalCtx ctxKeyPrincipal = "principal"
+
+// NewContext returns the context which carries the authenticated caller
+func NewContext(ctx context.Context, p core.Principal) context.Context {
+	return context.WithValue(ctx, principalCtx, p)
+}
+
+// FromContext returns the caller which was authenticated for the request
+func FromContext(ctx context.Context) (core.Principal, bool) {
+	p, ok := ctx.Value(principalCtx).(core.Principal)
+	return p, ok
+}
+
+// UserID returns ID of the user which was authenticated for the request
+func UserID(ctx context.Context) (int, bool) {
+	p, ok := FromContext(ctx)
+	return p.UserID, ok
+}
+
+// HasScopes reports whether the request's credentials grant all the scopes,
+// credentials without scopes grant everything
+func HasScopes(ctx context.Context, scopes ...string) bool {
+	p, ok := FromContext(ctx)
+	if !ok || p.Scopes == nil {
+		return true
+	}
+	for _, scope := range scopes {
+		if !containsScope(p.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
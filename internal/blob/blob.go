@@ -0,0 +1,39 @@
+// Package blob implements storages of binary objects, such as attachments of
+// todos
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store keeps binary objects by their keys
+type Store interface {
+	// Put saves the object of the given size, the existing object is replaced
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns content of the object, the caller has to close it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object, missing objects aren't reported
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey reports whether the key is a relative slash separated path which
+// doesn't leave the store
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+// Package blobtest implements a local stand-in of S3-compatible storage. It
+// keeps objects in memory and supports only the requests used by blob.S3, so
+// it's only suitable for development and integration tests
+package blobtest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/vbetsun/todo-app/internal/blob"
+)
+
+const maxObjectSize = 1 << 30
+
+// Object represents the stored object
+type Object struct {
+	Data        []byte
+	ContentType string
+}
+
+// S3 serves path-style requests to buckets, every bucket exists. Requests
+// have to be signed with the configured credentials
+type S3 struct {
+	Credentials blob.Credentials
+	Region      string
+
+	mu      sync.Mutex
+	objects map[string]Object
+}
+
+// NewS3 returns the storage which accepts the credentials
+func NewS3(cred blob.Credentials, region string) *S3 {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3{Credentials: cred, Region: region, objects: make(map[string]Object)}
+}
+
+// NewServer starts the storage on the local loopback interface, the caller
+// should close the server when finished
+func NewServer(cred blob.Credentials, region string) (*S3, *httptest.Server) {
+	s := NewS3(cred, region)
+	return s, httptest.NewServer(s)
+}
+
+// Object returns the stored object by bucket and key
+func (s *S3) Object(bucket, key string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.objects[bucket+"/"+key]
+	return o, ok
+}
+
+// Len returns number of the stored objects
+func (s *S3) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.objects)
+}
+
+func (s *S3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !blob.VerifyV4(r, s.Credentials, s.Region) {
+		s3Error(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if i := strings.IndexByte(path, '/'); i <= 0 || i == len(path)-1 {
+		s3Error(w, http.StatusBadRequest, "InvalidRequest")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxObjectSize))
+		if err != nil {
+			s3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.mu.Lock()
+		s.objects[path] = Object{Data: data, ContentType: r.Header.Get("Content-Type")}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		s.mu.Lock()
+		o, ok := s.objects[path]
+		s.mu.Unlock()
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if o.ContentType != "" {
+			w.Header().Set("Content-Type", o.ContentType)
+		}
+		_, _ = w.Write(o.Data)
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, path)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, "<Error><Code>"+code+"</Code></Error>")
+}
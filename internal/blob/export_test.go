@@ -0,0 +1,18 @@
+package blob
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// DialTo routes every connection of the client to the address, so requests to
+// virtual-hosted buckets reach the local server
+func (s *S3) DialTo(addr string) {
+	s.client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
@@ -0,0 +1,164 @@
+package blob
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	sigV4Service   = "s3"
+	amzDateFormat  = "20060102T150405Z"
+	// UnsignedPayload is used as hash of the payload which isn't signed, it
+	// allows streaming of uploads
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// Credentials represents the access key of S3-compatible storage
+type Credentials struct {
+	AccessKey string
+	SecretKey string
+}
+
+// SignV4 signs the request with AWS Signature Version 4, headers which are set
+// afterwards aren't covered by the signature
+func SignV4(req *http.Request, cred Credentials, region, payloadHash string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		signed = append(signed, "content-type")
+	}
+	sort.Strings(signed)
+	scope := strings.Join([]string{t.Format("20060102"), region, sigV4Service, "aws4_request"}, "/")
+	signature := signatureV4(req, cred.SecretKey, region, scope, amzDate, signed, payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, cred.AccessKey, scope, strings.Join(signed, ";"), signature))
+}
+
+// VerifyV4 checks signature of the request made with the credentials, it's
+// used by stand-ins of S3-compatible storages
+func VerifyV4(req *http.Request, cred Credentials, region string) bool {
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, sigV4Algorithm+" ") {
+		return false
+	}
+	fields := make(map[string]string)
+	for _, field := range strings.Split(strings.TrimPrefix(auth, sigV4Algorithm+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	credential := strings.SplitN(fields["Credential"], "/", 2)
+	if len(credential) != 2 || credential[0] != cred.AccessKey {
+		return false
+	}
+	scope := credential[1]
+	amzDate := req.Header.Get("X-Amz-Date")
+	t, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil || !strings.HasPrefix(scope, t.Format("20060102")+"/"+region+"/") {
+		return false
+	}
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	expected := signatureV4(req, cred.SecretKey, region, scope, amzDate, signed, req.Header.Get("X-Amz-Content-Sha256"))
+	return hmac.Equal([]byte(expected), []byte(fields["Signature"]))
+}
+
+func signatureV4(req *http.Request, secret, region, scope, amzDate string, signed []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signed {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		}
+		fmt.Fprintf(&headers, "%s:%s\n", name, strings.TrimSpace(value))
+	}
+	canonical := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(req.URL),
+		headers.String(),
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hashHex([]byte(canonical))}, "\n")
+	key := []byte("AWS4" + secret)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalPath returns URI encoded path, S3 doesn't normalize paths
+func canonicalPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			unescaped = part
+		}
+		parts[i] = uriEncode(unescaped)
+	}
+	return strings.Join(parts, "/")
+}
+
+func canonicalQuery(u *url.URL) string {
+	q := u.Query()
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		values := q[k]
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode escapes everything except unreserved characters as required by
+// the signature
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
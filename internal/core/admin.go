@@ -0,0 +1,63 @@
+// Package core represents domain's entities
+package core
+
+import "time"
+
+// Roles of the users
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+var Roles = []string{RoleUser, RoleAdmin}
+
+// Actions recorded in the admin audit log
+const (
+	AuditUserDisable     = "user.disable"
+	AuditUserEnable      = "user.enable"
+	AuditUserSignOut     = "user.sign_out"
+	AuditUserRole        = "user.role"
+	AuditUserImpersonate = "user.impersonate"
+)
+
+// Principal it is an entity that represents the authenticated caller of the API
+type Principal struct {
+	UserID int
+	Role   string
+	// Scopes are granted by personal access token, nil grants full access
+	Scopes []string
+	// ImpersonatorID is ID of the admin who acts on behalf of the user
+	ImpersonatorID int
+}
+
+// UserFilter it is a DTO for searching users by admins
+type UserFilter struct {
+	// Query matches name, username or email
+	Query    string
+	Disabled *bool
+	Limit    int
+	Offset   int
+}
+
+// UsageStats it is an entity that represents usage of the app
+type UsageStats struct {
+	Users         int `json:"users"`
+	Admins        int `json:"admins"`
+	DisabledUsers int `json:"disabled_users"`
+	NewUsers      int `json:"new_users_30d"`
+	Lists         int `json:"lists"`
+	Todos         int `json:"todos"`
+	DoneTodos     int `json:"done_todos"`
+	AccessTokens  int `json:"access_tokens"`
+	Webhooks      int `json:"webhooks"`
+}
+
+// AuditEntry it is an entity that represents an action of the admin
+type AuditEntry struct {
+	ID           int       `json:"id"`
+	AdminID      int       `json:"admin_id"`
+	Action       string    `json:"action"`
+	TargetUserID int       `json:"target_user_id"`
+	Details      string    `json:"details,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
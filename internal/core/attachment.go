@@ -0,0 +1,17 @@
+// Package core represents domain's entities
+package core
+
+import "time"
+
+// Attachment it is an entity that represents file attached to the todo
+type Attachment struct {
+	ID     int `json:"id"`
+	TodoID int `json:"todo_id"`
+	// UserID is the uploader, it's zero when the uploader's account was deleted
+	UserID      int       `json:"user_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Key         string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,23 @@
+// Package core represents domain's entities
+package core
+
+import "time"
+
+// Comment it is an entity that represents a message in the discussion of the todo
+type Comment struct {
+	ID     int `json:"id"`
+	TodoID int `json:"todo_id"`
+	// AuthorID is zero when the author's account was deleted
+	AuthorID       int       `json:"author_id"`
+	AuthorUsername string    `json:"author_username,omitempty"`
+	Body           string    `json:"body"`
+	Mentions       []Mention `json:"mentions"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Mention it is an entity that represents the list member mentioned in the comment
+type Mention struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+}
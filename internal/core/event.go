@@ -0,0 +1,61 @@
+// Package core represents domain's entities
+package core
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Types of the events which are emitted on lists and todos changes
+const (
+	EventListCreated   = "list.created"
+	EventListUpdated   = "list.updated"
+	EventListDeleted   = "list.deleted"
+	EventTodoCreated   = "todo.created"
+	EventTodoUpdated   = "todo.updated"
+	EventTodoDeleted   = "todo.deleted"
+	EventTodoCompleted = "todo.completed"
+	EventTodoAssigned  = "todo.assigned"
+)
+
+// EventTypes contains all known types of the events
+var EventTypes = []string{
+	EventListCreated,
+	EventListUpdated,
+	EventListDeleted,
+	EventTodoCreated,
+	EventTodoUpdated,
+	EventTodoDeleted,
+	EventTodoCompleted,
+	EventTodoAssigned,
+}
+
+// Event it is an entity that represents a single change of the list or its todos
+type Event struct {
+	ID int64 `json:"id,omitempty"`
+	// Seq is the position of the published event in the stream of all events,
+	// it only grows in the order events become visible, so clients resume from it
+	Seq       int64           `json:"seq,omitempty"`
+	Type      string          `json:"type"`
+	ListID    int             `json:"list_id"`
+	TodoID    int             `json:"todo_id,omitempty"`
+	UserID    int             `json:"user_id"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// NewEvent returns event of the given type which has happened just now
+func NewEvent(eventType string, userID, listID, todoID int, data interface{}) Event {
+	e := Event{
+		Type:      eventType,
+		ListID:    listID,
+		TodoID:    todoID,
+		UserID:    userID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if data != nil {
+		// entities always can be marshaled, so the error is ignored
+		e.Data, _ = json.Marshal(data)
+	}
+	return e
+}
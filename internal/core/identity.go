@@ -0,0 +1,15 @@
+// Package core represents domain's entities
+package core
+
+import "time"
+
+// Identity it is an entity that represents user's account at the external
+// OpenID provider linked to the User
+type Identity struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+// Package core represents domain's entities
+package core
+
+// ImportError it is an entity that represents a problem with a single line of imported file
+type ImportError struct {
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult it is an entity that represents outcome of importing lists and todos
+type ImportResult struct {
+	DryRun bool            `json:"dry_run"`
+	Lists  []ListWithTodos `json:"lists"`
+	Errors []ImportError   `json:"errors"`
+}
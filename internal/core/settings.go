@@ -0,0 +1,33 @@
+// Package core represents domain's entities
+package core
+
+import "time"
+
+// UserSettings it is an entity that represents preferences of the user
+type UserSettings struct {
+	// TimeZone is IANA name of the user's time zone, e.g. Europe/Kyiv
+	TimeZone string `json:"time_zone"`
+	// WeekStart is the first day of the week, 0 is Sunday
+	WeekStart time.Weekday `json:"week_start"`
+	// DefaultListID is the list of quickly added todos, zero means Inbox
+	DefaultListID int `json:"default_list_id"`
+	// Locale is BCP 47 language tag of the user's interface, e.g. en-US
+	Locale        string               `json:"locale"`
+	Notifications NotificationSettings `json:"notifications"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+// NotificationSettings it is an entity that represents which emails the user wants to get
+type NotificationSettings struct {
+	Assigned  bool `json:"assigned"`  // the todo is assigned to the user
+	Mentioned bool `json:"mentioned"` // the user is mentioned in the comment
+}
+
+// Location returns the user's time zone, UTC is used for unknown zones
+func (s UserSettings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,38 @@
+// Package core represents domain's entities
+package core
+
+import "time"
+
+// ShareLink it is an entity that represents read-only public link to the list
+type ShareLink struct {
+	ID     int `json:"id"`
+	ListID int `json:"list_id"`
+	// CreatedBy is zero when the author's account was deleted
+	CreatedBy int `json:"created_by"`
+	// Token is returned only once, when the link is created
+	Token        string     `json:"token,omitempty"`
+	Hash         string     `json:"-"`
+	PasswordHash string     `json:"-"`
+	HasPassword  bool       `json:"has_password"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// PublicList it is an entity that represents the list shown by the share link,
+// it doesn't reveal IDs and members of the workspace
+type PublicList struct {
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Todos       []PublicTodo `json:"todos"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// PublicTodo it is an entity that represents the todo of the public list
+type PublicTodo struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Done        bool       `json:"done"`
+	Due         *time.Time `json:"due,omitempty"`
+	Priority    int        `json:"priority,omitempty"`
+	Labels      []string   `json:"labels,omitempty"`
+}
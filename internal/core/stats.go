@@ -0,0 +1,36 @@
+// Package core represents domain's entities
+package core
+
+// Stats it is an entity that represents productivity report of the user over
+// the range of days in the time zone
+type Stats struct {
+	From     string     `json:"from"`
+	To       string     `json:"to"`
+	TimeZone string     `json:"time_zone"`
+	Days     []DayStats `json:"days"`
+	// AvgCompletionSeconds is the average time from creation to completion of
+	// todos completed in the range
+	AvgCompletionSeconds float64 `json:"avg_completion_seconds"`
+	// Overdue is the number of open todos which are due before now
+	Overdue int            `json:"overdue"`
+	Lists   []ListProgress `json:"lists"`
+}
+
+// DayStats it is an entity that represents counts of todos created and
+// completed during the day
+type DayStats struct {
+	Date      string `json:"date"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+// ListProgress it is an entity that represents completion of the list
+type ListProgress struct {
+	ListID  int    `json:"list_id"`
+	Title   string `json:"title"`
+	Total   int    `json:"total"`
+	Done    int    `json:"done"`
+	Overdue int    `json:"overdue"`
+	// Progress is the percentage of done todos, empty lists have zero progress
+	Progress float64 `json:"progress"`
+}
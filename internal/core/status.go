@@ -0,0 +1,24 @@
+// Package core represents domain's entities
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrWIPLimit is returned when the todo doesn't fit the WIP limit of its status
+var ErrWIPLimit = errors.New("status has reached its WIP limit")
+
+// ListStatus it is an entity that represents the column of the list's board,
+// the list becomes the board when it has statuses
+type ListStatus struct {
+	ID       int    `json:"id"`
+	ListID   int    `json:"list_id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	// Terminal status marks its todos as done, the board has exactly one of them
+	Terminal bool `json:"terminal"`
+	// WIPLimit is the maximum number of todos in the status, zero is unlimited
+	WIPLimit  int       `json:"wip_limit"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,91 @@
+// Package core represents domain's entities
+package core
+
+import "time"
+
+// Entities which can be synchronized
+const (
+	EntityList = "list"
+	EntityTodo = "todo"
+)
+
+// Operations of the sync mutations
+const (
+	SyncCreate = "create"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// Strategies of resolving conflicts between client and server changes
+const (
+	// SyncLastWriteWins applies the change if it was made later than the server's one
+	SyncLastWriteWins = "lww"
+	// SyncMerge applies fields which weren't changed on the server since the base version
+	SyncMerge = "merge"
+)
+
+// Statuses of the applied sync mutations
+const (
+	SyncApplied  = "applied"
+	SyncConflict = "conflict"
+	SyncFailed   = "failed"
+)
+
+// SyncTodo it is an entity that represents todo together with ID of its list
+type SyncTodo struct {
+	ListID int `json:"list_id"`
+	TodoItem
+}
+
+// Tombstone it is an entity that represents deleted list or todo
+type Tombstone struct {
+	ID        int       `json:"id"`
+	ListID    int       `json:"list_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// SyncChanges it is an entity that represents all changes of user's data since
+// the state identified by the previous token
+type SyncChanges struct {
+	Token        string      `json:"token"`
+	Lists        []Todolist  `json:"lists"`
+	Todos        []SyncTodo  `json:"todos"`
+	DeletedLists []Tombstone `json:"deleted_lists"`
+	DeletedTodos []Tombstone `json:"deleted_todos"`
+}
+
+// SyncFields it is a DTO for passing values of list or todo fields within sync mutation
+type SyncFields struct {
+	Title       *string    `json:"title"`
+	Description *string    `json:"description"`
+	Done        *bool      `json:"done"`
+	Due         *time.Time `json:"due"`
+}
+
+// SyncMutation it is an entity that represents single change made by client offline.
+// Base holds values of the changed fields which client had before the change
+type SyncMutation struct {
+	ID            string     `json:"id"`
+	Entity        string     `json:"entity"`
+	Op            string     `json:"op"`
+	EntityID      int        `json:"entity_id"`
+	ListID        int        `json:"list_id"`
+	ListRef       string     `json:"list_ref"`
+	Strategy      string     `json:"strategy"`
+	BaseUpdatedAt *time.Time `json:"base_updated_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Data          SyncFields `json:"data"`
+	Base          SyncFields `json:"base"`
+}
+
+// SyncResult it is an entity that represents outcome of the sync mutation,
+// it holds the server's version of the entity after the mutation
+type SyncResult struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	EntityID  int       `json:"entity_id,omitempty"`
+	Conflicts []string  `json:"conflicts,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	List      *Todolist `json:"list,omitempty"`
+	Todo      *SyncTodo `json:"todo,omitempty"`
+}
@@ -0,0 +1,62 @@
+// Package core represents domain's entities
+package core
+
+import "time"
+
+// Visibilities of list templates
+const (
+	TemplateVisibilityPrivate   = "private"   // only the author sees the template
+	TemplateVisibilityWorkspace = "workspace" // all members of the workspace see the template
+)
+
+// ListTemplate it is an entity that represents reusable checklist, lists are
+// created from it with variables like {{name}} substituted in titles
+type ListTemplate struct {
+	ID          int `json:"id"`
+	WorkspaceID int `json:"workspace_id"`
+	// AuthorID is zero when the author's account was deleted
+	AuthorID    int    `json:"author_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+	// Shared is set when the template is available by the public link
+	Shared bool `json:"shared"`
+	// ShareToken is returned only once, when the public link is created
+	ShareToken string `json:"share_token,omitempty"`
+	ShareHash  string `json:"-"`
+	// Variables are names of the variables used by the template
+	Variables []string       `json:"variables"`
+	Todos     []TemplateTodo `json:"todos"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// TemplateTodo it is an entity that represents the todo of the list template
+type TemplateTodo struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Priority    int      `json:"priority,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Recurrence  string   `json:"recurrence,omitempty"`
+	// DueOffset is seconds since the start of the day the template is
+	// instantiated on, todos without it have no due date
+	DueOffset *int64 `json:"due_offset,omitempty"`
+}
+
+// UpdateTemplateData it is a DTO for passing data to the Template service layer
+type UpdateTemplateData struct {
+	Title       *string         `json:"title"`
+	Description *string         `json:"description"`
+	Visibility  *string         `json:"visibility"`
+	Todos       *[]TemplateTodo `json:"todos"`
+}
+
+// TemplateInstance it is a DTO which describes the list created from the template
+type TemplateInstance struct {
+	// WorkspaceID of the new list, zero stands for the personal workspace
+	WorkspaceID int `json:"workspace_id"`
+	// Start is the day due offsets count from in the user's time zone,
+	// YYYY-MM-DD, today by default
+	Start     string            `json:"start"`
+	Variables map[string]string `json:"variables"`
+}
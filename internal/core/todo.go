@@ -1,19 +1,58 @@
 // Package core represents domain's entities
 package core
 
+import (
+	"regexp"
+	"time"
+)
+
+// priorities of todos, higher value is more important
+const (
+	PriorityNone = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
+// recurrencePattern matches the subset of RFC 5545 RRULE which todos support
+var recurrencePattern = regexp.MustCompile(
+	`^FREQ=(DAILY|WEEKLY|MONTHLY|YEARLY)(;INTERVAL=[1-9][0-9]{0,2})?(;BYDAY=(MO|TU|WE|TH|FR|SA|SU)(,(MO|TU|WE|TH|FR|SA|SU))*)?$`,
+)
+
+// ValidRecurrence reports whether the rule is supported recurrence of todos,
+// empty rule means the todo doesn't repeat
+func ValidRecurrence(rule string) bool {
+	return rule == "" || recurrencePattern.MatchString(rule)
+}
+
 // Todolist it is an entity that represents user's list of todos
 type Todolist struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	ID          int       `json:"id"`
+	WorkspaceID int       `json:"workspace_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // TodoItem it is an entity that represents user's single todo
 type TodoItem struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Done        bool   `json:"done"`
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Done        bool       `json:"done"`
+	Due         *time.Time `json:"due,omitempty"`
+	UID         string     `json:"uid,omitempty"`
+	AssigneeID  int        `json:"assignee_id,omitempty"`
+	Priority    int        `json:"priority,omitempty"`
+	Labels      []string   `json:"labels,omitempty"`
+	// Recurrence is RRULE of RFC 5545, e.g. FREQ=MONTHLY
+	Recurrence string `json:"recurrence,omitempty"`
+	// StatusID is the board's column of the todo, it's set only for lists with statuses
+	StatusID  int       `json:"status_id,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// CommentCount is loaded only by queries of todos for clients, so events
+	// don't carry it
+	CommentCount int `json:"-"`
 }
 
 // UpdateListData it is a DTO for passing data to the List service layer
@@ -24,6 +63,35 @@ type UpdateListData struct {
 
 // UpdateItemData it is a DTO for passing data to the Todo service layer
 type UpdateItemData struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
+	Title       *string    `json:"title"`
+	Description *string    `json:"description"`
+	Done        *bool      `json:"done"`
+	Due         *time.Time `json:"due"`
+	// AssigneeID assigns the todo to the member of its list, zero unassigns it
+	AssigneeID *int      `json:"assignee_id"`
+	Priority   *int      `json:"priority"`
+	Labels     *[]string `json:"labels"`
+	Recurrence *string   `json:"recurrence"`
+	// StatusID moves the todo to the column of the list's board
+	StatusID *int `json:"status_id"`
+	// ClearDue removes the due date, it takes precedence over Due
+	ClearDue bool `json:"-"`
+}
+
+// ListWithTodos it is an entity that represents user's list together with its todos
+type ListWithTodos struct {
+	Todolist
+	Todos []TodoItem `json:"todos"`
+}
+
+// AssignedTodo it is an entity that represents todo assigned to the user together with ID of its list
+type AssignedTodo struct {
+	ListID int `json:"list_id"`
+	TodoItem
+}
+
+// TodoAssignment it is a payload of the event about changed assignee of the todo
+type TodoAssignment struct {
+	TodoItem
+	PreviousAssigneeID int `json:"previous_assignee_id,omitempty"`
 }
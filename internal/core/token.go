@@ -0,0 +1,39 @@
+// Package core represents domain's entities
+package core
+
+import "time"
+
+// Scopes which can be granted to the personal access token
+const (
+	ScopeListsRead     = "lists:read"
+	ScopeListsWrite    = "lists:write"
+	ScopeTodosRead     = "todos:read"
+	ScopeTodosWrite    = "todos:write"
+	ScopeWebhooksRead  = "webhooks:read"
+	ScopeWebhooksWrite = "webhooks:write"
+)
+
+// Scopes contains all scopes which can be granted to the personal access token
+var Scopes = []string{
+	ScopeListsRead,
+	ScopeListsWrite,
+	ScopeTodosRead,
+	ScopeTodosWrite,
+	ScopeWebhooksRead,
+	ScopeWebhooksWrite,
+}
+
+// AccessToken it is an entity that represents user's personal access token.
+// Token holds the secret only when it's created, only its hash is stored
+type AccessToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Token      string     `json:"token,omitempty"`
+	Hash       string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+// Package core represents domain's entities
+package core
+
+// TOTP it is an entity that represents user's secret for time-based one-time
+// passwords. LastUsedStep prevents reusing the same code twice
+type TOTP struct {
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+}
+
+// TOTPEnrollment it is a DTO for passing the new secret to the authenticator app
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// SignIn it is an entity that represents outcome of the password check. When
+// user has two-factor authentication enabled, it holds challenge token instead
+// of access one, the challenge has to be exchanged with one-time password
+type SignIn struct {
+	Token          string
+	ChallengeToken string
+}
@@ -1,10 +1,43 @@
 // Package core represents domain's entities
 package core
 
+import "time"
+
 // User it is an entity of end user for this application
 type User struct {
 	ID       int    `json:"-"`
 	Name     string `json:"name"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Email    string `json:"email,omitempty"`
+	// EmailVerified is set when the user has confirmed the email
+	EmailVerified bool `json:"-"`
+	// TokenVersion is increased when all user's tokens are revoked
+	TokenVersion int       `json:"-"`
+	Role         string    `json:"-"`
+	Disabled     bool      `json:"-"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+// UpdateUserData it is a DTO for passing data to the Account service layer
+type UpdateUserData struct {
+	Name     *string `json:"name"`
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+}
+
+// Purposes of the one-time tokens which are sent by email
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+)
+
+// OneTimeToken it is an entity that represents single-use token sent to the
+// user's email, only its hash is stored
+type OneTimeToken struct {
+	UserID    int
+	Purpose   string
+	Hash      string
+	Email     string
+	ExpiresAt time.Time
 }
@@ -0,0 +1,62 @@
+// Package core represents domain's entities
+package core
+
+import "time"
+
+// Roles of the workspace members
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+	WorkspaceRoleViewer = "viewer"
+)
+
+var WorkspaceRoles = []string{WorkspaceRoleOwner, WorkspaceRoleAdmin, WorkspaceRoleMember, WorkspaceRoleViewer}
+
+// Workspace it is an entity that represents a team space which owns lists
+type Workspace struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Personal bool   `json:"personal"`
+	// Role is the role of the user who requested the workspace
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorkspaceMember it is an entity that represents membership of the user in the workspace
+type WorkspaceMember struct {
+	UserID   int       `json:"user_id"`
+	Name     string    `json:"name"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// WorkspaceInvite it is an entity that represents invitation to the workspace,
+// only hash of its token is stored
+type WorkspaceInvite struct {
+	ID          int       `json:"id"`
+	WorkspaceID int       `json:"workspace_id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	Token       string    `json:"token,omitempty"`
+	Hash        string    `json:"-"`
+	InvitedBy   int       `json:"invited_by"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// UpdateWorkspaceData it is a DTO for passing data to the Workspace service layer
+type UpdateWorkspaceData struct {
+	Name *string `json:"name"`
+}
+
+// CanWrite reports whether the role allows changing lists and todos
+func CanWrite(role string) bool {
+	return role == WorkspaceRoleOwner || role == WorkspaceRoleAdmin || role == WorkspaceRoleMember
+}
+
+// CanManage reports whether the role allows managing members and invitations
+func CanManage(role string) bool {
+	return role == WorkspaceRoleOwner || role == WorkspaceRoleAdmin
+}
@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// csvWriter writes one row per todo, the header matches the one accepted by the importer
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (cw *csvWriter) WriteList(list core.Todolist, todos []core.TodoItem) error {
+	if !cw.header {
+		if err := cw.w.Write([]string{"list", "title", "description", "done", "due"}); err != nil {
+			return err
+		}
+		cw.header = true
+	}
+	for _, t := range todos {
+		due := ""
+		if t.Due != nil {
+			due = t.Due.UTC().Format(time.RFC3339)
+		}
+		record := []string{list.Title, t.Title, t.Description, strconv.FormatBool(t.Done), due}
+		if err := cw.w.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	if !cw.header {
+		return cw.WriteList(core.Todolist{}, nil)
+	}
+	return nil
+}
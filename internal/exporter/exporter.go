@@ -0,0 +1,57 @@
+// Package exporter writes lists and todos in formats suitable for other tools
+package exporter
+
+import (
+	"errors"
+	"io"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Supported formats of exported files
+const (
+	FormatCSV      = "csv"
+	FormatJSON     = "json"
+	FormatMarkdown = "md"
+	FormatICS      = "ics"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Writer streams lists one by one, so the whole data set is never kept in memory
+type Writer interface {
+	// WriteList writes the list together with its todos
+	WriteList(list core.Todolist, todos []core.TodoItem) error
+	// Close writes the end of the document, it doesn't close the underlying writer
+	Close() error
+}
+
+// NewWriter returns writer of the given format
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatJSON:
+		return newJSONWriter(w), nil
+	case FormatMarkdown:
+		return newMarkdownWriter(w), nil
+	case FormatICS:
+		return newICSWriter(w), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType returns MIME type of the given format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSON:
+		return "application/json"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatICS:
+		return "text/calendar; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,173 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+const (
+	icsTimeFormat = "20060102T150405Z"
+	icsLineLimit  = 75
+	icsProdID     = "-//todo-app//EN"
+)
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icsWriter writes iCalendar (RFC 5545) document with VTODO component per todo
+type icsWriter struct {
+	w       io.Writer
+	started bool
+	stamp   string
+}
+
+func newICSWriter(w io.Writer) *icsWriter {
+	return &icsWriter{w: w, stamp: FormatICSTime(time.Now())}
+}
+
+func (iw *icsWriter) WriteList(list core.Todolist, todos []core.TodoItem) error {
+	var b strings.Builder
+	if !iw.started {
+		iw.start(&b)
+	}
+	for _, t := range todos {
+		WriteVTODO(&b, list, t, iw.stamp)
+	}
+	_, err := io.WriteString(iw.w, b.String())
+	return err
+}
+
+func (iw *icsWriter) Close() error {
+	var b strings.Builder
+	if !iw.started {
+		iw.start(&b)
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+	_, err := io.WriteString(iw.w, b.String())
+	return err
+}
+
+func (iw *icsWriter) start(b *strings.Builder) {
+	writeCalendarHeader(b)
+	iw.started = true
+}
+
+// CalendarObject returns iCalendar object with the single todo. It doesn't depend
+// on the time of the call, so the object stays the same until the todo is changed
+func CalendarObject(t core.TodoItem) string {
+	var b strings.Builder
+	writeCalendarHeader(&b)
+	WriteVTODO(&b, core.Todolist{}, t, FormatICSTime(t.UpdatedAt))
+	writeICSLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+func writeCalendarHeader(b *strings.Builder) {
+	writeICSLine(b, "BEGIN:VCALENDAR")
+	writeICSLine(b, "VERSION:2.0")
+	writeICSLine(b, "PRODID:"+icsProdID)
+	writeICSLine(b, "CALSCALE:GREGORIAN")
+}
+
+// WriteVTODO writes the todo as VTODO component, stamp is a DTSTAMP value
+func WriteVTODO(b *strings.Builder, list core.Todolist, t core.TodoItem, stamp string) {
+	writeICSLine(b, "BEGIN:VTODO")
+	writeICSLine(b, "UID:"+icsEscaper.Replace(TodoUID(t)))
+	writeICSLine(b, "DTSTAMP:"+stamp)
+	if !t.UpdatedAt.IsZero() {
+		writeICSLine(b, "LAST-MODIFIED:"+FormatICSTime(t.UpdatedAt))
+	}
+	writeICSLine(b, "SUMMARY:"+icsEscaper.Replace(t.Title))
+	if t.Description != "" {
+		writeICSLine(b, "DESCRIPTION:"+icsEscaper.Replace(t.Description))
+	}
+	categories := make([]string, 0, len(t.Labels)+1)
+	if list.Title != "" {
+		categories = append(categories, icsEscaper.Replace(list.Title))
+	}
+	for _, label := range t.Labels {
+		categories = append(categories, icsEscaper.Replace(label))
+	}
+	if len(categories) > 0 {
+		writeICSLine(b, "CATEGORIES:"+strings.Join(categories, ","))
+	}
+	if t.Priority != core.PriorityNone {
+		writeICSLine(b, fmt.Sprintf("PRIORITY:%d", ICSPriority(t.Priority)))
+	}
+	if t.Due != nil {
+		writeICSLine(b, "DUE:"+FormatICSTime(*t.Due))
+	}
+	if t.Recurrence != "" {
+		writeICSLine(b, "RRULE:"+t.Recurrence)
+	}
+	if t.Done {
+		writeICSLine(b, "STATUS:COMPLETED")
+		writeICSLine(b, "PERCENT-COMPLETE:100")
+	} else {
+		writeICSLine(b, "STATUS:NEEDS-ACTION")
+	}
+	writeICSLine(b, "END:VTODO")
+}
+
+// TodoUID returns globally unique identifier of the todo in calendars,
+// UID given by a calendar client is preserved
+func TodoUID(t core.TodoItem) string {
+	if t.UID != "" {
+		return t.UID
+	}
+	return fmt.Sprintf("todo-%d@todo-app", t.ID)
+}
+
+// ICSPriority maps priority of the todo to PRIORITY value of iCalendar, where
+// 1 is the highest and 9 is the lowest one
+func ICSPriority(priority int) int {
+	switch priority {
+	case core.PriorityHigh:
+		return 1
+	case core.PriorityMedium:
+		return 5
+	case core.PriorityLow:
+		return 9
+	}
+	return 0
+}
+
+// PriorityFromICS maps PRIORITY value of iCalendar to priority of the todo
+func PriorityFromICS(value int) int {
+	switch {
+	case value >= 1 && value <= 4:
+		return core.PriorityHigh
+	case value == 5:
+		return core.PriorityMedium
+	case value >= 6 && value <= 9:
+		return core.PriorityLow
+	}
+	return core.PriorityNone
+}
+
+// FormatICSTime returns time in the UTC form of iCalendar DATE-TIME value
+func FormatICSTime(t time.Time) string {
+	return t.UTC().Format(icsTimeFormat)
+}
+
+// writeICSLine writes content line folded to 75 octets as required by RFC 5545
+func writeICSLine(b *strings.Builder, line string) {
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		// don't split multi-byte characters
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space, which counts into the limit
+		limit = icsLineLimit - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// jsonWriter writes {"lists": [...]} document, which can be imported back
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONWriter(w io.Writer) *jsonWriter {
+	return &jsonWriter{w: w}
+}
+
+func (jw *jsonWriter) WriteList(list core.Todolist, todos []core.TodoItem) error {
+	prefix := ","
+	if jw.count == 0 {
+		prefix = `{"lists":[`
+	}
+	if todos == nil {
+		todos = make([]core.TodoItem, 0)
+	}
+	data, err := json.Marshal(core.ListWithTodos{Todolist: list, Todos: todos})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(jw.w, prefix); err != nil {
+		return err
+	}
+	jw.count++
+	_, err = jw.w.Write(data)
+	return err
+}
+
+func (jw *jsonWriter) Close() error {
+	end := "]}\n"
+	if jw.count == 0 {
+		end = `{"lists":[]}` + "\n"
+	}
+	_, err := io.WriteString(jw.w, end)
+	return err
+}
@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// markdownWriter writes every list as a heading followed by a checklist
+type markdownWriter struct {
+	w     io.Writer
+	count int
+}
+
+func newMarkdownWriter(w io.Writer) *markdownWriter {
+	return &markdownWriter{w: w}
+}
+
+func (mw *markdownWriter) WriteList(list core.Todolist, todos []core.TodoItem) error {
+	var b strings.Builder
+	if mw.count > 0 {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "# %s\n\n", oneLine(list.Title))
+	if list.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", list.Description)
+	}
+	for _, t := range todos {
+		mark := " "
+		if t.Done {
+			mark = "x"
+		}
+		fmt.Fprintf(&b, "- [%s] %s", mark, oneLine(t.Title))
+		if t.Due != nil {
+			fmt.Fprintf(&b, " (due %s)", t.Due.UTC().Format("2006-01-02"))
+		}
+		b.WriteString("\n")
+	}
+	mw.count++
+	_, err := io.WriteString(mw.w, b.String())
+	return err
+}
+
+func (mw *markdownWriter) Close() error {
+	return nil
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
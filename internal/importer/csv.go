@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// parseCSV reads rows with a header containing title and optional list,
+// description, done and due columns
+func parseCSV(r io.Reader, b *builder) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		b.fail(csvErrLine(err, 1), "invalid csv header: %v", err)
+		return nil
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := cols["title"]; !ok {
+		b.fail(1, "missing required title column")
+		return nil
+	}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			// the reader can't recover after malformed quotes, so parsing stops here
+			b.fail(csvErrLine(err, 0), "invalid csv: %v", err)
+			return nil
+		}
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		done, ok := parseBool(field("done"))
+		if !ok {
+			b.fail(line, "invalid done value %q", field("done"))
+			continue
+		}
+		due, err := parseDue(field("due"))
+		if err != nil {
+			b.fail(line, "invalid due value %q", field("due"))
+			continue
+		}
+		b.addTodo(line, field("list"), core.TodoItem{
+			Title:       field("title"),
+			Description: field("description"),
+			Done:        done,
+			Due:         due,
+		})
+	}
+}
+
+func csvErrLine(err error, def int) int {
+	var pErr *csv.ParseError
+	if errors.As(err, &pErr) {
+		return pErr.Line
+	}
+	return def
+}
+
+func parseBool(s string) (bool, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "no", "n":
+		return false, true
+	case "x", "yes", "y", "done":
+		return true, true
+	}
+	v, err := strconv.ParseBool(s)
+	return v, err == nil
+}
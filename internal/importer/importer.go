@@ -0,0 +1,160 @@
+// Package importer parses lists and todos exported from other tools
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Supported formats of imported files
+const (
+	FormatCSV      = "csv"
+	FormatJSON     = "json"
+	FormatTodoTxt  = "todotxt"
+	FormatMarkdown = "md"
+)
+
+// DefaultList is a title of the list for todos which don't specify their own
+const DefaultList = "Imported"
+
+// maxFieldLength is a limit of titles and descriptions length in the DB
+const maxFieldLength = 255
+
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// Parse reads lists and todos in the given format. Todos which don't belong to
+// any list are placed into the list with defaultList title. Problems with
+// particular lines are returned as import errors, so the whole file can be
+// reported at once
+func Parse(format string, r io.Reader, defaultList string) ([]core.ListWithTodos, []core.ImportError, error) {
+	if defaultList == "" {
+		defaultList = DefaultList
+	}
+	b := newBuilder(defaultList)
+	var err error
+	switch format {
+	case FormatCSV:
+		err = parseCSV(r, b)
+	case FormatJSON:
+		err = parseJSON(r, b)
+	case FormatTodoTxt:
+		err = parseTodoTxt(r, b)
+	case FormatMarkdown:
+		err = parseMarkdown(r, b)
+	default:
+		return nil, nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.lists, b.errs, nil
+}
+
+// FormatFromFilename guesses format of the file by its extension
+func FormatFromFilename(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	case ".txt":
+		return FormatTodoTxt
+	case ".md", ".markdown":
+		return FormatMarkdown
+	}
+	return ""
+}
+
+// builder groups parsed todos by lists preserving their order
+type builder struct {
+	defaultList string
+	lists       []core.ListWithTodos
+	index       map[string]int
+	errs        []core.ImportError
+}
+
+func newBuilder(defaultList string) *builder {
+	return &builder{
+		defaultList: defaultList,
+		index:       make(map[string]int),
+	}
+}
+
+// addList returns position of the list with the given title, creating it if needed
+func (b *builder) addList(line int, title, description string) (int, bool) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = b.defaultList
+	}
+	if i, ok := b.index[title]; ok {
+		return i, true
+	}
+	if !b.valid(line, "list title", title) || !b.valid(line, "list description", description) {
+		return 0, false
+	}
+	b.lists = append(b.lists, core.ListWithTodos{
+		Todolist: core.Todolist{Title: title, Description: description},
+		Todos:    make([]core.TodoItem, 0),
+	})
+	b.index[title] = len(b.lists) - 1
+	return len(b.lists) - 1, true
+}
+
+func (b *builder) addTodo(line int, listTitle string, todo core.TodoItem) {
+	todo.Title = strings.TrimSpace(todo.Title)
+	todo.Description = strings.TrimSpace(todo.Description)
+	if todo.Title == "" {
+		b.fail(line, "missing todo title")
+		return
+	}
+	if !b.valid(line, "todo title", todo.Title) || !b.valid(line, "todo description", todo.Description) {
+		return
+	}
+	i, ok := b.addList(line, listTitle, "")
+	if !ok {
+		return
+	}
+	b.lists[i].Todos = append(b.lists[i].Todos, todo)
+}
+
+func (b *builder) valid(line int, field, value string) bool {
+	if utf8.RuneCountInString(value) > maxFieldLength {
+		b.fail(line, "%s is longer than %d characters", field, maxFieldLength)
+		return false
+	}
+	return true
+}
+
+func (b *builder) fail(line int, format string, args ...interface{}) {
+	b.errs = append(b.errs, core.ImportError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// withPrefix adds location to the messages of errors which happened inside fn
+func (b *builder) withPrefix(prefix string, fn func()) {
+	before := len(b.errs)
+	fn()
+	for i := before; i < len(b.errs); i++ {
+		b.errs[i].Message = prefix + ": " + b.errs[i].Message
+	}
+}
+
+// parseDue accepts either RFC 3339 timestamp or a date, which means its midnight in UTC
+func parseDue(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date %q", s)
+}
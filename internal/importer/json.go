@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// document is a shape of the JSON export
+type document struct {
+	Lists []core.ListWithTodos `json:"lists"`
+}
+
+// parseJSON reads lists in the same shape as they are exported
+func parseJSON(r io.Reader, b *builder) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		var (
+			syntaxErr *json.SyntaxError
+			typeErr   *json.UnmarshalTypeError
+		)
+		switch {
+		case errors.As(err, &syntaxErr):
+			b.fail(lineAt(data, syntaxErr.Offset), "invalid json: %v", err)
+		case errors.As(err, &typeErr):
+			b.fail(lineAt(data, typeErr.Offset), "invalid json: %v", err)
+		default:
+			b.fail(0, "invalid json: %v", err)
+		}
+		return nil
+	}
+	for i, l := range doc.Lists {
+		if l.Title == "" {
+			b.fail(0, "lists[%d]: missing list title", i)
+			continue
+		}
+		var ok bool
+		b.withPrefix(fmt.Sprintf("lists[%d]", i), func() {
+			_, ok = b.addList(0, l.Title, l.Description)
+		})
+		if !ok {
+			continue
+		}
+		for j, t := range l.Todos {
+			b.withPrefix(fmt.Sprintf("lists[%d].todos[%d]", i, j), func() {
+				b.addTodo(0, l.Title, core.TodoItem{Title: t.Title, Description: t.Description, Done: t.Done, Due: t.Due})
+			})
+		}
+	}
+	return nil
+}
+
+// lineAt returns number of the line containing byte with the given offset
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
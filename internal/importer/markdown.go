@@ -0,0 +1,46 @@
+package importer
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+var (
+	mdHeading   = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*\s*$`)
+	mdChecklist = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	mdItemLike  = regexp.MustCompile(`^\s*[-*+]\s+\[.?\](\s|$)`)
+)
+
+// parseMarkdown reads "- [ ]" and "- [x]" checklist items, headings start new lists
+// and any other text is ignored
+func parseMarkdown(r io.Reader, b *builder) error {
+	s := bufio.NewScanner(r)
+	var (
+		line int
+		list string
+	)
+	for s.Scan() {
+		line++
+		text := s.Text()
+		if m := mdHeading.FindStringSubmatch(text); m != nil {
+			list = m[1]
+			continue
+		}
+		m := mdChecklist.FindStringSubmatch(text)
+		if m == nil {
+			if mdItemLike.MatchString(text) {
+				b.fail(line, "malformed checklist item")
+			}
+			continue
+		}
+		b.addTodo(line, list, core.TodoItem{
+			Title: strings.TrimSpace(m[2]),
+			Done:  m[1] != " ",
+		})
+	}
+	return s.Err()
+}
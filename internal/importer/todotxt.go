@@ -0,0 +1,87 @@
+package importer
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+var (
+	todoTxtDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	todoTxtPriority = regexp.MustCompile(`^\([A-Z]\)$`)
+)
+
+// parseTodoTxt reads tasks in todo.txt format, the first +project of a task
+// becomes its list, due:date sets due date and other key:value tags are kept
+// in the description
+func parseTodoTxt(r io.Reader, b *builder) error {
+	s := bufio.NewScanner(r)
+	line := 0
+	for s.Scan() {
+		line++
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var todo core.TodoItem
+		if fields[0] == "x" {
+			todo.Done = true
+			fields = fields[1:]
+			// completion date goes before the creation one
+			fields = skipDates(fields, 2)
+		} else {
+			if len(fields) > 0 && todoTxtPriority.MatchString(fields[0]) {
+				fields = fields[1:]
+			}
+			fields = skipDates(fields, 1)
+		}
+		var (
+			list  string
+			title []string
+			tags  []string
+		)
+		valid := true
+		for _, f := range fields {
+			switch {
+			case strings.HasPrefix(f, "due:"):
+				due, err := parseDue(strings.TrimPrefix(f, "due:"))
+				if err != nil {
+					b.fail(line, "invalid due date %q", f)
+					valid = false
+				}
+				todo.Due = due
+			case strings.HasPrefix(f, "+") && len(f) > 1:
+				if list == "" {
+					list = f[1:]
+				}
+			case isTodoTxtTag(f):
+				tags = append(tags, f)
+			default:
+				title = append(title, f)
+			}
+		}
+		if !valid {
+			continue
+		}
+		todo.Title = strings.Join(title, " ")
+		todo.Description = strings.Join(tags, " ")
+		b.addTodo(line, list, todo)
+	}
+	return s.Err()
+}
+
+func skipDates(fields []string, max int) []string {
+	for i := 0; i < max && len(fields) > 0 && todoTxtDate.MatchString(fields[0]); i++ {
+		fields = fields[1:]
+	}
+	return fields
+}
+
+// isTodoTxtTag reports whether the word is a key:value tag, but not an URL
+func isTodoTxtTag(f string) bool {
+	i := strings.Index(f, ":")
+	return i > 0 && i < len(f)-1 && !strings.Contains(f, "://")
+}
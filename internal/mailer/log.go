@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Log is a mailer for local development, it writes emails into the directory
+// as .eml files or into the log when the directory isn't set
+type Log struct {
+	from string
+	dir  string
+	log  *zap.Logger
+}
+
+// NewLog returns instance of Log mailer
+func NewLog(from, dir string, log *zap.Logger) *Log {
+	return &Log{from, dir, log}
+}
+
+func (l *Log) Send(ctx context.Context, m Message) error {
+	if !validHeader(m.To) || !validHeader(m.Subject) {
+		return errInvalidHeader
+	}
+	if l.dir == "" {
+		l.log.Info("Mail", zap.String("to", m.To), zap.String("subject", m.Subject), zap.String("body", m.Body))
+		return nil
+	}
+	if err := os.MkdirAll(l.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	if err := os.WriteFile(filepath.Join(l.dir, name), format(l.from, m), 0o644); err != nil {
+		return err
+	}
+	l.log.Info("Mail saved", zap.String("to", m.To), zap.String("file", name))
+	return nil
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory keeps sent emails in memory, so tests can inspect them
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemory returns instance of Memory mailer
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns copy of all sent emails
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Last returns the latest email sent to the address
+func (m *Memory) Last(to string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i], true
+		}
+	}
+	return Message{}, false
+}
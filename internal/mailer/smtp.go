@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+)
+
+var errInvalidHeader = errors.New("mail headers can't contain line breaks")
+
+// SMTPConfig represents all required fields for connecting to SMTP server
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP sends emails through SMTP server, STARTTLS is used when the server supports it
+type SMTP struct {
+	cfg SMTPConfig
+}
+
+// NewSMTP returns instance of SMTP mailer
+func NewSMTP(cfg SMTPConfig) *SMTP {
+	return &SMTP{cfg}
+}
+
+// Send sends the message, net/smtp doesn't support cancellation, so the context
+// is only checked before sending
+func (s *SMTP) Send(ctx context.Context, m Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !validHeader(m.To) || !validHeader(m.Subject) {
+		return errInvalidHeader
+	}
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+	addr := net.JoinHostPort(s.cfg.Host, s.cfg.Port)
+	return smtp.SendMail(addr, auth, s.cfg.From, []string{m.To}, format(s.cfg.From, m))
+}
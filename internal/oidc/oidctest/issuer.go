@@ -0,0 +1,246 @@
+// Package oidctest implements a local OpenID provider stand-in. It signs in the
+// configured identity without asking for credentials, so it's only suitable
+// for development and integration tests
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/vbetsun/todo-app/internal/oidc"
+)
+
+const (
+	keyID    = "oidctest"
+	codeTTL  = time.Minute
+	tokenTTL = time.Hour
+)
+
+// Identity represents the user who is signed in by the issuer
+type Identity struct {
+	Subject           string
+	Email             string
+	Name              string
+	PreferredUsername string
+}
+
+// Issuer is an OpenID provider which approves every authorization request
+type Issuer struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	// Identity is signed in unless the authorization request has login_hint,
+	// which is used as subject then
+	Identity Identity
+
+	key   *rsa.PrivateKey
+	mux   *http.ServeMux
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+// grant represents the issued authorization code
+type grant struct {
+	identity    Identity
+	redirectURI string
+	nonce       string
+	challenge   string
+	expiresAt   time.Time
+}
+
+// NewIssuer returns the issuer which is served at the URL
+func NewIssuer(issuerURL, clientID string) (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	i := &Issuer{
+		URL:      issuerURL,
+		ClientID: clientID,
+		Identity: Identity{
+			Subject:           "oidctest-user",
+			Email:             "user@example.com",
+			Name:              "Test User",
+			PreferredUsername: "testuser",
+		},
+		key:   key,
+		mux:   http.NewServeMux(),
+		codes: make(map[string]grant),
+	}
+	i.mux.HandleFunc("/.well-known/openid-configuration", i.discovery)
+	i.mux.HandleFunc("/authorize", i.authorize)
+	i.mux.HandleFunc("/token", i.token)
+	i.mux.HandleFunc("/jwks", i.jwks)
+	return i, nil
+}
+
+// NewServer starts the issuer on the local loopback interface, the caller
+// should close the server when finished
+func NewServer(clientID string) (*Issuer, *httptest.Server, error) {
+	var issuer *Issuer
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issuer.ServeHTTP(w, r)
+	}))
+	issuer, err := NewIssuer(srv.URL, clientID)
+	if err != nil {
+		srv.Close()
+		return nil, nil, err
+	}
+	return issuer, srv, nil
+}
+
+func (i *Issuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i.mux.ServeHTTP(w, r)
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                i.URL,
+		"authorization_endpoint":                i.URL + "/authorize",
+		"token_endpoint":                        i.URL + "/token",
+		"jwks_uri":                              i.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize issues the code and redirects the user back to the client
+func (i *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("client_id") != i.ClientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "only code flow with S256 PKCE is supported", http.StatusBadRequest)
+		return
+	}
+	identity := i.Identity
+	if hint := q.Get("login_hint"); hint != "" {
+		identity = Identity{Subject: hint, Email: hint + "@example.com", Name: hint, PreferredUsername: hint}
+	}
+	code, err := oidc.RandomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	i.mu.Lock()
+	i.codes[code] = grant{
+		identity:    identity,
+		redirectURI: redirectURI.String(),
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		expiresAt:   time.Now().Add(codeTTL),
+	}
+	i.mu.Unlock()
+	back := redirectURI.Query()
+	back.Set("code", code)
+	back.Set("state", q.Get("state"))
+	redirectURI.RawQuery = back.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// token exchanges the code for signed ID token. Codes can be used only once
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != i.ClientID || subtle.ConstantTimeCompare([]byte(secret), []byte(i.ClientSecret)) != 1 {
+		tokenError(w, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+	code := r.PostForm.Get("code")
+	i.mu.Lock()
+	g, ok := i.codes[code]
+	delete(i.codes, code)
+	i.mu.Unlock()
+	if !ok || time.Now().After(g.expiresAt) || g.redirectURI != r.PostForm.Get("redirect_uri") ||
+		oidc.Challenge(r.PostForm.Get("code_verifier")) != g.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, oidc.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    i.URL,
+			Subject:   g.identity.Subject,
+			Audience:  jwt.ClaimStrings{i.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenTTL)),
+		},
+		Nonce:             g.nonce,
+		Email:             g.identity.Email,
+		EmailVerified:     g.identity.Email != "",
+		Name:              g.identity.Name,
+		PreferredUsername: g.identity.PreferredUsername,
+	})
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(i.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": idToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(tokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := i.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,89 @@
+// Package pubsub implements in-process delivery of events to their subscribers
+package pubsub
+
+import (
+	"sync"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// subscriptionBuffer is an amount of events which can wait for a slow subscriber
+// before it is disconnected
+const subscriptionBuffer = 64
+
+// Hub fans out events of every list to the subscribers of that list
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[int]map[*Subscription]struct{}
+}
+
+// Subscription represents a single consumer of list's events
+type Subscription struct {
+	listID int
+	events chan core.Event
+	hub    *Hub
+	once   sync.Once
+}
+
+// NewHub returns instance of events hub
+func NewHub() *Hub {
+	return &Hub{subs: make(map[int]map[*Subscription]struct{})}
+}
+
+// Subscribe registers new consumer of the given list's events
+func (h *Hub) Subscribe(listID int) *Subscription {
+	s := &Subscription{
+		listID: listID,
+		events: make(chan core.Event, subscriptionBuffer),
+		hub:    h,
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[listID] == nil {
+		h.subs[listID] = make(map[*Subscription]struct{})
+	}
+	h.subs[listID][s] = struct{}{}
+	return s
+}
+
+// Broadcast sends event to all subscribers of its list without blocking.
+// Subscribers which can't keep up are closed, so they have to resume
+// from the last received event
+func (h *Hub) Broadcast(e core.Event) {
+	h.mu.RLock()
+	var slow []*Subscription
+	for s := range h.subs[e.ListID] {
+		select {
+		case s.events <- e:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+	for _, s := range slow {
+		s.Close()
+	}
+}
+
+func (h *Hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs[s.listID], s)
+	if len(h.subs[s.listID]) == 0 {
+		delete(h.subs, s.listID)
+	}
+}
+
+// Events returns channel of the subscribed list's events.
+// The channel is closed when subscription is closed
+func (s *Subscription) Events() <-chan core.Event {
+	return s.events
+}
+
+// Close unregisters subscription from the hub
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.remove(s)
+		close(s.events)
+	})
+}
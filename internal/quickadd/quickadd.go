@@ -0,0 +1,570 @@
+// Package quickadd parses free text of the new todo, such as
+// "Pay rent tomorrow 9am #home !high every month", into the todo's title, due
+// date, labels, priority and recurrence. Relative dates are resolved in the
+// location of the given current time. Todos which have a date but no time are
+// due by the end of that day
+package quickadd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Result holds attributes of the todo found in the text
+type Result struct {
+	Title    string
+	Due      *time.Time
+	Labels   []string
+	Priority int
+	// Recurrence is RRULE of RFC 5545, e.g. FREQ=WEEKLY;INTERVAL=2;BYDAY=MO
+	Recurrence string
+}
+
+var (
+	labelPattern   = regexp.MustCompile(`^#(\p{L}[\p{L}\p{N}_\-]*)$`)
+	isoDatePattern = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	dayPattern     = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+	yearPattern    = regexp.MustCompile(`^\d{4}$`)
+	clockPattern   = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+	hourPattern    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?$`)
+	clock24Pattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+)
+
+var priorities = map[string]int{
+	"!low":    core.PriorityLow,
+	"!med":    core.PriorityMedium,
+	"!medium": core.PriorityMedium,
+	"!high":   core.PriorityHigh,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+var byDayCodes = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// frequencies maps units of recurrence to FREQ values
+var frequencies = map[string]string{
+	"day": "DAILY", "days": "DAILY",
+	"week": "WEEKLY", "weeks": "WEEKLY",
+	"month": "MONTHLY", "months": "MONTHLY",
+	"year": "YEARLY", "years": "YEARLY",
+}
+
+// prepositions may precede dates and times, they're removed from the title
+// together with the expression
+var prepositions = map[string]bool{"on": true, "at": true, "by": true, "due": true}
+
+type clock struct {
+	hour, min int
+}
+
+// Parser extracts attributes of the todo from the text according to the
+// user's preferences
+type Parser struct {
+	// WeekStart is the first day of the week, "next week" is due on it
+	WeekStart time.Weekday
+}
+
+type parser struct {
+	now       time.Time
+	weekStart time.Weekday
+
+	date  *time.Time // midnight of the due date
+	clock *clock
+	exact *time.Time // due time given relatively to now, e.g. "in 2 hours"
+	// evening is set by "tonight", it's the default time of the day
+	evening bool
+
+	labels     []string
+	priority   int
+	freq       string
+	interval   int
+	byDay      []time.Weekday
+	recurrence bool
+}
+
+// Parse extracts attributes of the todo from the text with weeks starting on
+// Monday, see Parser.Parse
+func Parse(text string, now time.Time) Result {
+	return Parser{WeekStart: time.Monday}.Parse(text, now)
+}
+
+// Parse extracts attributes of the todo from the text, the rest of the text
+// becomes the title. Only the first occurrence of the date, time, priority and
+// recurrence is taken, the following ones stay in the title
+func (ps Parser) Parse(text string, now time.Time) Result {
+	p := &parser{now: now, weekStart: ps.WeekStart}
+	tokens := strings.Fields(text)
+	var title []string
+	for i := 0; i < len(tokens); {
+		if n := p.match(tokens[i:]); n > 0 {
+			i += n
+			continue
+		}
+		title = append(title, tokens[i])
+		i++
+	}
+	return Result{
+		Title:      strings.Join(title, " "),
+		Due:        p.due(),
+		Labels:     p.labels,
+		Priority:   p.priority,
+		Recurrence: p.rrule(),
+	}
+}
+
+// match consumes the expression at the beginning of tokens and returns number
+// of the consumed tokens, zero means tokens don't start with an expression
+func (p *parser) match(tokens []string) int {
+	word := normalize(tokens[0])
+	if m := labelPattern.FindStringSubmatch(strings.TrimRight(tokens[0], ",.;")); m != nil {
+		p.addLabel(strings.ToLower(m[1]))
+		return 1
+	}
+	if priority, ok := priorities[word]; ok && p.priority == 0 {
+		p.priority = priority
+		return 1
+	}
+	if !p.recurrence {
+		if n := p.matchRecurrence(tokens); n > 0 {
+			return n
+		}
+	}
+	skip := 0
+	if prepositions[word] && len(tokens) > 1 {
+		skip = 1
+	}
+	for ; skip >= 0; skip-- {
+		if n := p.matchWhen(tokens[skip:]); n > 0 {
+			return skip + n
+		}
+	}
+	return 0
+}
+
+// matchWhen consumes the date or time which aren't set yet
+func (p *parser) matchWhen(tokens []string) int {
+	if p.date == nil && p.exact == nil {
+		if n := p.matchDate(tokens); n > 0 {
+			return n
+		}
+	}
+	if p.clock == nil && p.exact == nil {
+		if c, n := matchClock(tokens); n > 0 {
+			p.clock = &c
+			return n
+		}
+	}
+	return 0
+}
+
+func (p *parser) addLabel(label string) {
+	for _, l := range p.labels {
+		if l == label {
+			return
+		}
+	}
+	p.labels = append(p.labels, label)
+}
+
+// matchRecurrence consumes "every day", "every other week", "every 3 months",
+// "every weekday" and "every mon, wed and fri"
+func (p *parser) matchRecurrence(tokens []string) int {
+	if normalize(tokens[0]) != "every" || len(tokens) < 2 {
+		return 0
+	}
+	word := normalize(tokens[1])
+	if freq, ok := frequencies[word]; ok && !strings.HasSuffix(word, "s") {
+		p.setRecurrence(freq, 1, nil)
+		return 2
+	}
+	if word == "weekday" || word == "weekdays" {
+		p.setRecurrence("WEEKLY", 1, []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday})
+		return 2
+	}
+	if len(tokens) > 2 {
+		interval := 0
+		if word == "other" {
+			interval = 2
+		} else if n, err := strconv.Atoi(word); err == nil && n > 0 && n < 1000 {
+			interval = n
+		}
+		if freq, ok := frequencies[normalize(tokens[2])]; ok && interval > 0 {
+			p.setRecurrence(freq, interval, nil)
+			return 3
+		}
+	}
+	days, n := matchWeekdays(tokens[1:])
+	if n == 0 {
+		return 0
+	}
+	p.setRecurrence("WEEKLY", 1, days)
+	return n + 1
+}
+
+func (p *parser) setRecurrence(freq string, interval int, byDay []time.Weekday) {
+	p.recurrence = true
+	p.freq = freq
+	p.interval = interval
+	p.byDay = byDay
+}
+
+// matchWeekdays consumes the list of weekdays joined by commas and "and"
+func matchWeekdays(tokens []string) ([]time.Weekday, int) {
+	var (
+		days []time.Weekday
+		n    int
+	)
+	for i, token := range tokens {
+		word := normalize(token)
+		// "and" is consumed only when a weekday follows it
+		if word == "and" && len(days) > 0 {
+			continue
+		}
+		var parsed []time.Weekday
+		for _, part := range strings.Split(word, ",") {
+			day, ok := weekdays[part]
+			if !ok {
+				parsed = nil
+				break
+			}
+			parsed = append(parsed, day)
+		}
+		if len(parsed) == 0 {
+			break
+		}
+		for _, day := range parsed {
+			days = appendWeekday(days, day)
+		}
+		n = i + 1
+	}
+	return days, n
+}
+
+func appendWeekday(days []time.Weekday, day time.Weekday) []time.Weekday {
+	for _, d := range days {
+		if d == day {
+			return days
+		}
+	}
+	return append(days, day)
+}
+
+// matchDate consumes the date, it's resolved relatively to the current time
+func (p *parser) matchDate(tokens []string) int {
+	today := midnight(p.now)
+	word := normalize(tokens[0])
+	next := ""
+	if len(tokens) > 1 {
+		next = normalize(tokens[1])
+	}
+	switch word {
+	case "today", "tod":
+		p.date = &today
+		return 1
+	case "tonight":
+		p.date = &today
+		p.evening = true
+		return 1
+	case "tomorrow", "tmr", "tmrw":
+		d := today.AddDate(0, 0, 1)
+		p.date = &d
+		return 1
+	case "next":
+		switch next {
+		case "week":
+			d := nextWeekday(today, p.weekStart)
+			p.date = &d
+			return 2
+		case "month":
+			d := today.AddDate(0, 1, 0)
+			p.date = &d
+			return 2
+		case "year":
+			d := today.AddDate(1, 0, 0)
+			p.date = &d
+			return 2
+		}
+		if day, ok := weekdays[next]; ok {
+			d := nextWeekday(today, day)
+			p.date = &d
+			return 2
+		}
+		return 0
+	case "in":
+		return p.matchIn(tokens[1:])
+	}
+	if day, ok := weekdays[word]; ok {
+		d := nextWeekday(today, day)
+		p.date = &d
+		return 1
+	}
+	if m := isoDatePattern.FindStringSubmatch(word); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if d, ok := date(year, time.Month(month), day, p.now.Location()); ok {
+			p.date = &d
+			return 1
+		}
+		return 0
+	}
+	// "may 5", "may 5th 2025", "5 may" and "5th of may 2025"
+	var (
+		month time.Month
+		day   int
+		n     int
+	)
+	if m, ok := months[word]; ok && next != "" {
+		if dm := dayPattern.FindStringSubmatch(next); dm != nil {
+			month = m
+			day, _ = strconv.Atoi(dm[1])
+			n = 2
+		}
+	} else if dm := dayPattern.FindStringSubmatch(word); dm != nil && next != "" {
+		i := 1
+		if next == "of" && len(tokens) > 2 {
+			i = 2
+		}
+		if m, ok := months[normalize(tokens[i])]; ok {
+			month = m
+			day, _ = strconv.Atoi(dm[1])
+			n = i + 1
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	year := today.Year()
+	explicitYear := false
+	if len(tokens) > n && yearPattern.MatchString(normalize(tokens[n])) {
+		year, _ = strconv.Atoi(normalize(tokens[n]))
+		explicitYear = true
+		n++
+	}
+	d, ok := date(year, month, day, p.now.Location())
+	if !ok {
+		return 0
+	}
+	if !explicitYear && d.Before(today) {
+		if d, ok = date(year+1, month, day, p.now.Location()); !ok {
+			return 0
+		}
+	}
+	p.date = &d
+	return n
+}
+
+// matchIn consumes the amount of time after "in", like "2 days" or "an hour"
+func (p *parser) matchIn(tokens []string) int {
+	if len(tokens) < 2 {
+		return 0
+	}
+	amount := 0
+	switch word := normalize(tokens[0]); word {
+	case "a", "an":
+		amount = 1
+	default:
+		n, err := strconv.Atoi(word)
+		if err != nil || n <= 0 || n > 1000 {
+			return 0
+		}
+		amount = n
+	}
+	today := midnight(p.now)
+	var d time.Time
+	switch normalize(tokens[1]) {
+	case "min", "mins", "minute", "minutes":
+		d = p.now.Add(time.Duration(amount) * time.Minute).Truncate(time.Minute)
+		p.exact = &d
+		return 3
+	case "h", "hr", "hrs", "hour", "hours":
+		d = p.now.Add(time.Duration(amount) * time.Hour).Truncate(time.Minute)
+		p.exact = &d
+		return 3
+	case "day", "days":
+		d = today.AddDate(0, 0, amount)
+	case "week", "weeks":
+		d = today.AddDate(0, 0, 7*amount)
+	case "month", "months":
+		d = today.AddDate(0, amount, 0)
+	case "year", "years":
+		d = today.AddDate(amount, 0, 0)
+	default:
+		return 0
+	}
+	p.date = &d
+	return 3
+}
+
+// matchClock consumes time of the day: "9am", "9:30 pm", "21:00" or "noon"
+func matchClock(tokens []string) (clock, int) {
+	word := normalize(tokens[0])
+	if word == "noon" {
+		return clock{hour: 12}, 1
+	}
+	if m := clockPattern.FindStringSubmatch(word); m != nil {
+		c, ok := twelveHour(m[1], m[2], m[3])
+		if ok {
+			return c, 1
+		}
+		return clock{}, 0
+	}
+	if len(tokens) > 1 {
+		if m := hourPattern.FindStringSubmatch(word); m != nil {
+			suffix := normalize(tokens[1])
+			if suffix == "am" || suffix == "pm" {
+				if c, ok := twelveHour(m[1], m[2], suffix); ok {
+					return c, 2
+				}
+			}
+		}
+	}
+	if m := clock24Pattern.FindStringSubmatch(word); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		if hour < 24 && min < 60 {
+			return clock{hour, min}, 1
+		}
+	}
+	return clock{}, 0
+}
+
+func twelveHour(hourStr, minStr, suffix string) (clock, bool) {
+	hour, _ := strconv.Atoi(hourStr)
+	min := 0
+	if minStr != "" {
+		min, _ = strconv.Atoi(minStr)
+	}
+	if hour < 1 || hour > 12 || min > 59 {
+		return clock{}, false
+	}
+	hour %= 12
+	if suffix == "pm" {
+		hour += 12
+	}
+	return clock{hour, min}, true
+}
+
+// due combines the found date and time. Time without date means the nearest
+// such time in the future, the recurring todo without date is due on its
+// first occurrence
+func (p *parser) due() *time.Time {
+	if p.exact != nil {
+		return p.exact
+	}
+	if p.date == nil && p.clock == nil && !p.recurrence {
+		return nil
+	}
+	if p.date != nil {
+		d := p.at(*p.date)
+		return &d
+	}
+	day := midnight(p.now)
+	for i := 0; i < 8; i++ {
+		d := p.at(day)
+		if (p.clock == nil || d.After(p.now)) && p.occurs(day) {
+			return &d
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return nil
+}
+
+// at returns the due time on the day, end of the day is used without time.
+// Time skipped by the daylight saving transition is moved forward by the gap
+func (p *parser) at(day time.Time) time.Time {
+	if p.clock == nil && p.evening {
+		return time.Date(day.Year(), day.Month(), day.Day(), 20, 0, 0, 0, day.Location())
+	}
+	if p.clock == nil {
+		return time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 0, day.Location())
+	}
+	d := time.Date(day.Year(), day.Month(), day.Day(), p.clock.hour, p.clock.min, 0, 0, day.Location())
+	if d.Hour() != p.clock.hour || d.Minute() != p.clock.min {
+		_, offset := day.Zone()
+		d = time.Date(day.Year(), day.Month(), day.Day(), p.clock.hour, p.clock.min, 0, 0, time.FixedZone("", offset)).In(day.Location())
+	}
+	return d
+}
+
+// occurs reports whether the recurring todo happens on the day
+func (p *parser) occurs(day time.Time) bool {
+	if len(p.byDay) == 0 {
+		return true
+	}
+	for _, d := range p.byDay {
+		if d == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) rrule() string {
+	if !p.recurrence {
+		return ""
+	}
+	rule := "FREQ=" + p.freq
+	if p.interval > 1 {
+		rule += ";INTERVAL=" + strconv.Itoa(p.interval)
+	}
+	if len(p.byDay) > 0 {
+		codes := make([]string, len(p.byDay))
+		for i, d := range p.byDay {
+			codes[i] = byDayCodes[d]
+		}
+		rule += ";BYDAY=" + strings.Join(codes, ",")
+	}
+	return rule
+}
+
+// normalize lowercases the token and strips punctuation which may follow it
+func normalize(token string) string {
+	return strings.ToLower(strings.TrimRight(token, ",.;"))
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nextWeekday returns the nearest day after today which is the weekday
+func nextWeekday(today time.Time, day time.Weekday) time.Time {
+	diff := (int(day) - int(today.Weekday()) + 7) % 7
+	if diff == 0 {
+		diff = 7
+	}
+	return today.AddDate(0, 0, diff)
+}
+
+// date returns midnight of the date, it fails for dates like February 30
+func date(year int, month time.Month, day int, loc *time.Location) (time.Time, bool) {
+	d := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	return d, d.Month() == month && d.Day() == day
+}
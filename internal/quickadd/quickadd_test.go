@@ -0,0 +1,162 @@
+package quickadd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata" // the tests don't depend on zoneinfo of the system
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestParse(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	kolkata := time.FixedZone("IST", 5*60*60+30*60)
+	// Wednesday, daylight saving time starts on Sunday, March 9
+	now := time.Date(2025, time.March, 5, 10, 30, 0, 0, newYork)
+	at := func(loc *time.Location, year int, month time.Month, day, hour, min, sec int) *time.Time {
+		d := time.Date(year, month, day, hour, min, sec, 0, loc)
+		return &d
+	}
+	utc := func(year int, month time.Month, day, hour, min, sec int) *time.Time {
+		return at(time.UTC, year, month, day, hour, min, sec)
+	}
+
+	tests := []struct {
+		text string
+		now  time.Time
+		want Result
+	}{
+		// relative dates
+		{"Pay rent tomorrow 9am #home !high", now, Result{
+			Title:    "Pay rent",
+			Due:      at(newYork, 2025, time.March, 6, 9, 0, 0),
+			Labels:   []string{"home"},
+			Priority: core.PriorityHigh,
+		}},
+		{"Call mom friday", now, Result{Title: "Call mom", Due: at(newYork, 2025, time.March, 7, 23, 59, 59)}},
+		{"Call mom on wed", now, Result{Title: "Call mom", Due: at(newYork, 2025, time.March, 12, 23, 59, 59)}},
+		{"Stand-up next monday at 9:30 am", now, Result{Title: "Stand-up", Due: at(newYork, 2025, time.March, 10, 9, 30, 0)}},
+		{"Plan sprint next week", now, Result{Title: "Plan sprint", Due: at(newYork, 2025, time.March, 10, 23, 59, 59)}},
+		{"Send report in 3 days", now, Result{Title: "Send report", Due: at(newYork, 2025, time.March, 8, 23, 59, 59)}},
+		{"Water plants in 2 hours", now, Result{Title: "Water plants", Due: at(newYork, 2025, time.March, 5, 12, 30, 0)}},
+		{"Check oven in 15 mins", now.Add(42 * time.Second), Result{Title: "Check oven", Due: at(newYork, 2025, time.March, 5, 10, 45, 0)}},
+		{"Dinner tonight", now, Result{Title: "Dinner", Due: at(newYork, 2025, time.March, 5, 20, 0, 0)}},
+		{"Dinner tonight 7pm", now, Result{Title: "Dinner", Due: at(newYork, 2025, time.March, 5, 19, 0, 0)}},
+		// time without date is due at the nearest such time
+		{"Lunch noon", now, Result{Title: "Lunch", Due: at(newYork, 2025, time.March, 5, 12, 0, 0)}},
+		{"Run 9am", now, Result{Title: "Run", Due: at(newYork, 2025, time.March, 6, 9, 0, 0)}},
+		{"Deploy at 21:15", now, Result{Title: "Deploy", Due: at(newYork, 2025, time.March, 5, 21, 15, 0)}},
+		// absolute dates
+		{"Taxes 2025-04-15", now, Result{Title: "Taxes", Due: at(newYork, 2025, time.April, 15, 23, 59, 59)}},
+		{"Party on 5th of may", now, Result{Title: "Party", Due: at(newYork, 2025, time.May, 5, 23, 59, 59)}},
+		{"Renew passport mar 3", now, Result{Title: "Renew passport", Due: at(newYork, 2026, time.March, 3, 23, 59, 59)}},
+		{"Archive may 5 2024", now, Result{Title: "Archive", Due: at(newYork, 2024, time.May, 5, 23, 59, 59)}},
+
+		// daylight saving time, due times keep the wall clock of the location
+		{"Brunch sunday 10am", now, Result{Title: "Brunch", Due: utc(2025, time.March, 9, 14, 0, 0)}},
+		{"Review in 5 days", now, Result{Title: "Review", Due: utc(2025, time.March, 11, 3, 59, 59)}},
+		// while hours are counted in absolute time
+		{"Reboot in 100 hours", now, Result{Title: "Reboot", Due: utc(2025, time.March, 9, 19, 30, 0)}},
+		{"Brunch sunday 2:30 am", now, Result{Title: "Brunch", Due: utc(2025, time.March, 9, 7, 30, 0)}},
+
+		// non-UTC location, it's already Thursday in Kolkata
+		{"Call team today", time.Date(2025, time.March, 5, 23, 30, 0, 0, time.UTC).In(kolkata), Result{
+			Title: "Call team",
+			Due:   at(kolkata, 2025, time.March, 6, 23, 59, 59),
+		}},
+		{"Call team tomorrow 9am", time.Date(2025, time.March, 5, 23, 30, 0, 0, time.UTC).In(kolkata), Result{
+			Title: "Call team",
+			Due:   utc(2025, time.March, 7, 3, 30, 0),
+		}},
+
+		// recurrence
+		{"Backup every other week", now, Result{Title: "Backup", Due: at(newYork, 2025, time.March, 5, 23, 59, 59), Recurrence: "FREQ=WEEKLY;INTERVAL=2"}},
+		{"Invoices every 3 months", now, Result{Title: "Invoices", Due: at(newYork, 2025, time.March, 5, 23, 59, 59), Recurrence: "FREQ=MONTHLY;INTERVAL=3"}},
+		{"Stretch every day 8pm", now, Result{Title: "Stretch", Due: at(newYork, 2025, time.March, 5, 20, 0, 0), Recurrence: "FREQ=DAILY"}},
+		{"Pay rent every month 2025-04-01", now, Result{Title: "Pay rent", Due: at(newYork, 2025, time.April, 1, 23, 59, 59), Recurrence: "FREQ=MONTHLY"}},
+		{"Standup every weekday 9am", now, Result{
+			Title:      "Standup",
+			Due:        at(newYork, 2025, time.March, 6, 9, 0, 0),
+			Recurrence: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		}},
+		{"Gym every mon, wed and fri 7am", now, Result{
+			Title:      "Gym",
+			Due:        at(newYork, 2025, time.March, 7, 7, 0, 0),
+			Recurrence: "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+		}},
+		{"Yoga every tue,thu", now, Result{
+			Title:      "Yoga",
+			Due:        at(newYork, 2025, time.March, 6, 23, 59, 59),
+			Recurrence: "FREQ=WEEKLY;BYDAY=TU,TH",
+		}},
+
+		// labels and priorities
+		{"Fix bug #Work #work, #urgent", now, Result{Title: "Fix bug", Labels: []string{"work", "urgent"}}},
+		{"Buy milk !low", now, Result{Title: "Buy milk", Priority: core.PriorityLow}},
+		{"Buy milk !MEDIUM", now, Result{Title: "Buy milk", Priority: core.PriorityMedium}},
+		{"Buy milk !low !high", now, Result{Title: "Buy milk !high", Priority: core.PriorityLow}},
+		{"Close issue #42", now, Result{Title: "Close issue #42"}},
+		{"Wow !!", now, Result{Title: "Wow !!"}},
+
+		// ambiguous and unmatched expressions stay in the title
+		{"Read chapter 5", now, Result{Title: "Read chapter 5"}},
+		{"Meet at the station", now, Result{Title: "Meet at the station"}},
+		{"Knock next door", now, Result{Title: "Knock next door"}},
+		{"Relax in a while", now, Result{Title: "Relax in a while"}},
+		{"Celebrate feb 30", now, Result{Title: "Celebrate feb 30"}},
+		{"Celebrate 2025-02-30", now, Result{Title: "Celebrate 2025-02-30"}},
+		{"Wake up 13pm", now, Result{Title: "Wake up 13pm"}},
+		{"Wake up 25:00", now, Result{Title: "Wake up 25:00"}},
+		{"Take every chance", now, Result{Title: "Take every chance"}},
+		{"Say it every time", now, Result{Title: "Say it every time"}},
+		{"every", now, Result{Title: "every"}},
+		{"", now, Result{}},
+		// only the first date and time are taken
+		{"Move tomorrow today 9am 10am", now, Result{Title: "Move today 10am", Due: at(newYork, 2025, time.March, 6, 9, 0, 0)}},
+		{"Sleep every day every week", now, Result{Title: "Sleep every week", Due: at(newYork, 2025, time.March, 5, 23, 59, 59), Recurrence: "FREQ=DAILY"}},
+	}
+	for _, tt := range tests {
+		got := Parse(tt.text, tt.now)
+		if got.Title != tt.want.Title || got.Priority != tt.want.Priority || got.Recurrence != tt.want.Recurrence ||
+			!reflect.DeepEqual(got.Labels, tt.want.Labels) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+		switch {
+		case got.Due == nil && tt.want.Due == nil:
+		case got.Due == nil || tt.want.Due == nil || !got.Due.Equal(*tt.want.Due):
+			t.Errorf("Parse(%q) due = %v, want %v", tt.text, got.Due, tt.want.Due)
+		case got.Due.Location() != tt.now.Location():
+			t.Errorf("Parse(%q) due in %v, want %v", tt.text, got.Due.Location(), tt.now.Location())
+		}
+	}
+}
+
+func TestParseWeekStart(t *testing.T) {
+	now := time.Date(2025, time.March, 5, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		weekStart time.Weekday
+		want      time.Time
+	}{
+		{time.Monday, time.Date(2025, time.March, 10, 23, 59, 59, 0, time.UTC)},
+		{time.Sunday, time.Date(2025, time.March, 9, 23, 59, 59, 0, time.UTC)},
+		{time.Saturday, time.Date(2025, time.March, 8, 23, 59, 59, 0, time.UTC)},
+		// the week starting today is next week
+		{time.Wednesday, time.Date(2025, time.March, 12, 23, 59, 59, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got := Parser{WeekStart: tt.weekStart}.Parse("Plan next week", now)
+		if got.Due == nil || !got.Due.Equal(tt.want) {
+			t.Errorf("next week starting on %v: due = %v, want %v", tt.weekStart, got.Due, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often the buckets which are full again are removed
+const pruneInterval = time.Minute
+
+// Memory keeps buckets in memory of the process, so every instance of the app
+// has its own limits
+type Memory struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	prunedAt time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// expiresAt is the time when the bucket is full, so it can be forgotten
+	expiresAt time.Time
+}
+
+// NewMemory returns instance of Memory store
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]*bucket)}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, rule Rule) (Result, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Limit), updatedAt: now}
+		m.buckets[key] = b
+	}
+	b.tokens = rule.Refill(b.tokens, now.Sub(b.updatedAt))
+	b.updatedAt = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.expiresAt = now.Add(rule.Period)
+	return rule.Result(b.tokens, allowed), nil
+}
+
+func (m *Memory) prune(now time.Time) {
+	if now.Sub(m.prunedAt) < pruneInterval {
+		return
+	}
+	m.prunedAt = now
+	for key, b := range m.buckets {
+		if now.After(b.expiresAt) {
+			delete(m.buckets, key)
+		}
+	}
+}
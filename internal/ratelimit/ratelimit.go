@@ -0,0 +1,74 @@
+// Package ratelimit implements token bucket rate limiting. Buckets are kept by
+// the pluggable store, so limits can be shared between instances of the app
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Rule allows Limit requests per Period. Unused requests are accumulated up to
+// Limit, so bursts are allowed as well
+type Rule struct {
+	Limit  int
+	Period time.Duration
+}
+
+// Result represents the state of the bucket after taking the request
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, it's zero for
+	// allowed requests
+	RetryAfter time.Duration
+}
+
+// Store takes requests from the buckets identified by the key
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+// Enabled reports whether the rule limits anything
+func (r Rule) Enabled() bool {
+	return r.Limit > 0 && r.Period > 0
+}
+
+// Rate returns number of tokens added to the bucket per second
+func (r Rule) Rate() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+// Refill returns amount of tokens in the bucket after the elapsed time
+func (r Rule) Refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(r.Limit), tokens+elapsed.Seconds()*r.Rate())
+}
+
+// Result returns result of taking the request, tokens is the amount left in
+// the bucket after that
+func (r Rule) Result(tokens float64, allowed bool) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     r.Limit,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     r.duration(float64(r.Limit) - tokens),
+	}
+	if !allowed {
+		res.RetryAfter = r.duration(1 - tokens)
+	}
+	return res
+}
+
+// duration returns the time needed for adding the amount of tokens
+func (r Rule) duration(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / r.Rate() * float64(time.Second))
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/exporter"
+)
+
+const maxUserFieldLen = 255
+
+var (
+	ErrUsernameTaken    = errors.New("username is already taken")
+	ErrEmailTaken       = errors.New("email is already taken")
+	ErrEmptyUsername    = errors.New("username can't be empty")
+	ErrEmptyName        = errors.New("name can't be empty")
+	ErrWrongPassword    = errors.New("current password is wrong")
+	ErrEmptyPassword    = errors.New("new password can't be empty")
+	errNothingToUpdate  = errors.New("you should provide one of Name, Username or Email")
+	errUserFieldTooLong = errors.New("name and username can't be longer than 255 characters")
+)
+
+type AccountStorage interface {
+	GetUserByID(userID int) (core.User, error)
+	GetUser(username, password string) (core.User, error)
+	UpdateUser(userID int, data core.UpdateUserData) (core.User, error)
+	UsernameTaken(username string, userID int) (bool, error)
+	GetUserByEmail(email string) (core.User, error)
+	UpdatePassword(userID int, hash string) error
+	DeleteUser(userID int) error
+}
+
+// AccountService manages account of the signed in user
+type AccountService struct {
+	storage AccountStorage
+	auth    *AuthService
+	export  *ExportService
+}
+
+func NewAccountService(storage AccountStorage, auth *AuthService, export *ExportService) *AccountService {
+	return &AccountService{storage, auth, export}
+}
+
+func (s *AccountService) GetUser(userID int) (core.User, error) {
+	return s.storage.GetUserByID(userID)
+}
+
+func (s *AccountService) UpdateUser(userID int, data core.UpdateUserData) (core.User, error) {
+	if data.Name == nil && data.Username == nil && data.Email == nil {
+		return core.User{}, errNothingToUpdate
+	}
+	if data.Name != nil {
+		name := strings.TrimSpace(*data.Name)
+		if name == "" {
+			return core.User{}, ErrEmptyName
+		}
+		if len(name) > maxUserFieldLen {
+			return core.User{}, errUserFieldTooLong
+		}
+		data.Name = &name
+	}
+	if data.Username != nil {
+		username := strings.TrimSpace(*data.Username)
+		if username == "" {
+			return core.User{}, ErrEmptyUsername
+		}
+		if len(username) > maxUserFieldLen {
+			return core.User{}, errUserFieldTooLong
+		}
+		taken, err := s.storage.UsernameTaken(username, userID)
+		if err != nil {
+			return core.User{}, err
+		}
+		if taken {
+			return core.User{}, ErrUsernameTaken
+		}
+		data.Username = &username
+	}
+	if data.Email != nil {
+		current, err := s.storage.GetUserByID(userID)
+		if err != nil {
+			return core.User{}, err
+		}
+		email := ""
+		if *data.Email != "" {
+			if email, err = normalizeEmail(*data.Email); err != nil {
+				return core.User{}, err
+			}
+		}
+		if email == current.Email {
+			// the same email stays verified
+			data.Email = nil
+		} else {
+			if other, err := s.storage.GetUserByEmail(email); err == nil && other.ID != userID {
+				return core.User{}, ErrEmailTaken
+			}
+			data.Email = &email
+		}
+		if data.Name == nil && data.Username == nil && data.Email == nil {
+			return current, nil
+		}
+	}
+	user, err := s.storage.UpdateUser(userID, data)
+	if err != nil || data.Email == nil || user.Email == "" {
+		return user, err
+	}
+	return user, s.auth.recovery.SendVerification(user)
+}
+
+// ChangePassword replaces the password when the current one is right. All
+// tokens issued before are revoked, so the new token is returned
+func (s *AccountService) ChangePassword(userID int, current, password string) (string, error) {
+	if password == "" {
+		return "", ErrEmptyPassword
+	}
+	user, err := s.storage.GetUserByID(userID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.storage.GetUser(user.Username, generateHash(current)); err != nil {
+		return "", ErrWrongPassword
+	}
+	if err := s.storage.UpdatePassword(userID, generateHash(password)); err != nil {
+		return "", err
+	}
+	return s.auth.newToken(userID)
+}
+
+// DeleteUser writes export of all user's lists and removes the account only
+// when the export is written successfully
+func (s *AccountService) DeleteUser(userID int, w exporter.Writer) error {
+	if err := s.export.ExportAll(userID, w); err != nil {
+		return err
+	}
+	return s.storage.DeleteUser(userID)
+}
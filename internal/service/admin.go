@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+const (
+	impersonationTTL   = time.Hour
+	defaultUsersLimit  = 50
+	maxUsersLimit      = 500
+	maxImpersonateNote = 255
+)
+
+var (
+	ErrUnknownRole         = errors.New("unknown role")
+	ErrAdminSelfAction     = errors.New("admins can't apply this action to themselves")
+	ErrImpersonateAdmin    = errors.New("admins can't be impersonated")
+	ErrImpersonateDisabled = errors.New("disabled users can't be impersonated")
+	ErrMissingReason       = errors.New("reason of impersonation is required")
+)
+
+type AdminStorage interface {
+	GetUsers(f core.UserFilter) ([]core.User, error)
+	GetUser(userID int) (core.User, error)
+	SetUserDisabled(userID int, disabled bool, e core.AuditEntry) (core.User, error)
+	SetUserRole(userID int, role string, e core.AuditEntry) (core.User, error)
+	RevokeSessions(userID int, e core.AuditEntry) error
+	CreateAuditEntry(e core.AuditEntry) error
+	GetAuditLog(targetUserID, limit int) ([]core.AuditEntry, error)
+	GetUsageStats() (core.UsageStats, error)
+}
+
+// AdminService manages users on behalf of admins, every change is recorded in
+// the audit log
+type AdminService struct {
+	storage AdminStorage
+}
+
+func NewAdminService(storage AdminStorage) *AdminService {
+	return &AdminService{storage}
+}
+
+// GetUsers returns users matching the filter
+func (s *AdminService) GetUsers(f core.UserFilter) ([]core.User, error) {
+	f.Query = strings.TrimSpace(f.Query)
+	if f.Limit <= 0 {
+		f.Limit = defaultUsersLimit
+	}
+	if f.Limit > maxUsersLimit {
+		f.Limit = maxUsersLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	return s.storage.GetUsers(f)
+}
+
+func (s *AdminService) GetUser(userID int) (core.User, error) {
+	return s.storage.GetUser(userID)
+}
+
+// DisableUser disables the user, the user's tokens are rejected until the user
+// is enabled again
+func (s *AdminService) DisableUser(adminID, userID int) (core.User, error) {
+	if adminID == userID {
+		return core.User{}, ErrAdminSelfAction
+	}
+	return s.storage.SetUserDisabled(userID, true, audit(adminID, core.AuditUserDisable, userID, ""))
+}
+
+func (s *AdminService) EnableUser(adminID, userID int) (core.User, error) {
+	return s.storage.SetUserDisabled(userID, false, audit(adminID, core.AuditUserEnable, userID, ""))
+}
+
+// SetRole changes role of the user, admins can't demote themselves so there's
+// always at least one admin
+func (s *AdminService) SetRole(adminID, userID int, role string) (core.User, error) {
+	if !containsString(core.Roles, role) {
+		return core.User{}, fmt.Errorf("%w: %s", ErrUnknownRole, role)
+	}
+	if adminID == userID {
+		return core.User{}, ErrAdminSelfAction
+	}
+	return s.storage.SetUserRole(userID, role, audit(adminID, core.AuditUserRole, userID, role))
+}
+
+// SignOutUser revokes all sessions of the user
+func (s *AdminService) SignOutUser(adminID, userID int) error {
+	return s.storage.RevokeSessions(userID, audit(adminID, core.AuditUserSignOut, userID, ""))
+}
+
+// Impersonate returns short-lived token of the user for the admin. The reason
+// is recorded in the audit log and requests made with the token are logged
+func (s *AdminService) Impersonate(adminID, userID int, reason string) (string, time.Time, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", time.Time{}, ErrMissingReason
+	}
+	if runes := []rune(reason); len(runes) > maxImpersonateNote {
+		reason = string(runes[:maxImpersonateNote])
+	}
+	if adminID == userID {
+		return "", time.Time{}, ErrAdminSelfAction
+	}
+	user, err := s.storage.GetUser(userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if user.Role == core.RoleAdmin {
+		return "", time.Time{}, ErrImpersonateAdmin
+	}
+	if user.Disabled {
+		return "", time.Time{}, ErrImpersonateDisabled
+	}
+	if err := s.storage.CreateAuditEntry(audit(adminID, core.AuditUserImpersonate, userID, reason)); err != nil {
+		return "", time.Time{}, err
+	}
+	token, err := signToken(user, adminID, impersonationTTL)
+	return token, time.Now().Add(impersonationTTL), err
+}
+
+func (s *AdminService) GetAuditLog(targetUserID, limit int) ([]core.AuditEntry, error) {
+	return s.storage.GetAuditLog(targetUserID, limit)
+}
+
+func (s *AdminService) GetUsageStats() (core.UsageStats, error) {
+	return s.storage.GetUsageStats()
+}
+
+func audit(adminID int, action string, targetUserID int, details string) core.AuditEntry {
+	return core.AuditEntry{AdminID: adminID, Action: action, TargetUserID: targetUserID, Details: details}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vbetsun/todo-app/internal/blob"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAttachmentSize = 10 << 20 // 10 Mb
+	maxFilenameLen        = 255
+	sniffLen              = 512
+	blobSweepBatchSize    = 100
+	blobSweepInterval     = time.Minute
+)
+
+var (
+	ErrEmptyAttachment              = errors.New("attachment can't be empty")
+	ErrAttachmentTooLarge           = errors.New("attachment is too large")
+	ErrAttachmentQuota              = errors.New("storage quota is exceeded")
+	ErrAttachmentDeleteAccess error = AccessError("only the uploader or workspace admins can delete the attachment")
+)
+
+type AttachmentStorage interface {
+	GetAttachments(todoID int) ([]core.Attachment, error)
+	GetAttachment(todoID, attachmentID int) (core.Attachment, error)
+	UsedSpace(userID int) (int64, error)
+	CreateAttachment(a core.Attachment, quota int64) (core.Attachment, bool, error)
+	DeleteAttachment(attachmentID int) error
+	GetBlobDeletions(limit int) ([]string, error)
+	DeleteBlobDeletion(key string) error
+}
+
+// AttachmentLimits restricts sizes of the uploaded files
+type AttachmentLimits struct {
+	MaxSize   int64 // bytes of a single file, 10 Mb by default
+	UserQuota int64 // bytes of all files uploaded by the user, zero is unlimited
+}
+
+// AttachmentService manages files attached to todos. Contents are kept in the
+// blob store, blobs of the removed attachments are deleted in background
+type AttachmentService struct {
+	storage AttachmentStorage
+	blobs   blob.Store
+	access  WorkspaceAccess
+	limits  AttachmentLimits
+	log     *zap.Logger
+}
+
+func NewAttachmentService(storage AttachmentStorage, blobs blob.Store, access WorkspaceAccess, limits AttachmentLimits, log *zap.Logger) *AttachmentService {
+	if limits.MaxSize <= 0 {
+		limits.MaxSize = defaultAttachmentSize
+	}
+	return &AttachmentService{storage, blobs, access, limits, log}
+}
+
+// MaxSize returns the biggest size of the file which can be uploaded
+func (s *AttachmentService) MaxSize() int64 {
+	return s.limits.MaxSize
+}
+
+func (s *AttachmentService) GetAttachments(todoID int) ([]core.Attachment, error) {
+	return s.storage.GetAttachments(todoID)
+}
+
+func (s *AttachmentService) GetAttachment(todoID, attachmentID int) (core.Attachment, error) {
+	return s.storage.GetAttachment(todoID, attachmentID)
+}
+
+// Upload saves the file of the given size to the todo. The content type is
+// sniffed from the content, so the type declared by the client is ignored
+func (s *AttachmentService) Upload(ctx context.Context, userID, listID, todoID int, filename string, size int64, r io.Reader) (core.Attachment, error) {
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return core.Attachment{}, err
+	}
+	if size <= 0 {
+		return core.Attachment{}, ErrEmptyAttachment
+	}
+	if size > s.limits.MaxSize {
+		return core.Attachment{}, ErrAttachmentTooLarge
+	}
+	if s.limits.UserQuota > 0 {
+		used, err := s.storage.UsedSpace(userID)
+		if err != nil {
+			return core.Attachment{}, err
+		}
+		if used+size > s.limits.UserQuota {
+			return core.Attachment{}, ErrAttachmentQuota
+		}
+	}
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return core.Attachment{}, err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return core.Attachment{}, err
+	}
+	a := core.Attachment{
+		TodoID:      todoID,
+		UserID:      userID,
+		Filename:    attachmentName(filename),
+		ContentType: http.DetectContentType(head),
+		Size:        size,
+		Key:         "attachments/" + token,
+	}
+	if err := s.blobs.Put(ctx, a.Key, br, a.Size, a.ContentType); err != nil {
+		return core.Attachment{}, err
+	}
+	a, ok, err := s.storage.CreateAttachment(a, s.limits.UserQuota)
+	if err == nil && !ok {
+		err = ErrAttachmentQuota
+	}
+	if err != nil {
+		if dErr := s.blobs.Delete(ctx, a.Key); dErr != nil {
+			s.log.Error("can't delete orphaned blob", zap.String("key", a.Key), zap.Error(dErr))
+		}
+		return core.Attachment{}, err
+	}
+	return a, nil
+}
+
+// Open returns content of the attachment, the caller has to close it
+func (s *AttachmentService) Open(ctx context.Context, a core.Attachment) (io.ReadCloser, error) {
+	return s.blobs.Get(ctx, a.Key)
+}
+
+// DeleteAttachment removes the attachment, it's allowed to the uploader and to
+// admins of the workspace
+func (s *AttachmentService) DeleteAttachment(userID, listID int, a core.Attachment) error {
+	role, err := s.access.GetListRole(listID, userID)
+	if err != nil || !core.CanWrite(role) {
+		return ErrWorkspaceForbidden
+	}
+	if a.UserID != userID && !core.CanManage(role) {
+		return ErrAttachmentDeleteAccess
+	}
+	return s.storage.DeleteAttachment(a.ID)
+}
+
+// Run deletes blobs of the removed attachments until the context is canceled
+func (s *AttachmentService) Run(ctx context.Context) {
+	ticker := time.NewTicker(blobSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+// sweep deletes queued blobs, failed ones are retried on the next run
+func (s *AttachmentService) sweep(ctx context.Context) {
+	keys, err := s.storage.GetBlobDeletions(blobSweepBatchSize)
+	if err != nil {
+		s.log.Error("can't get blobs for deletion", zap.Error(err))
+		return
+	}
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			s.log.Error("can't delete blob", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		if err := s.storage.DeleteBlobDeletion(key); err != nil {
+			s.log.Error("can't forget deleted blob", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// attachmentName strips directories and control characters from the name of
+// the uploaded file
+func attachmentName(filename string) string {
+	filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, filename)
+	filename = strings.TrimSpace(filename)
+	if filename == "" || filename == "." || filename == "/" {
+		return "attachment"
+	}
+	if runes := []rune(filename); len(runes) > maxFilenameLen {
+		filename = string(runes[:maxFilenameLen])
+	}
+	return filename
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// attachmentStorage saves attachments of the list writer in memory
+type attachmentStorage struct {
+	AttachmentStorage
+	WorkspaceAccess
+	attachments []core.Attachment
+}
+
+func (m *attachmentStorage) GetListRole(listID, userID int) (string, error) {
+	return core.WorkspaceRoleMember, nil
+}
+
+func (m *attachmentStorage) CreateAttachment(a core.Attachment, quota int64) (core.Attachment, bool, error) {
+	a.ID = len(m.attachments) + 1
+	m.attachments = append(m.attachments, a)
+	return a, true, nil
+}
+
+// memoryBlobs keeps contents and types of the uploaded blobs
+type memoryBlobs struct {
+	data  map[string][]byte
+	types map[string]string
+}
+
+func (m *memoryBlobs) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.data[key], m.types[key] = data, contentType
+	return nil
+}
+
+func (m *memoryBlobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(m.data[key])), nil
+}
+
+func (m *memoryBlobs) Delete(ctx context.Context, key string) error {
+	delete(m.data, key)
+	return nil
+}
+
+func TestUploadSniffsContentType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tests := []struct {
+		filename string
+		content  []byte
+		want     string
+	}{
+		{"image.png", png, "image/png"},
+		{"report.pdf", []byte("%PDF-1.7\n%âãÏÓ\n1 0 obj"), "application/pdf"},
+		{"notes.txt", []byte("buy milk"), "text/plain; charset=utf-8"},
+		// the client's extension doesn't change the sniffed type
+		{"image.png", []byte("<html><script>alert(1)</script></html>"), "text/html; charset=utf-8"},
+		{"notes.txt", png, "image/png"},
+		{"data.bin", []byte{0x00, 0x01, 0x02, 0xff}, "application/octet-stream"},
+		// only the head is sniffed, the whole content is stored
+		{"long.txt", []byte(strings.Repeat("a", 3*sniffLen)), "text/plain; charset=utf-8"},
+	}
+	storage := &attachmentStorage{}
+	blobs := &memoryBlobs{data: make(map[string][]byte), types: make(map[string]string)}
+	s := NewAttachmentService(storage, blobs, storage, AttachmentLimits{}, zap.NewNop())
+
+	for _, tt := range tests {
+		a, err := s.Upload(context.Background(), 1, 1, 1, tt.filename, int64(len(tt.content)), bytes.NewReader(tt.content))
+		if err != nil {
+			t.Fatalf("Upload(%s): %v", tt.filename, err)
+		}
+		if a.ContentType != tt.want {
+			t.Errorf("Upload(%s) content type = %q, want %q", tt.filename, a.ContentType, tt.want)
+		}
+		if blobs.types[a.Key] != tt.want {
+			t.Errorf("Upload(%s) blob content type = %q, want %q", tt.filename, blobs.types[a.Key], tt.want)
+		}
+		if !bytes.Equal(blobs.data[a.Key], tt.content) {
+			t.Errorf("Upload(%s) stored %d bytes, want %d", tt.filename, len(blobs.data[a.Key]), len(tt.content))
+		}
+	}
+}
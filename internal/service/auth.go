@@ -8,6 +8,7 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
 )
 
 const (
@@ -16,45 +17,194 @@ const (
 	tokenTTL   = 12 * time.Hour
 )
 
+var (
+	ErrAccessTokenRequired = errors.New("personal access token is required when two-factor authentication is enabled")
+	ErrInsufficientScope   = errors.New("access token doesn't have the required scopes")
+	ErrTokenRevoked        = errors.New("token is revoked")
+	ErrAccountDisabled     = errors.New("account is disabled")
+)
+
 type AuthStorage interface {
 	CreateUser(core.User) (core.User, error)
 	GetUser(username, password string) (core.User, error)
+	GetUserByID(userID int) (core.User, error)
+	GetTOTP(userID int) (core.TOTP, error)
+	SaveTOTPSecret(userID int, secret string) error
+	EnableTOTP(userID int, step int64, codeHashes []string) error
+	DisableTOTP(userID int) error
+	UseTOTPStep(userID int, step int64) (bool, error)
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	CreateChallenge(id string, userID int, expiresAt time.Time) error
+	AttemptChallenge(id string, maxAttempts int) (bool, error)
+	UseChallenge(id string) (bool, error)
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	LoginLockedUntil(username string) (time.Time, error)
+	RecordLoginFailure(username string) (int, error)
+	LockLogin(username string, until time.Time) error
+	ResetLoginFailures(userID int) error
 }
 
 type AuthService struct {
-	storage AuthStorage
+	storage  AuthStorage
+	tokens   AccessTokenStorage
+	recovery *RecoveryService
+	lockout  LockoutPolicy
 }
 
 type TokenClaims struct {
 	jwt.RegisteredClaims
 	UserID int `json:"user_id"`
+	// Version is the user's token version at the moment of issuing, tokens of
+	// older versions are revoked
+	Version int `json:"ver,omitempty"`
+	// ImpersonatorID is ID of the admin who acts on behalf of the user
+	ImpersonatorID int `json:"imp,omitempty"`
 }
 
-func NewAuthService(storage AuthStorage) *AuthService {
-	return &AuthService{storage}
+func NewAuthService(storage AuthStorage, tokens AccessTokenStorage, recovery *RecoveryService, lockout LockoutPolicy) *AuthService {
+	return &AuthService{storage, tokens, recovery, lockout}
 }
 
+// CreateUser creates the user and sends the link for verifying the email if it's set
 func (s *AuthService) CreateUser(u core.User) (core.User, error) {
-	u.Password = s.generateHash(u.Password)
-	return s.storage.CreateUser(u)
+	if u.Email != "" {
+		email, err := normalizeEmail(u.Email)
+		if err != nil {
+			return u, err
+		}
+		u.Email = email
+	}
+	u.Password = generateHash(u.Password)
+	user, err := s.storage.CreateUser(u)
+	if err != nil || user.Email == "" {
+		return user, err
+	}
+	// the user is already created, so the email can be sent again later
+	if err := s.recovery.SendVerification(user); err != nil {
+		s.recovery.log.Error("can't send email verification", zap.Int("userID", user.ID), zap.Error(err))
+	}
+	return user, nil
+}
+
+// Authenticate returns ID of the user with the given credentials. Failed
+// attempts lock the account according to the lockout policy
+func (s *AuthService) Authenticate(uname, pwd string) (int, error) {
+	if err := s.checkLockout(uname); err != nil {
+		return 0, err
+	}
+	user, err := s.storage.GetUser(uname, generateHash(pwd))
+	if err != nil {
+		s.loginFailed(uname)
+		return 0, err
+	}
+	if s.lockout.Enabled() {
+		if err := s.storage.ResetLoginFailures(user.ID); err != nil {
+			return 0, err
+		}
+	}
+	if user.Disabled {
+		return 0, ErrAccountDisabled
+	}
+	return user.ID, nil
+}
+
+// SignIn checks user's credentials and returns access token or challenge
+// token when the user has two-factor authentication enabled
+func (s *AuthService) SignIn(uname, pwd string) (core.SignIn, error) {
+	userID, err := s.Authenticate(uname, pwd)
+	if err != nil {
+		return core.SignIn{}, err
+	}
+	return s.signInUser(userID)
+}
+
+// signInUser returns the access token of the authenticated user, users who
+// have two-factor authentication enabled get the challenge token instead
+func (s *AuthService) signInUser(userID int) (core.SignIn, error) {
+	t, err := s.storage.GetTOTP(userID)
+	if err != nil {
+		return core.SignIn{}, err
+	}
+	if !t.Enabled {
+		token, err := s.newToken(userID)
+		return core.SignIn{Token: token}, err
+	}
+	user, err := s.storage.GetUserByID(userID)
+	if err != nil {
+		return core.SignIn{}, err
+	}
+	challenge, err := s.newChallenge(user)
+	return core.SignIn{ChallengeToken: challenge}, err
+}
+
+// AuthenticateBasic returns ID of the user by credentials of Basic auth which
+// can't pass the second factor. So users who have it enabled have to use
+// personal access token with the scopes instead of the password
+func (s *AuthService) AuthenticateBasic(uname, secret string, scopes ...string) (int, error) {
+	if !IsAccessToken(secret) {
+		userID, err := s.Authenticate(uname, secret)
+		if err != nil {
+			return 0, err
+		}
+		t, err := s.storage.GetTOTP(userID)
+		if err != nil {
+			return 0, err
+		}
+		if t.Enabled {
+			return 0, ErrAccessTokenRequired
+		}
+		return userID, nil
+	}
+	t, err := s.tokens.UseAccessToken(hashSecret(secret))
+	if err != nil {
+		return 0, ErrInvalidAccessToken
+	}
+	user, err := s.storage.GetUserByID(t.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if user.Username != uname {
+		return 0, ErrInvalidAccessToken
+	}
+	if user.Disabled {
+		return 0, ErrAccountDisabled
+	}
+	for _, scope := range scopes {
+		if !containsString(t.Scopes, scope) {
+			return 0, ErrInsufficientScope
+		}
+	}
+	return t.UserID, nil
 }
 
-func (s *AuthService) GenerateToken(uname, pwd string) (string, error) {
-	user, err := s.storage.GetUser(uname, s.generateHash(pwd))
+// newToken returns signed JWT of the user
+func (s *AuthService) newToken(userID int) (string, error) {
+	user, err := s.storage.GetUserByID(userID)
 	if err != nil {
 		return "", err
 	}
+	if user.Disabled {
+		return "", ErrAccountDisabled
+	}
+	return signToken(user, 0, tokenTTL)
+}
+
+func signToken(user core.User, impersonatorID int, ttl time.Duration) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, TokenClaims{
 		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenTTL)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 		user.ID,
+		user.TokenVersion,
+		impersonatorID,
 	})
 	return token.SignedString([]byte(signingKey))
 }
 
-func (s *AuthService) ParseToken(accessToken string) (int, error) {
+// ParseToken returns the caller identified by JWT. Tokens of disabled users are
+// rejected, as well as impersonation tokens of admins who lost the role
+func (s *AuthService) ParseToken(accessToken string) (core.Principal, error) {
 	token, err := jwt.ParseWithClaims(accessToken, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
@@ -62,18 +212,61 @@ func (s *AuthService) ParseToken(accessToken string) (int, error) {
 		return []byte(signingKey), nil
 	})
 	if err != nil {
-		return 0, err
+		return core.Principal{}, err
 	}
 	claims, ok := token.Claims.(*TokenClaims)
 	if !ok {
-		return 0, errors.New("claims are not of type *TokenClaims")
+		return core.Principal{}, errors.New("claims are not of type *TokenClaims")
+	}
+	// the user could be deleted or could revoke the token since it was issued
+	user, err := s.storage.GetUserByID(claims.UserID)
+	if err != nil || user.TokenVersion != claims.Version {
+		return core.Principal{}, ErrTokenRevoked
 	}
-	return claims.UserID, nil
+	if user.Disabled {
+		return core.Principal{}, ErrAccountDisabled
+	}
+	if claims.ImpersonatorID != 0 {
+		admin, err := s.storage.GetUserByID(claims.ImpersonatorID)
+		if err != nil || admin.Role != core.RoleAdmin || admin.Disabled {
+			return core.Principal{}, ErrTokenRevoked
+		}
+	}
+	return core.Principal{UserID: user.ID, Role: user.Role, ImpersonatorID: claims.ImpersonatorID}, nil
 }
 
-func (s *AuthService) generateHash(pwd string) string {
+// Identify returns the caller by JWT or personal access token together with
+// the scopes granted to the token. Scopes of JWT are nil, it grants full access
+func (s *AuthService) Identify(token string) (core.Principal, error) {
+	if !IsAccessToken(token) {
+		return s.ParseToken(token)
+	}
+	t, err := s.tokens.UseAccessToken(hashSecret(token))
+	if err != nil {
+		return core.Principal{}, ErrInvalidAccessToken
+	}
+	user, err := s.storage.GetUserByID(t.UserID)
+	if err != nil {
+		return core.Principal{}, err
+	}
+	if user.Disabled {
+		return core.Principal{}, ErrAccountDisabled
+	}
+	return core.Principal{UserID: user.ID, Role: user.Role, Scopes: t.Scopes}, nil
+}
+
+func generateHash(pwd string) string {
 	hash := sha256.New()
 	hash.Write([]byte(pwd))
 
 	return fmt.Sprintf("%x", hash.Sum([]byte(salt)))
 }
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
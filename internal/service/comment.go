@@ -0,0 +1,144 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+const (
+	maxCommentLen        = 10000
+	defaultCommentsLimit = 50
+	maxCommentsLimit     = 200
+)
+
+var (
+	ErrEmptyComment              = errors.New("comment can't be empty")
+	ErrCommentTooLong            = errors.New("comment can't be longer than 10000 characters")
+	ErrCommentAuthor       error = AccessError("only the author can edit the comment")
+	ErrCommentDeleteAccess error = AccessError("only the author or workspace admins can delete the comment")
+)
+
+// mentionPattern matches @username which isn't a part of an email or another word
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w(?:[\w.\-]*\w)?)`)
+
+type CommentStorage interface {
+	GetComments(todoID, limit, offset int) ([]core.Comment, error)
+	GetComment(todoID, commentID int) (core.Comment, error)
+	CreateComment(listID int, c core.Comment, usernames []string) (core.Comment, error)
+	UpdateComment(listID int, c core.Comment, usernames []string) (core.Comment, error)
+	DeleteComment(commentID int) error
+}
+
+// MentionNotifier notifies users mentioned in comments
+type MentionNotifier interface {
+	NotifyMentions(listID int, c core.Comment, previous []core.Mention)
+}
+
+// CommentService manages discussions of todos. Mentioned usernames are linked
+// to the comment only when they belong to members of the list
+type CommentService struct {
+	storage  CommentStorage
+	access   WorkspaceAccess
+	notifier MentionNotifier
+}
+
+func NewCommentService(storage CommentStorage, access WorkspaceAccess, notifier MentionNotifier) *CommentService {
+	return &CommentService{storage, access, notifier}
+}
+
+// GetComments returns page of the todo's comments
+func (s *CommentService) GetComments(todoID, limit, offset int) ([]core.Comment, error) {
+	if limit <= 0 {
+		limit = defaultCommentsLimit
+	}
+	if limit > maxCommentsLimit {
+		limit = maxCommentsLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.storage.GetComments(todoID, limit, offset)
+}
+
+func (s *CommentService) GetComment(todoID, commentID int) (core.Comment, error) {
+	return s.storage.GetComment(todoID, commentID)
+}
+
+func (s *CommentService) CreateComment(userID, listID, todoID int, body string) (core.Comment, error) {
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return core.Comment{}, err
+	}
+	body, err := commentBody(body)
+	if err != nil {
+		return core.Comment{}, err
+	}
+	c, err := s.storage.CreateComment(listID, core.Comment{TodoID: todoID, AuthorID: userID, Body: body}, mentions(body))
+	if err != nil {
+		return c, err
+	}
+	s.notifier.NotifyMentions(listID, c, nil)
+	return c, nil
+}
+
+// UpdateComment replaces body of the comment, only its author can do it
+func (s *CommentService) UpdateComment(userID, listID int, c core.Comment, body string) (core.Comment, error) {
+	if c.AuthorID != userID {
+		return core.Comment{}, ErrCommentAuthor
+	}
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return core.Comment{}, err
+	}
+	body, err := commentBody(body)
+	if err != nil {
+		return core.Comment{}, err
+	}
+	previous := c.Mentions
+	c.Body = body
+	c, err = s.storage.UpdateComment(listID, c, mentions(body))
+	if err != nil {
+		return c, err
+	}
+	s.notifier.NotifyMentions(listID, c, previous)
+	return c, nil
+}
+
+// DeleteComment removes the comment, authors can remove their comments and
+// workspace admins can remove any comment
+func (s *CommentService) DeleteComment(userID, listID int, c core.Comment) error {
+	role, err := s.access.GetListRole(listID, userID)
+	if err != nil {
+		return ErrWorkspaceForbidden
+	}
+	if !(c.AuthorID == userID && core.CanWrite(role)) && !core.CanManage(role) {
+		return ErrCommentDeleteAccess
+	}
+	return s.storage.DeleteComment(c.ID)
+}
+
+func commentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", ErrEmptyComment
+	}
+	if utf8.RuneCountInString(body) > maxCommentLen {
+		return "", ErrCommentTooLong
+	}
+	return body, nil
+}
+
+// mentions returns unique usernames mentioned in the body
+func mentions(body string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			usernames = append(usernames, m[1])
+		}
+	}
+	return usernames
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/pubsub"
+	"go.uber.org/zap"
+)
+
+// listenRetryDelay is a pause before reconnecting to the events channel
+const listenRetryDelay = time.Second
+
+type EventStorage interface {
+	SaveEvent(e core.Event) (core.Event, error)
+	GetEventsSince(listID int, seq int64) ([]core.Event, error)
+	GetLastEventSeq(listID int) (int64, error)
+	ListenEvents(ctx context.Context, fn func(core.Event)) error
+}
+
+// EventPublisher delivers events from the outbox to their consumers.
+// Publishing must be idempotent because an event is retried until every
+// consumer accepts it
+type EventPublisher interface {
+	Publish(e core.Event) error
+}
+
+// Publishers passes every event to each of the underlying publishers
+type Publishers []EventPublisher
+
+func (p Publishers) Publish(e core.Event) error {
+	for _, pub := range p {
+		if err := pub.Publish(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type EventService struct {
+	storage EventStorage
+	hub     *pubsub.Hub
+	log     *zap.Logger
+}
+
+func NewEventService(storage EventStorage, hub *pubsub.Hub, log *zap.Logger) *EventService {
+	return &EventService{storage, hub, log}
+}
+
+// Publish persists the event and notifies all API instances about it
+func (s *EventService) Publish(e core.Event) error {
+	_, err := s.storage.SaveEvent(e)
+	return err
+}
+
+// Subscribe returns subscription to the events of the given list
+func (s *EventService) Subscribe(listID int) *pubsub.Subscription {
+	return s.hub.Subscribe(listID)
+}
+
+// GetEventsSince returns list's events which were published after the given sequence number
+func (s *EventService) GetEventsSince(listID int, seq int64) ([]core.Event, error) {
+	return s.storage.GetEventsSince(listID, seq)
+}
+
+// GetLastEventSeq returns sequence number of the latest event of the list or zero if there are none
+func (s *EventService) GetLastEventSeq(listID int) (int64, error) {
+	return s.storage.GetLastEventSeq(listID)
+}
+
+// Listen receives events from all API instances and broadcasts them to the local
+// subscribers. It blocks until the context is canceled
+func (s *EventService) Listen(ctx context.Context) {
+	for {
+		err := s.storage.ListenEvents(ctx, s.hub.Broadcast)
+		if ctx.Err() != nil {
+			return
+		}
+		s.log.Error("events listener stopped, reconnecting", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/exporter"
+)
+
+type ExportService struct {
+	lists TodoListStorage
+	todos TodoItemStorage
+}
+
+func NewExportService(lists TodoListStorage, todos TodoItemStorage) *ExportService {
+	return &ExportService{lists, todos}
+}
+
+// ExportAll writes all lists of the user, todos are loaded list by list
+func (s *ExportService) ExportAll(userID int, w exporter.Writer) error {
+	lists, err := s.lists.GetAllLists(userID)
+	if err != nil {
+		return err
+	}
+	for _, l := range lists {
+		if err := s.writeList(l, w); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// ExportList writes a single list with its todos
+func (s *ExportService) ExportList(list core.Todolist, w exporter.Writer) error {
+	if err := s.writeList(list, w); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (s *ExportService) writeList(list core.Todolist, w exporter.Writer) error {
+	todos, err := s.todos.GetAllTodos(list.ID)
+	if err != nil {
+		return err
+	}
+	return w.WriteList(list, todos)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"io"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/importer"
+)
+
+type ImportStorage interface {
+	ImportLists(userID int, lists []core.ListWithTodos) ([]core.ListWithTodos, error)
+}
+
+type ImportService struct {
+	storage ImportStorage
+}
+
+func NewImportService(storage ImportStorage) *ImportService {
+	return &ImportService{storage}
+}
+
+// Import parses the file and creates all its lists and todos. Nothing is created
+// if the file contains errors or only the preview is requested
+func (s *ImportService) Import(userID int, format string, r io.Reader, defaultList string, dryRun bool) (core.ImportResult, error) {
+	lists, errs, err := importer.Parse(format, r, defaultList)
+	if err != nil {
+		return core.ImportResult{}, err
+	}
+	res := core.ImportResult{
+		DryRun: dryRun,
+		Lists:  lists,
+		Errors: errs,
+	}
+	if dryRun || len(errs) > 0 || len(lists) == 0 {
+		return res, nil
+	}
+	res.Lists, err = s.storage.ImportLists(userID, lists)
+	return res, err
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultMaxLockout limits the lock duration when the policy doesn't
+const defaultMaxLockout = 24 * time.Hour
+
+// LockoutPolicy locks sign in of the account after Threshold failed attempts in
+// a row. Every next failure doubles the lock duration up to MaxDuration
+type LockoutPolicy struct {
+	Threshold   int
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// LockedError is returned when sign in of the account is locked
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return "account is temporarily locked after failed sign in attempts"
+}
+
+// RetryAfter returns the time left until the account is unlocked
+func (e *LockedError) RetryAfter() time.Duration {
+	return time.Until(e.Until)
+}
+
+// Enabled reports whether the policy locks accounts at all
+func (p LockoutPolicy) Enabled() bool {
+	return p.Threshold > 0 && p.Duration > 0
+}
+
+// lockDuration returns how long the account is locked after the number of
+// failures in a row
+func (p LockoutPolicy) lockDuration(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+	max := p.MaxDuration
+	if max <= 0 {
+		max = defaultMaxLockout
+	}
+	d := p.Duration
+	for i := p.Threshold; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// checkLockout returns LockedError if sign in of the user is locked. Unknown
+// usernames are never locked, so they aren't revealed
+func (s *AuthService) checkLockout(uname string) error {
+	if !s.lockout.Enabled() {
+		return nil
+	}
+	until, err := s.storage.LoginLockedUntil(uname)
+	if err == nil && time.Now().Before(until) {
+		return &LockedError{until}
+	}
+	return nil
+}
+
+// loginFailed counts the failed attempt and locks the account when there're
+// too many of them
+func (s *AuthService) loginFailed(uname string) {
+	if !s.lockout.Enabled() {
+		return
+	}
+	failures, err := s.storage.RecordLoginFailure(uname)
+	if err != nil {
+		return
+	}
+	if d := s.lockout.lockDuration(failures); d > 0 {
+		if err := s.storage.LockLogin(uname, time.Now().Add(d)); err != nil {
+			s.recovery.log.Error("can't lock sign in", zap.String("username", uname), zap.Error(err))
+		}
+	}
+}
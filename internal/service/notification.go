@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/mailer"
+	"go.uber.org/zap"
+)
+
+// maxExcerptLen limits the part of the comment quoted in the email
+const maxExcerptLen = 500
+
+type NotificationStorage interface {
+	GetUserByID(userID int) (core.User, error)
+	GetSettings(userID int) (core.UserSettings, error)
+	MarkNotified(eventID int64) (bool, error)
+}
+
+// NotificationTodos provides todos mentioned in notifications
+type NotificationTodos interface {
+	GetTodoByID(listID, todoID int) (core.TodoItem, error)
+}
+
+// NotificationService emails users about assignments and mentions, users
+// without verified email or who turned the notification off aren't notified
+type NotificationService struct {
+	storage NotificationStorage
+	todos   NotificationTodos
+	mailer  mailer.Mailer
+	appURL  string
+	log     *zap.Logger
+}
+
+func NewNotificationService(storage NotificationStorage, todos NotificationTodos, m mailer.Mailer, appURL string, log *zap.Logger) *NotificationService {
+	return &NotificationService{storage, todos, m, strings.TrimSuffix(appURL, "/"), log}
+}
+
+// Publish notifies the assignee of the todo about the assignment. The event is
+// recorded before the email is sent, so the outbox can deliver it again when
+// other publishers fail, while nobody gets the same email twice. Other errors
+// are only logged
+func (s *NotificationService) Publish(e core.Event) error {
+	if e.Type != core.EventTodoAssigned {
+		return nil
+	}
+	if first, err := s.storage.MarkNotified(e.ID); err != nil || !first {
+		return err
+	}
+	var a core.TodoAssignment
+	if err := json.Unmarshal(e.Data, &a); err != nil {
+		s.log.Error("can't decode assignment", zap.Int64("eventID", e.ID), zap.Error(err))
+		return nil
+	}
+	if a.AssigneeID == 0 || a.AssigneeID == e.UserID {
+		return nil
+	}
+	to, ok := s.recipient(a.AssigneeID, func(n core.NotificationSettings) bool { return n.Assigned })
+	if !ok {
+		return nil
+	}
+	s.send(mailer.Message{
+		To:      to.Email,
+		Subject: fmt.Sprintf("Assigned to you: %s", a.Title),
+		Body: fmt.Sprintf("Hi %s,\n\n%s assigned the todo \"%s\" to you.\n%s\n\n"+
+			"You can turn these emails off in your settings.\n", to.Name, s.actor(e.UserID), a.Title, s.todoLink(e.ListID, a.ID)),
+	})
+	return nil
+}
+
+// NotifyMentions emails users mentioned in the comment, except its author and
+// users mentioned before the comment was edited
+func (s *NotificationService) NotifyMentions(listID int, c core.Comment, previous []core.Mention) {
+	notified := make(map[int]bool)
+	for _, m := range previous {
+		notified[m.UserID] = true
+	}
+	var todo core.TodoItem
+	for _, m := range c.Mentions {
+		if m.UserID == c.AuthorID || notified[m.UserID] {
+			continue
+		}
+		notified[m.UserID] = true
+		to, ok := s.recipient(m.UserID, func(n core.NotificationSettings) bool { return n.Mentioned })
+		if !ok {
+			continue
+		}
+		if todo.ID == 0 {
+			t, err := s.todos.GetTodoByID(listID, c.TodoID)
+			if err != nil {
+				s.log.Error("can't get mentioned todo", zap.Int("todoID", c.TodoID), zap.Error(err))
+				return
+			}
+			todo = t
+		}
+		s.send(mailer.Message{
+			To:      to.Email,
+			Subject: fmt.Sprintf("You were mentioned in: %s", todo.Title),
+			Body: fmt.Sprintf("Hi %s,\n\n%s mentioned you in a comment to \"%s\":\n\n%s\n\n%s\n\n"+
+				"You can turn these emails off in your settings.\n",
+				to.Name, s.actor(c.AuthorID), todo.Title, excerpt(c.Body), s.todoLink(listID, todo.ID)),
+		})
+	}
+}
+
+// recipient returns the user when the notification is enabled in their
+// settings and their email is verified
+func (s *NotificationService) recipient(userID int, enabled func(n core.NotificationSettings) bool) (core.User, bool) {
+	settings, err := s.storage.GetSettings(userID)
+	if err != nil {
+		s.log.Error("can't get user settings", zap.Int("userID", userID), zap.Error(err))
+		return core.User{}, false
+	}
+	if !enabled(settings.Notifications) {
+		return core.User{}, false
+	}
+	u, err := s.storage.GetUserByID(userID)
+	if err != nil {
+		s.log.Error("can't get user", zap.Int("userID", userID), zap.Error(err))
+		return core.User{}, false
+	}
+	return u, u.Email != "" && u.EmailVerified && !u.Disabled
+}
+
+// actor returns the name of the user who has made the change
+func (s *NotificationService) actor(userID int) string {
+	u, err := s.storage.GetUserByID(userID)
+	if err != nil || u.Name == "" {
+		return "Someone"
+	}
+	return u.Name
+}
+
+func (s *NotificationService) todoLink(listID, todoID int) string {
+	return fmt.Sprintf("%s/api/lists/%d/todos/%d", s.appURL, listID, todoID)
+}
+
+func (s *NotificationService) send(m mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, m); err != nil {
+			s.log.Error("can't send email", zap.String("subject", m.Subject), zap.Error(err))
+		}
+	}()
+}
+
+// excerpt quotes the beginning of the comment
+func excerpt(body string) string {
+	if runes := []rune(body); len(runes) > maxExcerptLen {
+		body = string(runes[:maxExcerptLen]) + "…"
+	}
+	return "> " + strings.ReplaceAll(body, "\n", "\n> ")
+}
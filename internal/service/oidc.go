@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/oidc"
+)
+
+const (
+	oidcSessionKey = "123oidc123"
+	oidcSessionTTL = 10 * time.Minute
+	maxUsernameLen = 200
+)
+
+var ErrInvalidOIDCSession = errors.New("login session is invalid or expired")
+
+type OIDCProvider interface {
+	Issuer() string
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (oidc.Claims, error)
+}
+
+type IdentityStorage interface {
+	GetOrCreateUser(u core.User, i core.Identity) (core.User, error)
+}
+
+// OIDCService signs users in through the external OpenID provider, users are
+// created on their first sign in
+type OIDCService struct {
+	provider OIDCProvider
+	storage  IdentityStorage
+	auth     *AuthService
+}
+
+// oidcSession holds secrets of the sign in which is in progress, it's kept by
+// the user agent until the provider redirects it back
+type oidcSession struct {
+	jwt.RegisteredClaims
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+func NewOIDCService(provider OIDCProvider, storage IdentityStorage, auth *AuthService) *OIDCService {
+	return &OIDCService{provider, storage, auth}
+}
+
+// BeginLogin returns URL of the provider's sign in page and the signed session
+// which has to be passed to FinishLogin
+func (s *OIDCService) BeginLogin(ctx context.Context) (string, string, error) {
+	var sess oidcSession
+	for _, v := range []*string{&sess.State, &sess.Nonce, &sess.Verifier} {
+		random, err := oidc.RandomString()
+		if err != nil {
+			return "", "", err
+		}
+		*v = random
+	}
+	authURL, err := s.provider.AuthCodeURL(ctx, sess.State, sess.Nonce, sess.Verifier)
+	if err != nil {
+		return "", "", err
+	}
+	sess.ExpiresAt = jwt.NewNumericDate(time.Now().Add(oidcSessionTTL))
+	session, err := jwt.NewWithClaims(jwt.SigningMethodHS256, sess).SignedString([]byte(oidcSessionKey))
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, session, nil
+}
+
+// FinishLogin exchanges the code returned by the provider and signs in the user
+// linked to the provider's identity the same way as SignIn does
+func (s *OIDCService) FinishLogin(ctx context.Context, session, state, code string) (core.SignIn, error) {
+	var sess oidcSession
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	_, err := parser.ParseWithClaims(session, &sess, func(*jwt.Token) (interface{}, error) {
+		return []byte(oidcSessionKey), nil
+	})
+	if err != nil || subtle.ConstantTimeCompare([]byte(sess.State), []byte(state)) != 1 {
+		return core.SignIn{}, ErrInvalidOIDCSession
+	}
+	claims, err := s.provider.Exchange(ctx, code, sess.Verifier, sess.Nonce)
+	if err != nil {
+		return core.SignIn{}, err
+	}
+	username := claims.PreferredUsername
+	if username == "" {
+		username = claims.Email
+	}
+	if username == "" {
+		username = claims.Subject
+	}
+	if runes := []rune(username); len(runes) > maxUsernameLen {
+		username = string(runes[:maxUsernameLen])
+	}
+	name := claims.Name
+	if name == "" {
+		name = username
+	}
+	identity := core.Identity{Issuer: s.provider.Issuer(), Subject: claims.Subject}
+	if claims.EmailVerified {
+		identity.Email = claims.Email
+	}
+	user, err := s.storage.GetOrCreateUser(core.User{Name: name, Username: username}, identity)
+	if err != nil {
+		return core.SignIn{}, err
+	}
+	// the provider replaces the password only, so the second factor is required
+	return s.auth.signInUser(user.ID)
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/oidc"
+	"github.com/vbetsun/todo-app/internal/oidc/oidctest"
+)
+
+const (
+	testClientID    = "todo-app"
+	testRedirectURL = "https://todo.example/auth/oidc/callback"
+)
+
+// identityStorage links identities to users in memory, it serves the users to
+// AuthService as well, so tokens of the signed in users can be parsed
+type identityStorage struct {
+	AuthStorage
+	mu         sync.Mutex
+	users      map[int]core.User
+	identities map[string]int
+	linked     []core.Identity
+}
+
+func newIdentityStorage() *identityStorage {
+	return &identityStorage{users: make(map[int]core.User), identities: make(map[string]int)}
+}
+
+func (m *identityStorage) GetOrCreateUser(u core.User, i core.Identity) (core.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.linked = append(m.linked, i)
+	key := i.Issuer + " " + i.Subject
+	if id, ok := m.identities[key]; ok {
+		return m.users[id], nil
+	}
+	u.ID = len(m.users) + 1
+	m.users[u.ID] = u
+	m.identities[key] = u.ID
+	return u, nil
+}
+
+// GetTOTP reports the second factor disabled for every user
+func (m *identityStorage) GetTOTP(userID int) (core.TOTP, error) {
+	return core.TOTP{}, nil
+}
+
+func (m *identityStorage) GetUserByID(userID int) (core.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[userID]
+	if !ok {
+		return u, errors.New("user not found")
+	}
+	return u, nil
+}
+
+func newTestOIDCService(t *testing.T) (*OIDCService, *oidctest.Issuer, *identityStorage) {
+	t.Helper()
+	storage := newIdentityStorage()
+	s, issuer := newOIDCServiceWithStorage(t, storage)
+	return s, issuer, storage
+}
+
+// newOIDCServiceWithStorage returns the service signing in through the stub
+// provider, storage serves AuthService as well
+func newOIDCServiceWithStorage(t *testing.T, storage interface {
+	AuthStorage
+	IdentityStorage
+}) (*OIDCService, *oidctest.Issuer) {
+	t.Helper()
+	issuer, srv, err := oidctest.NewServer(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:      issuer.URL,
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+	})
+	auth := NewAuthService(storage, nil, nil, LockoutPolicy{})
+	return NewOIDCService(provider, storage, auth), issuer
+}
+
+// authorize follows the authorization URL to the issuer and returns query of
+// the redirect back to the app
+func authorize(t *testing.T, authURL, loginHint string) url.Values {
+	t.Helper()
+	if loginHint != "" {
+		authURL += "&login_hint=" + url.QueryEscape(loginHint)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	loc, err := resp.Location()
+	if err != nil {
+		t.Fatalf("authorize returned %d without redirect: %v", resp.StatusCode, err)
+	}
+	if !strings.HasPrefix(loc.String(), testRedirectURL+"?") {
+		t.Fatalf("redirected to %s, want %s", loc, testRedirectURL)
+	}
+	return loc.Query()
+}
+
+func TestOIDCLogin(t *testing.T) {
+	s, issuer, storage := newTestOIDCService(t)
+	ctx := context.Background()
+
+	authURL, session, err := s.BeginLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" || q.Get("redirect_uri") != testRedirectURL {
+		t.Errorf("authorization URL %s doesn't request code flow with PKCE", authURL)
+	}
+	back := authorize(t, authURL, "")
+	if back.Get("state") != q.Get("state") {
+		t.Errorf("state = %q, want %q", back.Get("state"), q.Get("state"))
+	}
+	res, err := s.FinishLogin(ctx, session, back.Get("state"), back.Get("code"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ChallengeToken != "" {
+		t.Errorf("sign in without second factor returned challenge")
+	}
+	p, err := s.auth.ParseToken(res.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := storage.users[p.UserID]
+	if user.Username != issuer.Identity.PreferredUsername || user.Name != issuer.Identity.Name {
+		t.Errorf("signed in user = %+v, want identity %+v", user, issuer.Identity)
+	}
+	want := core.Identity{Issuer: issuer.URL, Subject: issuer.Identity.Subject, Email: issuer.Identity.Email}
+	if len(storage.linked) != 1 || storage.linked[0] != want {
+		t.Errorf("linked identities = %+v, want %+v", storage.linked, want)
+	}
+
+	// the code can be exchanged only once
+	if _, err := s.FinishLogin(ctx, session, back.Get("state"), back.Get("code")); err == nil {
+		t.Error("code was exchanged twice")
+	}
+
+	// the next sign in of the identity returns the same user
+	authURL, session, err = s.BeginLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back = authorize(t, authURL, "")
+	res, err = s.FinishLogin(ctx, session, back.Get("state"), back.Get("code"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2, err := s.auth.ParseToken(res.Token); err != nil || p2.UserID != p.UserID {
+		t.Errorf("second sign in returned user %d (%v), want %d", p2.UserID, err, p.UserID)
+	}
+}
+
+func TestOIDCLoginRejectsForeignSession(t *testing.T) {
+	s, _, storage := newTestOIDCService(t)
+	ctx := context.Background()
+
+	authURL, session, err := s.BeginLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back := authorize(t, authURL, "")
+	_, otherSession, err := s.BeginLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		session string
+		state   string
+		want    error
+	}{
+		{"tampered session", session + "x", back.Get("state"), ErrInvalidOIDCSession},
+		{"state mismatch", session, "forged", ErrInvalidOIDCSession},
+		// the state is checked first, so the other session's verifier isn't used
+		{"session of another login", otherSession, back.Get("state"), ErrInvalidOIDCSession},
+	}
+	for _, tt := range tests {
+		if _, err := s.FinishLogin(ctx, tt.session, tt.state, back.Get("code")); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if len(storage.linked) != 0 {
+		t.Errorf("identities %+v were linked by invalid sessions", storage.linked)
+	}
+	// the code is still valid, since it wasn't sent to the provider
+	if _, err := s.FinishLogin(ctx, session, back.Get("state"), back.Get("code")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOIDCLoginWrongVerifier(t *testing.T) {
+	s, _, storage := newTestOIDCService(t)
+	ctx := context.Background()
+
+	authURL, _, err := s.BeginLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back := authorize(t, authURL, "")
+	// the session with the same state but another verifier, as if the state leaked
+	sess := oidcSession{State: back.Get("state"), Nonce: "nonce", Verifier: "another-verifier"}
+	sess.ExpiresAt = jwt.NewNumericDate(time.Now().Add(oidcSessionTTL))
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, sess).SignedString([]byte(oidcSessionKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FinishLogin(ctx, forged, back.Get("state"), back.Get("code")); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("error = %v, want invalid_grant", err)
+	}
+	if len(storage.linked) != 0 {
+		t.Errorf("identities %+v were linked without the verifier", storage.linked)
+	}
+}
+
+func TestOIDCUsernameTruncatedByRunes(t *testing.T) {
+	s, _, storage := newTestOIDCService(t)
+	ctx := context.Background()
+
+	hint := strings.Repeat("ж", maxUsernameLen+10)
+	authURL, session, err := s.BeginLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back := authorize(t, authURL, hint)
+	if _, err := s.FinishLogin(ctx, session, back.Get("state"), back.Get("code")); err != nil {
+		t.Fatal(err)
+	}
+	username := storage.users[1].Username
+	if !utf8.ValidString(username) || utf8.RuneCountInString(username) != maxUsernameLen {
+		t.Errorf("username has %d runes (valid UTF-8: %v), want %d", utf8.RuneCountInString(username), utf8.ValidString(username), maxUsernameLen)
+	}
+}
+
+// twoFactorIdentityStorage links every identity to the user with enabled TOTP
+type twoFactorIdentityStorage struct {
+	*twoFactorStorage
+}
+
+func (m twoFactorIdentityStorage) GetOrCreateUser(u core.User, i core.Identity) (core.User, error) {
+	return m.user, nil
+}
+
+func TestOIDCLoginRequiresSecondFactor(t *testing.T) {
+	storage := newTwoFactorStorage(t)
+	s, _ := newOIDCServiceWithStorage(t, twoFactorIdentityStorage{storage})
+	ctx := context.Background()
+
+	authURL, session, err := s.BeginLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back := authorize(t, authURL, "")
+	res, err := s.FinishLogin(ctx, session, back.Get("state"), back.Get("code"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Token != "" || res.ChallengeToken == "" {
+		t.Fatalf("sign in = %+v, want challenge", res)
+	}
+	if _, err := s.auth.ParseToken(res.ChallengeToken); err == nil {
+		t.Error("challenge token is accepted as access token")
+	}
+	token, err := s.auth.VerifyChallenge(res.ChallengeToken, currentCode(t, storage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := s.auth.ParseToken(token); err != nil || p.UserID != storage.user.ID {
+		t.Errorf("token after the second factor is of user %d (%v), want %d", p.UserID, err, storage.user.ID)
+	}
+}
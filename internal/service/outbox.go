@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+const (
+	outboxBatchSize    = 100
+	outboxPollInterval = 500 * time.Millisecond
+)
+
+type OutboxStorage interface {
+	ProcessOutbox(limit int, fn func(core.Event) error) (int, error)
+}
+
+// OutboxRelay moves events, which were saved together with the changes of lists
+// and todos, from the outbox to the publisher
+type OutboxRelay struct {
+	storage   OutboxStorage
+	publisher EventPublisher
+	log       *zap.Logger
+}
+
+func NewOutboxRelay(storage OutboxStorage, publisher EventPublisher, log *zap.Logger) *OutboxRelay {
+	return &OutboxRelay{storage, publisher, log}
+}
+
+// Run relays events until the context is canceled
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.drain(ctx)
+		}
+	}
+}
+
+// drain relays batches while the outbox is full
+func (r *OutboxRelay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := r.storage.ProcessOutbox(outboxBatchSize, r.publisher.Publish)
+		if err != nil {
+			r.log.Error("can't relay events from outbox", zap.Error(err))
+			return
+		}
+		if n < outboxBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/quickadd"
+)
+
+// defaultListTitle is the title of the list which gets quickly added todos
+// when the list isn't named, it's created in the personal workspace on demand
+const defaultListTitle = "Inbox"
+
+var (
+	ErrEmptyQuickAdd      = errors.New("text doesn't contain title of the todo")
+	ErrQuickAddNoSuchList = errors.New("list with the given title doesn't exist")
+)
+
+// QuickAddListService is the part of TodoListService used for finding the list
+// of the new todo
+type QuickAddListService interface {
+	CreateList(userID int, list core.Todolist) (core.Todolist, error)
+	GetAllLists(userID int) ([]core.Todolist, error)
+}
+
+// QuickAddSettings provides preferences of the user which affect parsing
+type QuickAddSettings interface {
+	GetSettings(userID int) (core.UserSettings, error)
+}
+
+// QuickAddService creates todos from free text, like "Pay rent tomorrow 9am
+// #home !high every month"
+type QuickAddService struct {
+	lists    QuickAddListService
+	todos    *TodoItemService
+	settings QuickAddSettings
+}
+
+func NewQuickAddService(lists QuickAddListService, todos *TodoItemService, settings QuickAddSettings) *QuickAddService {
+	return &QuickAddService{lists, todos, settings}
+}
+
+// QuickAdd parses the text and creates the todo in the list with the given
+// title or in the user's default list, relative dates are resolved in the
+// location, the user's time zone is used when it's nil. It returns ID of the
+// list together with the created todo
+func (s *QuickAddService) QuickAdd(userID int, text, listTitle string, loc *time.Location) (int, core.TodoItem, error) {
+	settings, err := s.settings.GetSettings(userID)
+	if err != nil {
+		return 0, core.TodoItem{}, err
+	}
+	if loc == nil {
+		loc = settings.Location()
+	}
+	res := quickadd.Parser{WeekStart: settings.WeekStart}.Parse(text, time.Now().In(loc))
+	if res.Title == "" {
+		return 0, core.TodoItem{}, ErrEmptyQuickAdd
+	}
+	listID, err := s.listID(userID, listTitle, settings.DefaultListID)
+	if err != nil {
+		return 0, core.TodoItem{}, err
+	}
+	todo, err := s.todos.CreateTodo(userID, listID, core.TodoItem{
+		Title:      res.Title,
+		Due:        res.Due,
+		Labels:     res.Labels,
+		Priority:   res.Priority,
+		Recurrence: res.Recurrence,
+	})
+	return listID, todo, err
+}
+
+// listID finds the user's list by its title ignoring case, the oldest one wins
+// when lists of several workspaces have the same title. Without the title the
+// default list from settings is used while the user has it, otherwise Inbox,
+// which is created when it doesn't exist yet
+func (s *QuickAddService) listID(userID int, title string, defaultListID int) (int, error) {
+	title = strings.TrimSpace(title)
+	isDefault := title == ""
+	if isDefault {
+		title = defaultListTitle
+	}
+	lists, err := s.lists.GetAllLists(userID)
+	if err != nil {
+		return 0, err
+	}
+	listID := 0
+	for _, l := range lists {
+		if isDefault && l.ID == defaultListID {
+			return l.ID, nil
+		}
+		if strings.EqualFold(l.Title, title) && (listID == 0 || l.ID < listID) {
+			listID = l.ID
+		}
+	}
+	if listID != 0 {
+		return listID, nil
+	}
+	if !isDefault {
+		return 0, ErrQuickAddNoSuchList
+	}
+	list, err := s.lists.CreateList(userID, core.Todolist{Title: defaultListTitle})
+	return list.ID, err
+}
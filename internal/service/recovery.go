@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/mailer"
+	"go.uber.org/zap"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 24 * time.Hour
+	oneTimeTokenSize     = 32
+	mailTimeout          = 30 * time.Second
+)
+
+var (
+	ErrInvalidEmail        = errors.New("invalid email address")
+	ErrInvalidOneTimeToken = errors.New("token is invalid, expired or already used")
+	ErrMissingEmail        = errors.New("account doesn't have email")
+	ErrEmailVerified       = errors.New("email is already verified")
+	errEmailAddressTooLong = errors.New("email can't be longer than 255 characters")
+)
+
+type RecoveryStorage interface {
+	GetUserByID(userID int) (core.User, error)
+	GetUserByEmail(email string) (core.User, error)
+	CreateOneTimeToken(t core.OneTimeToken) error
+	VerifyEmail(hash string) error
+	ResetPassword(hash, passwordHash string) error
+}
+
+// RecoveryService verifies users' emails and resets forgotten passwords through
+// single-use tokens sent by email
+type RecoveryService struct {
+	storage  RecoveryStorage
+	mailer   mailer.Mailer
+	appURL   string
+	resetURL string
+	log      *zap.Logger
+}
+
+// NewRecoveryService creates the service. The resetURL is the optional page of
+// the frontend which gets the password reset token in the "token" param
+func NewRecoveryService(storage RecoveryStorage, m mailer.Mailer, appURL, resetURL string, log *zap.Logger) *RecoveryService {
+	return &RecoveryService{storage, m, strings.TrimSuffix(appURL, "/"), resetURL, log}
+}
+
+// SendVerification sends the link which confirms user's email
+func (s *RecoveryService) SendVerification(u core.User) error {
+	token, err := s.createToken(u, core.TokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := s.appURL + "/auth/verify-email?token=" + url.QueryEscape(token)
+	s.send(mailer.Message{
+		To:      u.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hi %s,\n\nplease confirm your email by opening the link:\n%s\n\n"+
+			"The link expires in %s.\n", u.Name, link, emailVerificationTTL),
+	})
+	return nil
+}
+
+// ResendVerification sends the verification link again if the user's email
+// isn't verified yet
+func (s *RecoveryService) ResendVerification(userID int) error {
+	u, err := s.storage.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if u.Email == "" {
+		return ErrMissingEmail
+	}
+	if u.EmailVerified {
+		return ErrEmailVerified
+	}
+	return s.SendVerification(u)
+}
+
+// VerifyEmail marks the email as verified by the token from the email
+func (s *RecoveryService) VerifyEmail(token string) error {
+	if err := s.storage.VerifyEmail(hashSecret(token)); err != nil {
+		return ErrInvalidOneTimeToken
+	}
+	return nil
+}
+
+// ForgotPassword sends password reset token to the email if it's verified email
+// of any user. The result doesn't depend on it, so emails can't be enumerated
+func (s *RecoveryService) ForgotPassword(email string) error {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	u, err := s.storage.GetUserByEmail(email)
+	if err != nil || !u.EmailVerified {
+		return nil
+	}
+	token, err := s.createToken(u, core.TokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Hi %s,\n\nsomeone asked to reset password of your account %s.\n", u.Name, u.Username)
+	if link := s.resetLink(token); link != "" {
+		body += fmt.Sprintf("If it was you, open the link to choose the new password:\n%s\n\n", link)
+	}
+	body += fmt.Sprintf("Or send the token with the new password to POST %s/auth/reset-password:\n%s\n\n"+
+		"The token expires in %s, ignore this email if you didn't ask for it.\n",
+		s.appURL, token, passwordResetTTL)
+	s.send(mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body:    body,
+	})
+	return nil
+}
+
+// resetLink returns the frontend page with the token or empty string when the
+// page isn't configured, the API serves the reset by POST only
+func (s *RecoveryService) resetLink(token string) string {
+	if s.resetURL == "" {
+		return ""
+	}
+	u, err := url.Parse(s.resetURL)
+	if err != nil {
+		s.log.Error("invalid password reset URL", zap.Error(err))
+		return ""
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// ResetPassword replaces the password of the token's user and unlocks the
+// account locked by failed sign in attempts
+func (s *RecoveryService) ResetPassword(token, password string) error {
+	if password == "" {
+		return ErrEmptyPassword
+	}
+	if err := s.storage.ResetPassword(hashSecret(token), generateHash(password)); err != nil {
+		return ErrInvalidOneTimeToken
+	}
+	return nil
+}
+
+func (s *RecoveryService) createToken(u core.User, purpose string, ttl time.Duration) (string, error) {
+	b := make([]byte, oneTimeTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	err := s.storage.CreateOneTimeToken(core.OneTimeToken{
+		UserID:    u.ID,
+		Purpose:   purpose,
+		Hash:      hashSecret(token),
+		Email:     u.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	return token, err
+}
+
+// send sends the email in background, so response time doesn't reveal whether
+// the email was sent
+func (s *RecoveryService) send(m mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, m); err != nil {
+			s.log.Error("can't send email", zap.String("subject", m.Subject), zap.Error(err))
+		}
+	}()
+}
+
+// normalizeEmail validates the address and returns it in lower case
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+	if len(email) > maxUserFieldLen {
+		return "", errEmailAddressTooLong
+	}
+	return email, nil
+}
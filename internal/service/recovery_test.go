@@ -0,0 +1,259 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/mailer"
+	"go.uber.org/zap"
+)
+
+// oneTimeToken represents the stored token, it's used once like in the database
+type oneTimeToken struct {
+	core.OneTimeToken
+	used bool
+}
+
+// recoveryStorage keeps users and their one-time tokens in memory
+type recoveryStorage struct {
+	AuthStorage
+	users  map[int]*core.User
+	tokens map[string]*oneTimeToken
+}
+
+func newRecoveryStorage() *recoveryStorage {
+	return &recoveryStorage{
+		users: map[int]*core.User{1: {
+			ID:            1,
+			Name:          "Alice",
+			Username:      "alice",
+			Email:         "alice@example.com",
+			Password:      generateHash("password"),
+			EmailVerified: true,
+		}},
+		tokens: make(map[string]*oneTimeToken),
+	}
+}
+
+func (m *recoveryStorage) CreateUser(u core.User) (core.User, error) {
+	u.ID = len(m.users) + 1
+	m.users[u.ID] = &u
+	return u, nil
+}
+
+func (m *recoveryStorage) GetUserByID(userID int) (core.User, error) {
+	u, ok := m.users[userID]
+	if !ok {
+		return core.User{}, errors.New("user not found")
+	}
+	return *u, nil
+}
+
+func (m *recoveryStorage) GetUserByEmail(email string) (core.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return *u, nil
+		}
+	}
+	return core.User{}, errors.New("user not found")
+}
+
+func (m *recoveryStorage) CreateOneTimeToken(t core.OneTimeToken) error {
+	for _, old := range m.tokens {
+		if old.UserID == t.UserID && old.Purpose == t.Purpose {
+			old.used = true
+		}
+	}
+	m.tokens[t.Hash] = &oneTimeToken{OneTimeToken: t}
+	return nil
+}
+
+func (m *recoveryStorage) useToken(hash, purpose string) (*core.User, error) {
+	t, ok := m.tokens[hash]
+	if !ok || t.used || t.Purpose != purpose || !time.Now().Before(t.ExpiresAt) {
+		return nil, errors.New("token not found")
+	}
+	t.used = true
+	return m.users[t.UserID], nil
+}
+
+func (m *recoveryStorage) VerifyEmail(hash string) error {
+	u, err := m.useToken(hash, core.TokenEmailVerification)
+	if err != nil {
+		return err
+	}
+	u.EmailVerified = true
+	return nil
+}
+
+func (m *recoveryStorage) ResetPassword(hash, passwordHash string) error {
+	u, err := m.useToken(hash, core.TokenPasswordReset)
+	if err != nil {
+		return err
+	}
+	u.Password = passwordHash
+	u.TokenVersion++
+	return nil
+}
+
+// mailTokenRe matches the base64 encoded one-time token
+var mailTokenRe = regexp.MustCompile(`[\w-]{43}`)
+
+// waitMail waits for n emails to the address and returns the latest one with
+// its token, the emails are sent in background
+func waitMail(t *testing.T, m *mailer.Memory, to string, n int) (mailer.Message, string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if msg, ok := m.Last(to); ok && countMails(m, to) >= n {
+			token := mailTokenRe.FindString(msg.Body)
+			if token == "" {
+				t.Fatalf("email doesn't contain the token: %q", msg.Body)
+			}
+			return msg, token
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("%d emails to %s weren't sent", n, to)
+	return mailer.Message{}, ""
+}
+
+func countMails(m *mailer.Memory, to string) int {
+	n := 0
+	for _, msg := range m.Messages() {
+		if msg.To == to {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestRecoveryService(storage RecoveryStorage, resetURL string) (*RecoveryService, *mailer.Memory) {
+	m := mailer.NewMemory()
+	return NewRecoveryService(storage, m, "http://localhost:8000/", resetURL, zap.NewNop()), m
+}
+
+func TestForgotPassword(t *testing.T) {
+	storage := newRecoveryStorage()
+	storage.users[2] = &core.User{ID: 2, Username: "bob", Email: "bob@example.com"}
+	s, m := newTestRecoveryService(storage, "https://todo.example/reset-password")
+
+	for _, email := range []string{"nobody@example.com", "bob@example.com"} {
+		if err := s.ForgotPassword(email); err != nil {
+			t.Errorf("ForgotPassword(%q) error = %v, want the same response as for known email", email, err)
+		}
+	}
+	if len(storage.tokens) != 0 {
+		t.Fatalf("tokens were created for unknown or unverified email: %d", len(storage.tokens))
+	}
+	if err := s.ForgotPassword(" Alice@Example.com "); err != nil {
+		t.Fatal(err)
+	}
+	msg, token := waitMail(t, m, "alice@example.com", 1)
+	if !strings.Contains(msg.Body, "https://todo.example/reset-password?token="+token) {
+		t.Errorf("email doesn't contain the reset page link: %q", msg.Body)
+	}
+	if !strings.Contains(msg.Body, "POST http://localhost:8000/auth/reset-password") {
+		t.Errorf("email doesn't point to the reset route: %q", msg.Body)
+	}
+	if len(m.Messages()) != 1 {
+		t.Errorf("sent %d emails, want 1", len(m.Messages()))
+	}
+}
+
+func TestForgotPasswordWithoutResetPage(t *testing.T) {
+	s, m := newTestRecoveryService(newRecoveryStorage(), "")
+	if err := s.ForgotPassword("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := waitMail(t, m, "alice@example.com", 1)
+	if strings.Contains(msg.Body, "?token=") {
+		t.Errorf("email contains link to the page which isn't configured: %q", msg.Body)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	storage := newRecoveryStorage()
+	s, m := newTestRecoveryService(storage, "")
+	auth := NewAuthService(storage, nil, s, LockoutPolicy{})
+	session, err := auth.newToken(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ForgotPassword("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	_, older := waitMail(t, m, "alice@example.com", 1)
+	if err := s.ForgotPassword("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	_, token := waitMail(t, m, "alice@example.com", 2)
+
+	if err := s.ResetPassword(older, "new password"); !errors.Is(err, ErrInvalidOneTimeToken) {
+		t.Errorf("reset by the older token: error = %v, want %v", err, ErrInvalidOneTimeToken)
+	}
+	if err := s.ResetPassword(token, ""); !errors.Is(err, ErrEmptyPassword) {
+		t.Errorf("reset with empty password: error = %v, want %v", err, ErrEmptyPassword)
+	}
+	if err := s.ResetPassword(token, "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if storage.users[1].Password != generateHash("new password") {
+		t.Error("password wasn't replaced")
+	}
+	if err := s.ResetPassword(token, "other password"); !errors.Is(err, ErrInvalidOneTimeToken) {
+		t.Errorf("second reset by the token: error = %v, want %v", err, ErrInvalidOneTimeToken)
+	}
+	if _, err := auth.ParseToken(session); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("token issued before the reset: error = %v, want %v", err, ErrTokenRevoked)
+	}
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	storage := newRecoveryStorage()
+	s, m := newTestRecoveryService(storage, "")
+	if err := s.ForgotPassword("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	_, token := waitMail(t, m, "alice@example.com", 1)
+	storage.tokens[hashSecret(token)].ExpiresAt = time.Now().Add(-time.Second)
+
+	if err := s.ResetPassword(token, "new password"); !errors.Is(err, ErrInvalidOneTimeToken) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidOneTimeToken)
+	}
+	if storage.users[1].Password != generateHash("password") {
+		t.Error("password was replaced by the expired token")
+	}
+}
+
+func TestSignUpVerifiesEmail(t *testing.T) {
+	storage := newRecoveryStorage()
+	s, m := newTestRecoveryService(storage, "")
+	auth := NewAuthService(storage, nil, s, LockoutPolicy{})
+
+	user, err := auth.CreateUser(core.User{Name: "Bob", Username: "bob", Email: "Bob@Example.com", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, token := waitMail(t, m, "bob@example.com", 1)
+	if !strings.Contains(msg.Body, "http://localhost:8000/auth/verify-email?token="+token) {
+		t.Errorf("email doesn't contain the verification link: %q", msg.Body)
+	}
+	if storage.users[user.ID].EmailVerified {
+		t.Fatal("email is verified before the confirmation")
+	}
+	if err := s.VerifyEmail(token); err != nil {
+		t.Fatal(err)
+	}
+	if !storage.users[user.ID].EmailVerified {
+		t.Error("email isn't verified after the confirmation")
+	}
+	if err := s.VerifyEmail(token); !errors.Is(err, ErrInvalidOneTimeToken) {
+		t.Errorf("second confirmation: error = %v, want %v", err, ErrInvalidOneTimeToken)
+	}
+}
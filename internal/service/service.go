@@ -1,21 +1,107 @@
 package service
 
+import (
+	"github.com/vbetsun/todo-app/internal/blob"
+	"github.com/vbetsun/todo-app/internal/mailer"
+	"github.com/vbetsun/todo-app/internal/pubsub"
+	"go.uber.org/zap"
+)
+
 type Deps struct {
-	AuthStorage     AuthStorage
-	TodoListStorage TodoListStorage
-	TodoItemStorage TodoItemStorage
+	AuthStorage         AuthStorage
+	TodoListStorage     TodoListStorage
+	TodoItemStorage     TodoItemStorage
+	EventStorage        EventStorage
+	WebhookStorage      WebhookStorage
+	OutboxStorage       OutboxStorage
+	ImportStorage       ImportStorage
+	SyncStorage         SyncStorage
+	TokenStorage        AccessTokenStorage
+	IdentityStorage     IdentityStorage
+	AccountStorage      AccountStorage
+	RecoveryStorage     RecoveryStorage
+	AdminStorage        AdminStorage
+	WorkspaceStorage    WorkspaceStorage
+	CommentStorage      CommentStorage
+	AttachmentStorage   AttachmentStorage
+	SettingsStorage     SettingsStorage
+	NotificationStorage NotificationStorage
+	TemplateStorage     TemplateStorage
+	ShareLinkStorage    ShareLinkStorage
+	StatsStorage        StatsStorage
+	StatusStorage       StatusStorage
+	Blobs               blob.Store
+	AttachmentLimits    AttachmentLimits
+	Mailer              mailer.Mailer
+	AppURL              string
+	PasswordResetURL    string // optional, page of the frontend which resets the password
+	Lockout             LockoutPolicy
+	OIDCProvider        OIDCProvider // optional, sign in through OpenID provider is disabled without it
+	Log                 *zap.Logger
 }
 
 type Service struct {
-	Auth     *AuthService
-	TodoList *TodoListService
-	TodoItem *TodoItemService
+	Auth       *AuthService
+	TodoList   *TodoListService
+	TodoItem   *TodoItemService
+	Event      *EventService
+	Webhook    *WebhookService
+	Outbox     *OutboxRelay
+	Import     *ImportService
+	Export     *ExportService
+	Sync       *SyncService
+	Token      *AccessTokenService
+	OIDC       *OIDCService
+	Account    *AccountService
+	Recovery   *RecoveryService
+	Admin      *AdminService
+	Workspace  *WorkspaceService
+	Comment    *CommentService
+	Attachment *AttachmentService
+	QuickAdd   *QuickAddService
+	Settings   *SettingsService
+	Notifier   *NotificationService
+	Template   *TemplateService
+	ShareLink  *ShareLinkService
+	Stats      *StatsService
 }
 
 func NewService(deps Deps) *Service {
-	return &Service{
-		Auth:     NewAuthService(deps.AuthStorage),
-		TodoList: NewTodoListService(deps.TodoListStorage),
-		TodoItem: NewTodoItemService(deps.TodoItemStorage),
+	events := NewEventService(deps.EventStorage, pubsub.NewHub(), deps.Log)
+	webhooks := NewWebhookService(deps.WebhookStorage, deps.Log)
+	recovery := NewRecoveryService(deps.RecoveryStorage, deps.Mailer, deps.AppURL, deps.PasswordResetURL, deps.Log)
+	auth := NewAuthService(deps.AuthStorage, deps.TokenStorage, recovery, deps.Lockout)
+	export := NewExportService(deps.TodoListStorage, deps.TodoItemStorage)
+	lists := NewTodoListService(deps.TodoListStorage, deps.WorkspaceStorage)
+	todos := NewTodoItemService(deps.TodoItemStorage, deps.WorkspaceStorage, deps.StatusStorage)
+	settings := NewSettingsService(deps.SettingsStorage, deps.WorkspaceStorage)
+	notifier := NewNotificationService(deps.NotificationStorage, deps.TodoItemStorage, deps.Mailer, deps.AppURL, deps.Log)
+	s := &Service{
+		Auth:       auth,
+		TodoList:   lists,
+		TodoItem:   todos,
+		Event:      events,
+		Webhook:    webhooks,
+		Outbox:     NewOutboxRelay(deps.OutboxStorage, Publishers{events, webhooks, notifier}, deps.Log),
+		Import:     NewImportService(deps.ImportStorage),
+		Export:     export,
+		Sync:       NewSyncService(deps.SyncStorage, lists, todos),
+		Token:      NewAccessTokenService(deps.TokenStorage),
+		Account:    NewAccountService(deps.AccountStorage, auth, export),
+		Recovery:   recovery,
+		Admin:      NewAdminService(deps.AdminStorage),
+		Workspace:  NewWorkspaceService(deps.WorkspaceStorage, deps.Mailer, deps.AppURL, deps.Log),
+		Comment:    NewCommentService(deps.CommentStorage, deps.WorkspaceStorage, notifier),
+		Attachment: NewAttachmentService(deps.AttachmentStorage, deps.Blobs, deps.WorkspaceStorage, deps.AttachmentLimits, deps.Log),
+		QuickAdd:   NewQuickAddService(lists, todos, settings),
+		Settings:   settings,
+		Notifier:   notifier,
+		Template:   NewTemplateService(deps.TemplateStorage, deps.TodoListStorage, deps.TodoItemStorage, deps.WorkspaceStorage, settings),
+		ShareLink:  NewShareLinkService(deps.ShareLinkStorage, deps.WorkspaceStorage),
+		Stats:      NewStatsService(deps.StatsStorage, settings),
+	}
+	if deps.OIDCProvider != nil {
+		s.OIDC = NewOIDCService(deps.OIDCProvider, deps.IdentityStorage, auth)
 	}
+	return s
 }
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+const maxTimeZoneLen = 64
+
+var (
+	ErrUnknownTimeZone         = errors.New("unknown time zone")
+	ErrInvalidWeekStart        = errors.New("week start must be between 0 (Sunday) and 6 (Saturday)")
+	ErrInvalidLocale           = errors.New("locale must be a language tag like en or en-US")
+	ErrDefaultListAccess error = AccessError("default list must be writable by the user")
+)
+
+// localePattern matches BCP 47 language tags, e.g. en, uk-UA or zh-Hant-TW
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8}){0,3}$`)
+
+type SettingsStorage interface {
+	GetSettings(userID int) (core.UserSettings, error)
+	UpdateSettings(userID int, s core.UserSettings) (core.UserSettings, error)
+}
+
+// SettingsService manages preferences of the users, other services use them
+// for time zones, default lists and notifications
+type SettingsService struct {
+	storage SettingsStorage
+	access  WorkspaceAccess
+}
+
+func NewSettingsService(storage SettingsStorage, access WorkspaceAccess) *SettingsService {
+	return &SettingsService{storage, access}
+}
+
+func (s *SettingsService) GetSettings(userID int) (core.UserSettings, error) {
+	return s.storage.GetSettings(userID)
+}
+
+// UpdateSettings validates and replaces all preferences of the user
+func (s *SettingsService) UpdateSettings(userID int, settings core.UserSettings) (core.UserSettings, error) {
+	if settings.TimeZone == "" || len(settings.TimeZone) > maxTimeZoneLen {
+		return core.UserSettings{}, ErrUnknownTimeZone
+	}
+	// an empty name and "Local" are accepted by time.LoadLocation, but they
+	// aren't zones of the user
+	if _, err := time.LoadLocation(settings.TimeZone); err != nil || settings.TimeZone == "Local" {
+		return core.UserSettings{}, ErrUnknownTimeZone
+	}
+	if settings.WeekStart < time.Sunday || settings.WeekStart > time.Saturday {
+		return core.UserSettings{}, ErrInvalidWeekStart
+	}
+	if !localePattern.MatchString(settings.Locale) {
+		return core.UserSettings{}, ErrInvalidLocale
+	}
+	if settings.DefaultListID < 0 {
+		return core.UserSettings{}, ErrDefaultListAccess
+	}
+	if settings.DefaultListID != 0 {
+		role, err := s.access.GetListRole(settings.DefaultListID, userID)
+		if err != nil || !core.CanWrite(role) {
+			return core.UserSettings{}, ErrDefaultListAccess
+		}
+	}
+	return s.storage.UpdateSettings(userID, settings)
+}
+
+// Location returns the time zone of the user, UTC is used when settings can't
+// be loaded
+func (s *SettingsService) Location(userID int) *time.Location {
+	settings, err := s.storage.GetSettings(userID)
+	if err != nil {
+		return time.UTC
+	}
+	return settings.Location()
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+const maxShareLinkPasswordLen = 255
+
+var (
+	ErrShareLinkExpiry                = errors.New("expiry of the link must be in the future")
+	ErrShareLinkPasswordTooLong       = errors.New("password can't be longer than 255 characters")
+	ErrShareLinkNotFound              = errors.New("link is invalid, expired or revoked")
+	ErrShareLinkDeleteAccess    error = AccessError("only the author or workspace admins can revoke the link")
+)
+
+type ShareLinkStorage interface {
+	GetShareLinks(listID int) ([]core.ShareLink, error)
+	GetShareLink(listID, linkID int) (core.ShareLink, error)
+	GetShareLinkByHash(hash string) (core.ShareLink, error)
+	CreateShareLink(l core.ShareLink) (core.ShareLink, error)
+	DeleteShareLink(linkID int) error
+	GetSharedList(listID int) (core.ListWithTodos, error)
+}
+
+// ShareLinkService manages read-only links which show lists to people without
+// accounts. Links are kept only as hashes of their tokens
+type ShareLinkService struct {
+	storage ShareLinkStorage
+	access  WorkspaceAccess
+}
+
+func NewShareLinkService(storage ShareLinkStorage, access WorkspaceAccess) *ShareLinkService {
+	return &ShareLinkService{storage, access}
+}
+
+// GetShareLinks returns links of the list to users who can change it
+func (s *ShareLinkService) GetShareLinks(userID, listID int) ([]core.ShareLink, error) {
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return nil, err
+	}
+	return s.storage.GetShareLinks(listID)
+}
+
+func (s *ShareLinkService) GetShareLink(listID, linkID int) (core.ShareLink, error) {
+	return s.storage.GetShareLink(listID, linkID)
+}
+
+// CreateShareLink creates the link to the list, the link without expiry works
+// until it's revoked and the link without password is open to everyone who has
+// the token. The token is returned only once
+func (s *ShareLinkService) CreateShareLink(userID, listID int, password string, expiresAt *time.Time) (core.ShareLink, error) {
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return core.ShareLink{}, err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return core.ShareLink{}, ErrShareLinkExpiry
+	}
+	if len(password) > maxShareLinkPasswordLen {
+		return core.ShareLink{}, ErrShareLinkPasswordTooLong
+	}
+	token, err := randomToken()
+	if err != nil {
+		return core.ShareLink{}, err
+	}
+	l := core.ShareLink{
+		ListID:    listID,
+		CreatedBy: userID,
+		Hash:      hashSecret(token),
+		ExpiresAt: expiresAt,
+	}
+	if password != "" {
+		l.PasswordHash = generateHash(password)
+	}
+	l, err = s.storage.CreateShareLink(l)
+	if err != nil {
+		return core.ShareLink{}, err
+	}
+	l.Token = token
+	return l, nil
+}
+
+// DeleteShareLink revokes the link, it's allowed to its author and to admins
+// of the workspace
+func (s *ShareLinkService) DeleteShareLink(userID, listID int, l core.ShareLink) error {
+	role, err := s.access.GetListRole(listID, userID)
+	if err != nil || !core.CanWrite(role) {
+		return ErrWorkspaceForbidden
+	}
+	if l.CreatedBy != userID && !core.CanManage(role) {
+		return ErrShareLinkDeleteAccess
+	}
+	return s.storage.DeleteShareLink(l.ID)
+}
+
+// GetShareLinkByToken returns the link which hasn't expired yet
+func (s *ShareLinkService) GetShareLinkByToken(token string) (core.ShareLink, error) {
+	l, err := s.storage.GetShareLinkByHash(hashSecret(token))
+	if err != nil || (l.ExpiresAt != nil && !l.ExpiresAt.After(time.Now())) {
+		return core.ShareLink{}, ErrShareLinkNotFound
+	}
+	return l, nil
+}
+
+// Unlock reports whether the password opens the link, links without password
+// are always open
+func (s *ShareLinkService) Unlock(l core.ShareLink, password string) bool {
+	if !l.HasPassword {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(generateHash(password)), []byte(l.PasswordHash)) == 1
+}
+
+// GetPublicList returns the shared list without IDs and members' details
+func (s *ShareLinkService) GetPublicList(l core.ShareLink) (core.PublicList, error) {
+	list, err := s.storage.GetSharedList(l.ListID)
+	if err != nil {
+		return core.PublicList{}, err
+	}
+	sort.Slice(list.Todos, func(i, j int) bool { return list.Todos[i].ID < list.Todos[j].ID })
+	public := core.PublicList{
+		Title:       list.Title,
+		Description: list.Description,
+		Todos:       make([]core.PublicTodo, 0, len(list.Todos)),
+		UpdatedAt:   list.UpdatedAt,
+	}
+	for _, t := range list.Todos {
+		public.Todos = append(public.Todos, core.PublicTodo{
+			Title:       t.Title,
+			Description: t.Description,
+			Done:        t.Done,
+			Due:         t.Due,
+			Priority:    t.Priority,
+			Labels:      t.Labels,
+		})
+	}
+	return public, nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 366
+)
+
+var (
+	ErrInvalidStatsDate  = errors.New("from and to must be dates like 2006-01-02")
+	ErrInvalidStatsRange = errors.New("range must start before it ends and be up to 366 days long")
+)
+
+type StatsStorage interface {
+	GetDailyCounts(userID int, timeZone string, from, to time.Time) ([]core.DayStats, error)
+	GetAvgCompletion(userID int, from, to time.Time) (float64, error)
+	GetListProgress(userID int) ([]core.ListProgress, error)
+}
+
+// StatsSettings provides time zones of the users, days of reports are taken in them
+type StatsSettings interface {
+	Location(userID int) *time.Location
+}
+
+// StatsService builds productivity reports over lists available to the user
+type StatsService struct {
+	storage  StatsStorage
+	settings StatsSettings
+}
+
+func NewStatsService(storage StatsStorage, settings StatsSettings) *StatsService {
+	return &StatsService{storage, settings}
+}
+
+// GetStats returns the report for days between from and to inclusive, which
+// are dates like 2006-01-02. The range ends today and lasts 30 days by default.
+// Days are taken in the location, the user's time zone is used when it's nil
+func (s *StatsService) GetStats(userID int, from, to string, loc *time.Location) (core.Stats, error) {
+	if loc == nil {
+		loc = s.settings.Location(userID)
+	}
+	end := startOfDay(time.Now().In(loc))
+	if to != "" {
+		day, err := time.ParseInLocation(dateLayout, to, loc)
+		if err != nil {
+			return core.Stats{}, ErrInvalidStatsDate
+		}
+		end = day
+	}
+	start := end.AddDate(0, 0, 1-defaultStatsDays)
+	if from != "" {
+		day, err := time.ParseInLocation(dateLayout, from, loc)
+		if err != nil {
+			return core.Stats{}, ErrInvalidStatsDate
+		}
+		start = day
+	}
+	if end.Before(start) || !end.Before(start.AddDate(0, 0, maxStatsDays)) {
+		return core.Stats{}, ErrInvalidStatsRange
+	}
+	// the range includes the whole last day
+	until := end.AddDate(0, 0, 1)
+	counts, err := s.storage.GetDailyCounts(userID, loc.String(), start, until)
+	if err != nil {
+		return core.Stats{}, err
+	}
+	avg, err := s.storage.GetAvgCompletion(userID, start, until)
+	if err != nil {
+		return core.Stats{}, err
+	}
+	lists, err := s.storage.GetListProgress(userID)
+	if err != nil {
+		return core.Stats{}, err
+	}
+	stats := core.Stats{
+		From:                 start.Format(dateLayout),
+		To:                   end.Format(dateLayout),
+		TimeZone:             loc.String(),
+		Days:                 statsDays(start, until, counts),
+		AvgCompletionSeconds: math.Round(avg),
+		Lists:                make([]core.ListProgress, 0, len(lists)),
+	}
+	for _, l := range lists {
+		if l.Total > 0 {
+			l.Progress = math.Round(float64(l.Done)*1000/float64(l.Total)) / 10
+		}
+		stats.Overdue += l.Overdue
+		stats.Lists = append(stats.Lists, l)
+	}
+	return stats, nil
+}
+
+// statsDays returns counts of every day of the range, days without todos have
+// zero counts
+func statsDays(start, until time.Time, counts []core.DayStats) []core.DayStats {
+	byDate := make(map[string]core.DayStats, len(counts))
+	for _, c := range counts {
+		byDate[c.Date] = c
+	}
+	var days []core.DayStats
+	for day := start; day.Before(until); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dateLayout)
+		days = append(days, core.DayStats{Date: date, Created: byDate[date].Created, Completed: byDate[date].Completed})
+	}
+	return days
+}
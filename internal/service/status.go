@@ -0,0 +1,183 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+const (
+	maxStatuses      = 20
+	maxStatusNameLen = 64
+)
+
+var (
+	ErrUnknownStatus     = errors.New("status doesn't belong to the list")
+	ErrInvalidBoard      = errors.New("board needs exactly one terminal status and at least one other status")
+	ErrTooManyStatuses   = errors.New("board can have up to 20 statuses")
+	ErrInvalidStatusName = errors.New("status names must be unique and up to 64 characters long")
+	ErrInvalidWIPLimit   = errors.New("WIP limit can't be negative")
+	ErrWIPLimit          = core.ErrWIPLimit
+)
+
+type StatusStorage interface {
+	GetStatuses(listID int) ([]core.ListStatus, error)
+	ReplaceStatuses(userID, listID int, statuses []core.ListStatus) ([]core.ListStatus, error)
+}
+
+// GetStatuses returns columns of the list's board, the list without them
+// isn't a board
+func (s *TodoItemService) GetStatuses(listID int) ([]core.ListStatus, error) {
+	return s.statuses.GetStatuses(listID)
+}
+
+// ReplaceStatuses saves columns of the list's board in the given order, empty
+// statuses turn the board back to the plain list. WIP limits aren't checked
+// here, todos of removed statuses may exceed them
+func (s *TodoItemService) ReplaceStatuses(userID, listID int, statuses []core.ListStatus) ([]core.ListStatus, error) {
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return nil, err
+	}
+	if len(statuses) > maxStatuses {
+		return nil, ErrTooManyStatuses
+	}
+	current, err := s.statuses.GetStatuses(listID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int]bool, len(current))
+	for _, st := range current {
+		known[st.ID] = true
+	}
+	var terminals int
+	names := make(map[string]bool, len(statuses))
+	for i := range statuses {
+		st := &statuses[i]
+		st.Name = strings.TrimSpace(st.Name)
+		name := strings.ToLower(st.Name)
+		if name == "" || utf8.RuneCountInString(name) > maxStatusNameLen || names[name] {
+			return nil, ErrInvalidStatusName
+		}
+		names[name] = true
+		if st.WIPLimit < 0 {
+			return nil, ErrInvalidWIPLimit
+		}
+		if st.ID != 0 {
+			if !known[st.ID] {
+				return nil, ErrUnknownStatus
+			}
+			// the same status can't be saved twice
+			delete(known, st.ID)
+		}
+		if st.Terminal {
+			terminals++
+		}
+	}
+	if len(statuses) != 0 && (terminals != 1 || len(statuses) < 2) {
+		return nil, ErrInvalidBoard
+	}
+	return s.statuses.ReplaceStatuses(userID, listID, statuses)
+}
+
+// MoveTodo moves the todo to the column of the list's board, the todo becomes
+// done in the terminal status only
+func (s *TodoItemService) MoveTodo(userID, listID, todoID, statusID int) (core.TodoItem, error) {
+	return s.UpdateTodo(userID, listID, todoID, core.UpdateItemData{StatusID: &statusID})
+}
+
+// placeNewTodo puts the new todo to its status on boards, the todo without
+// status goes to the terminal one when it's done and to the first one otherwise
+func (s *TodoItemService) placeNewTodo(listID int, todo *core.TodoItem) error {
+	statuses, err := s.statuses.GetStatuses(listID)
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		if todo.StatusID != 0 {
+			return ErrUnknownStatus
+		}
+		return nil
+	}
+	statusID := todo.StatusID
+	if statusID == 0 {
+		statusID = defaultStatus(statuses, todo.Done).ID
+	}
+	status, err := s.enterStatus(statuses, statusID)
+	if err != nil {
+		return err
+	}
+	todo.StatusID, todo.Done = status.ID, status.Terminal
+	return nil
+}
+
+// placeTodo keeps status and done of the updated todo in sync on boards, the
+// status takes precedence, done moves the todo to the terminal status or back
+// to the first one
+func (s *TodoItemService) placeTodo(listID, todoID int, data *core.UpdateItemData) error {
+	if data.StatusID == nil && data.Done == nil {
+		return nil
+	}
+	statuses, err := s.statuses.GetStatuses(listID)
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		if data.StatusID != nil && *data.StatusID != 0 {
+			return ErrUnknownStatus
+		}
+		data.StatusID = nil
+		return nil
+	}
+	todo, err := s.storage.GetTodoByID(listID, todoID)
+	if err != nil {
+		return err
+	}
+	current, placed := findStatus(statuses, todo.StatusID)
+	statusID := todo.StatusID
+	if data.StatusID != nil {
+		statusID = *data.StatusID
+	} else if !placed || current.Terminal != *data.Done {
+		statusID = defaultStatus(statuses, *data.Done).ID
+	}
+	status := current
+	if !placed || statusID != current.ID {
+		if status, err = s.enterStatus(statuses, statusID); err != nil {
+			return err
+		}
+	}
+	data.StatusID, data.Done = &status.ID, &status.Terminal
+	return nil
+}
+
+// enterStatus checks that the todo can be moved to the status of the board,
+// WIP limits are checked by the storage when the todo is saved, so concurrent
+// moves to the status can't exceed them
+func (s *TodoItemService) enterStatus(statuses []core.ListStatus, statusID int) (core.ListStatus, error) {
+	status, ok := findStatus(statuses, statusID)
+	if !ok {
+		return status, ErrUnknownStatus
+	}
+	return status, nil
+}
+
+func findStatus(statuses []core.ListStatus, statusID int) (core.ListStatus, bool) {
+	for _, st := range statuses {
+		if st.ID == statusID {
+			return st, true
+		}
+	}
+	return core.ListStatus{}, false
+}
+
+// defaultStatus returns the terminal status for done todos and the first other
+// status for the rest of them
+func defaultStatus(statuses []core.ListStatus, done bool) core.ListStatus {
+	for _, st := range statuses {
+		if st.Terminal == done {
+			return st
+		}
+	}
+	return statuses[0]
+}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+var (
+	errUnknownEntity     = errors.New("entity must be one of list or todo")
+	errUnknownOperation  = errors.New("op must be one of create, update or delete")
+	errUnknownStrategy   = errors.New("strategy must be one of lww or merge")
+	errMissingTitle      = errors.New("missing required title field")
+	errMissingEntityID   = errors.New("missing required entity_id field")
+	errUnknownListRef    = errors.New("list_ref doesn't refer to the list created within the batch")
+	errEntityNotFound    = errors.New("entity not found")
+	errEmptyModification = errors.New("data has no fields to change")
+)
+
+type SyncStorage interface {
+	GetChanges(userID int, since int64) (core.SyncChanges, error)
+}
+
+// SyncListService is the part of TodoListService used for applying mutations
+type SyncListService interface {
+	CreateList(userID int, list core.Todolist) (core.Todolist, error)
+	GetListByID(userID, listID int) (core.Todolist, error)
+	UpdateList(userID, listID int, data core.UpdateListData) (core.Todolist, error)
+	DeleteList(userID, listID int) error
+}
+
+// SyncTodoService is the part of TodoItemService used for applying mutations
+type SyncTodoService interface {
+	CreateTodo(userID, listID int, todo core.TodoItem) (core.TodoItem, error)
+	GetTodoByID(listID, todoID int) (core.TodoItem, error)
+	UpdateTodo(userID, listID, todoID int, data core.UpdateItemData) (core.TodoItem, error)
+	DeleteTodo(userID, listID, todoID int) error
+}
+
+// SyncService exchanges changes with clients which work offline
+type SyncService struct {
+	storage SyncStorage
+	lists   SyncListService
+	todos   SyncTodoService
+}
+
+func NewSyncService(storage SyncStorage, lists SyncListService, todos SyncTodoService) *SyncService {
+	return &SyncService{storage, lists, todos}
+}
+
+// GetChanges returns changes of user's data since the state identified by the
+// token, zero token returns the whole state
+func (s *SyncService) GetChanges(userID int, since int64) (core.SyncChanges, error) {
+	return s.storage.GetChanges(userID, since)
+}
+
+// ApplyMutations applies changes made by the client one by one. Failure of the
+// mutation doesn't stop the batch, it's reported in the corresponding result
+func (s *SyncService) ApplyMutations(userID int, mutations []core.SyncMutation) []core.SyncResult {
+	results := make([]core.SyncResult, 0, len(mutations))
+	// IDs of the lists created within the batch by IDs of their mutations
+	created := make(map[string]int)
+	for _, m := range mutations {
+		res, err := s.apply(userID, m, created)
+		if err != nil {
+			res = core.SyncResult{Status: core.SyncFailed, Error: err.Error()}
+		}
+		res.ID = m.ID
+		if m.Entity == core.EntityList && m.Op == core.SyncCreate && res.Status == core.SyncApplied {
+			created[m.ID] = res.EntityID
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func (s *SyncService) apply(userID int, m core.SyncMutation, created map[string]int) (core.SyncResult, error) {
+	if m.Strategy == "" {
+		m.Strategy = core.SyncLastWriteWins
+	}
+	if m.Strategy != core.SyncLastWriteWins && m.Strategy != core.SyncMerge {
+		return core.SyncResult{}, errUnknownStrategy
+	}
+	switch m.Entity {
+	case core.EntityList:
+		switch m.Op {
+		case core.SyncCreate:
+			return s.createList(userID, m)
+		case core.SyncUpdate:
+			return s.updateList(userID, m)
+		case core.SyncDelete:
+			return s.deleteList(userID, m)
+		}
+		return core.SyncResult{}, errUnknownOperation
+	case core.EntityTodo:
+		if m.ListRef != "" {
+			listID, ok := created[m.ListRef]
+			if !ok {
+				return core.SyncResult{}, errUnknownListRef
+			}
+			m.ListID = listID
+		}
+		if _, err := s.lists.GetListByID(userID, m.ListID); err != nil {
+			return core.SyncResult{}, errEntityNotFound
+		}
+		switch m.Op {
+		case core.SyncCreate:
+			return s.createTodo(userID, m)
+		case core.SyncUpdate:
+			return s.updateTodo(userID, m)
+		case core.SyncDelete:
+			return s.deleteTodo(userID, m)
+		}
+		return core.SyncResult{}, errUnknownOperation
+	}
+	return core.SyncResult{}, errUnknownEntity
+}
+
+func (s *SyncService) createList(userID int, m core.SyncMutation) (core.SyncResult, error) {
+	if m.Data.Title == nil || *m.Data.Title == "" {
+		return core.SyncResult{}, errMissingTitle
+	}
+	list := core.Todolist{Title: *m.Data.Title}
+	if m.Data.Description != nil {
+		list.Description = *m.Data.Description
+	}
+	list, err := s.lists.CreateList(userID, list)
+	if err != nil {
+		return core.SyncResult{}, err
+	}
+	return listResult(core.SyncApplied, list, nil), nil
+}
+
+func (s *SyncService) updateList(userID int, m core.SyncMutation) (core.SyncResult, error) {
+	if m.EntityID == 0 {
+		return core.SyncResult{}, errMissingEntityID
+	}
+	list, err := s.lists.GetListByID(userID, m.EntityID)
+	if err != nil {
+		return core.SyncResult{}, errEntityNotFound
+	}
+	if m.Data.Title == nil && m.Data.Description == nil {
+		return core.SyncResult{}, errEmptyModification
+	}
+	current := core.SyncFields{Title: &list.Title, Description: &list.Description}
+	fields, conflicts := resolve(m, current, list.UpdatedAt)
+	if fields.Title == nil && fields.Description == nil {
+		return listResult(core.SyncConflict, list, conflicts), nil
+	}
+	list, err = s.lists.UpdateList(userID, list.ID, core.UpdateListData{Title: fields.Title, Description: fields.Description})
+	if err != nil {
+		return core.SyncResult{}, err
+	}
+	return listResult(statusOf(conflicts), list, conflicts), nil
+}
+
+func (s *SyncService) deleteList(userID int, m core.SyncMutation) (core.SyncResult, error) {
+	if m.EntityID == 0 {
+		return core.SyncResult{}, errMissingEntityID
+	}
+	list, err := s.lists.GetListByID(userID, m.EntityID)
+	if err != nil {
+		// it's already deleted
+		return core.SyncResult{Status: core.SyncApplied, EntityID: m.EntityID}, nil
+	}
+	if changedAfter(m, list.UpdatedAt) {
+		return listResult(core.SyncConflict, list, nil), nil
+	}
+	if err := s.lists.DeleteList(userID, list.ID); err != nil {
+		return core.SyncResult{}, err
+	}
+	return core.SyncResult{Status: core.SyncApplied, EntityID: list.ID}, nil
+}
+
+func (s *SyncService) createTodo(userID int, m core.SyncMutation) (core.SyncResult, error) {
+	if m.Data.Title == nil || *m.Data.Title == "" {
+		return core.SyncResult{}, errMissingTitle
+	}
+	todo := core.TodoItem{Title: *m.Data.Title, Due: m.Data.Due}
+	if m.Data.Description != nil {
+		todo.Description = *m.Data.Description
+	}
+	if m.Data.Done != nil {
+		todo.Done = *m.Data.Done
+	}
+	todo, err := s.todos.CreateTodo(userID, m.ListID, todo)
+	if err != nil {
+		return core.SyncResult{}, err
+	}
+	return todoResult(core.SyncApplied, m.ListID, todo, nil), nil
+}
+
+func (s *SyncService) updateTodo(userID int, m core.SyncMutation) (core.SyncResult, error) {
+	if m.EntityID == 0 {
+		return core.SyncResult{}, errMissingEntityID
+	}
+	todo, err := s.todos.GetTodoByID(m.ListID, m.EntityID)
+	if err != nil {
+		return core.SyncResult{}, errEntityNotFound
+	}
+	if m.Data.Title == nil && m.Data.Description == nil && m.Data.Done == nil && m.Data.Due == nil {
+		return core.SyncResult{}, errEmptyModification
+	}
+	current := core.SyncFields{Title: &todo.Title, Description: &todo.Description, Done: &todo.Done, Due: todo.Due}
+	fields, conflicts := resolve(m, current, todo.UpdatedAt)
+	if fields.Title == nil && fields.Description == nil && fields.Done == nil && fields.Due == nil {
+		return todoResult(core.SyncConflict, m.ListID, todo, conflicts), nil
+	}
+	data := core.UpdateItemData{Title: fields.Title, Description: fields.Description, Done: fields.Done, Due: fields.Due}
+	todo, err = s.todos.UpdateTodo(userID, m.ListID, todo.ID, data)
+	if err != nil {
+		return core.SyncResult{}, err
+	}
+	return todoResult(statusOf(conflicts), m.ListID, todo, conflicts), nil
+}
+
+func (s *SyncService) deleteTodo(userID int, m core.SyncMutation) (core.SyncResult, error) {
+	if m.EntityID == 0 {
+		return core.SyncResult{}, errMissingEntityID
+	}
+	todo, err := s.todos.GetTodoByID(m.ListID, m.EntityID)
+	if err != nil {
+		// it's already deleted
+		return core.SyncResult{Status: core.SyncApplied, EntityID: m.EntityID}, nil
+	}
+	if changedAfter(m, todo.UpdatedAt) {
+		return todoResult(core.SyncConflict, m.ListID, todo, nil), nil
+	}
+	if err := s.todos.DeleteTodo(userID, m.ListID, todo.ID); err != nil {
+		return core.SyncResult{}, err
+	}
+	return core.SyncResult{Status: core.SyncApplied, EntityID: todo.ID}, nil
+}
+
+// resolve returns fields of the mutation which should be applied and names of
+// the fields which conflict with the server's changes
+func resolve(m core.SyncMutation, current core.SyncFields, updatedAt time.Time) (core.SyncFields, []string) {
+	if !changedAfter(m, updatedAt) {
+		return m.Data, nil
+	}
+	if m.Strategy == core.SyncLastWriteWins {
+		return core.SyncFields{}, changedFields(m.Data)
+	}
+	// the server's version was changed since the client's one, so only fields
+	// which still have base values are taken from the client
+	var (
+		fields    core.SyncFields
+		conflicts []string
+	)
+	if m.Data.Title != nil {
+		if mergeableString(m.Base.Title, current.Title, m.Data.Title) {
+			fields.Title = m.Data.Title
+		} else {
+			conflicts = append(conflicts, "title")
+		}
+	}
+	if m.Data.Description != nil {
+		if mergeableString(m.Base.Description, current.Description, m.Data.Description) {
+			fields.Description = m.Data.Description
+		} else {
+			conflicts = append(conflicts, "description")
+		}
+	}
+	if m.Data.Done != nil {
+		if mergeableBool(m.Base.Done, current.Done, m.Data.Done) {
+			fields.Done = m.Data.Done
+		} else {
+			conflicts = append(conflicts, "done")
+		}
+	}
+	if m.Data.Due != nil {
+		if mergeableTime(m.Base.Due, current.Due, m.Data.Due) {
+			fields.Due = m.Data.Due
+		} else {
+			conflicts = append(conflicts, "due")
+		}
+	}
+	return fields, conflicts
+}
+
+// changedAfter reports whether the server's version was changed after the one
+// which client has changed and the client's change isn't the latest one
+func changedAfter(m core.SyncMutation, updatedAt time.Time) bool {
+	if m.BaseUpdatedAt != nil && !updatedAt.After(*m.BaseUpdatedAt) {
+		return false
+	}
+	if m.Strategy == core.SyncLastWriteWins {
+		return !m.UpdatedAt.After(updatedAt)
+	}
+	return true
+}
+
+// mergeableString reports whether the client's value can replace the server's one,
+// it's true when the server hasn't changed the field or has the same value
+func mergeableString(base, current, value *string) bool {
+	return *current == *value || (base != nil && *base == *current)
+}
+
+func mergeableBool(base, current, value *bool) bool {
+	return *current == *value || (base != nil && *base == *current)
+}
+
+func mergeableTime(base, current, value *time.Time) bool {
+	if current == nil {
+		return base == nil
+	}
+	return current.Equal(*value) || (base != nil && base.Equal(*current))
+}
+
+func changedFields(f core.SyncFields) []string {
+	var fields []string
+	if f.Title != nil {
+		fields = append(fields, "title")
+	}
+	if f.Description != nil {
+		fields = append(fields, "description")
+	}
+	if f.Done != nil {
+		fields = append(fields, "done")
+	}
+	if f.Due != nil {
+		fields = append(fields, "due")
+	}
+	return fields
+}
+
+func statusOf(conflicts []string) string {
+	if len(conflicts) > 0 {
+		return core.SyncConflict
+	}
+	return core.SyncApplied
+}
+
+func listResult(status string, list core.Todolist, conflicts []string) core.SyncResult {
+	return core.SyncResult{Status: status, EntityID: list.ID, Conflicts: conflicts, List: &list}
+}
+
+func todoResult(status string, listID int, todo core.TodoItem, conflicts []string) core.SyncResult {
+	return core.SyncResult{
+		Status:    status,
+		EntityID:  todo.ID,
+		Conflicts: conflicts,
+		Todo:      &core.SyncTodo{ListID: listID, TodoItem: todo},
+	}
+}
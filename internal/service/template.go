@@ -0,0 +1,417 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+const (
+	maxTemplateTodos    = 500
+	maxTemplateFieldLen = 255
+	dateLayout          = "2006-01-02"
+	secondsPerDay       = 24 * 60 * 60
+)
+
+var (
+	ErrEmptyTemplateTitle            = errors.New("titles of the template and its todos can't be empty")
+	ErrTemplateFieldTooLong          = errors.New("titles and descriptions can't be longer than 255 characters")
+	ErrInvalidVisibility             = errors.New("visibility must be private or workspace")
+	ErrTooManyTemplateTodos          = errors.New("template can have up to 500 todos")
+	ErrInvalidDueOffset              = errors.New("due offset can't be negative")
+	ErrInvalidTemplateStart          = errors.New("start must be a date like 2006-01-02")
+	ErrMissingTemplateVariable       = errors.New("missing value of the template variable")
+	ErrTemplateNotFound              = errors.New("template not found")
+	ErrTemplateAccess          error = AccessError("only the author or workspace admins can change the template")
+)
+
+// templateVariablePattern matches variables like {{name}} in titles and descriptions
+var templateVariablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// builtinTemplateVariables are filled on instantiation unless they're given
+var builtinTemplateVariables = map[string]bool{"date": true}
+
+type TemplateStorage interface {
+	GetTemplates(userID int) ([]core.ListTemplate, error)
+	GetTemplate(templateID int) (core.ListTemplate, error)
+	GetTemplateByShareHash(hash string) (core.ListTemplate, error)
+	CreateTemplate(t core.ListTemplate) (core.ListTemplate, error)
+	UpdateTemplate(t core.ListTemplate) (core.ListTemplate, error)
+	DeleteTemplate(templateID int) error
+	ShareTemplate(templateID int, hash string) error
+	InstantiateTemplate(userID int, l core.ListWithTodos) (core.ListWithTodos, error)
+}
+
+// TemplateLists provides lists which are saved as templates
+type TemplateLists interface {
+	GetListByID(userID, listID int) (core.Todolist, error)
+}
+
+// TemplateTodos provides todos of lists which are saved as templates
+type TemplateTodos interface {
+	GetAllTodos(listID int) ([]core.TodoItem, error)
+}
+
+// TemplateSettings provides time zones of the users, due dates are relative
+// to days in them
+type TemplateSettings interface {
+	Location(userID int) *time.Location
+}
+
+// TemplateService manages reusable checklists. Templates belong to workspaces
+// and are visible to their authors or to all members of the workspace, anyone
+// with the token of the public link can use the shared template
+type TemplateService struct {
+	storage  TemplateStorage
+	lists    TemplateLists
+	todos    TemplateTodos
+	access   WorkspaceAccess
+	settings TemplateSettings
+}
+
+func NewTemplateService(storage TemplateStorage, lists TemplateLists, todos TemplateTodos, access WorkspaceAccess, settings TemplateSettings) *TemplateService {
+	return &TemplateService{storage, lists, todos, access, settings}
+}
+
+// GetTemplates returns templates available to the user
+func (s *TemplateService) GetTemplates(userID int) ([]core.ListTemplate, error) {
+	templates, err := s.storage.GetTemplates(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		templates[i].Variables = templateVariables(templates[i])
+	}
+	return templates, nil
+}
+
+// GetTemplate returns the template if it's visible to the user
+func (s *TemplateService) GetTemplate(userID, templateID int) (core.ListTemplate, error) {
+	t, err := s.storage.GetTemplate(templateID)
+	if err != nil {
+		return core.ListTemplate{}, err
+	}
+	role, err := s.access.GetMemberRole(t.WorkspaceID, userID)
+	if err != nil || role == "" || (t.AuthorID != userID && t.Visibility != core.TemplateVisibilityWorkspace) {
+		return core.ListTemplate{}, ErrTemplateNotFound
+	}
+	t.Variables = templateVariables(t)
+	return t, nil
+}
+
+// GetSharedTemplate returns the template by the token of its public link
+func (s *TemplateService) GetSharedTemplate(token string) (core.ListTemplate, error) {
+	t, err := s.storage.GetTemplateByShareHash(hashSecret(token))
+	if err != nil {
+		return core.ListTemplate{}, ErrTemplateNotFound
+	}
+	t.Variables = templateVariables(t)
+	return t, nil
+}
+
+// CreateTemplate saves the list with its todos as the template of the list's
+// workspace. Title of the list is used unless the template has its own, due
+// dates become offsets from the start of today in the user's time zone
+func (s *TemplateService) CreateTemplate(userID, listID int, t core.ListTemplate) (core.ListTemplate, error) {
+	role, err := s.access.GetListRole(listID, userID)
+	if err != nil || role == "" {
+		return core.ListTemplate{}, ErrWorkspaceForbidden
+	}
+	if t.Visibility == "" {
+		t.Visibility = core.TemplateVisibilityPrivate
+	}
+	if t.Visibility == core.TemplateVisibilityWorkspace && !core.CanWrite(role) {
+		return core.ListTemplate{}, ErrWorkspaceForbidden
+	}
+	list, err := s.lists.GetListByID(userID, listID)
+	if err != nil {
+		return core.ListTemplate{}, err
+	}
+	todos, err := s.todos.GetAllTodos(listID)
+	if err != nil {
+		return core.ListTemplate{}, err
+	}
+	sort.Slice(todos, func(i, j int) bool { return todos[i].ID < todos[j].ID })
+	start := startOfDay(time.Now().In(s.settings.Location(userID)))
+	t.WorkspaceID = list.WorkspaceID
+	t.AuthorID = userID
+	if strings.TrimSpace(t.Title) == "" {
+		t.Title = list.Title
+	}
+	if t.Description == "" {
+		t.Description = list.Description
+	}
+	t.Todos = make([]core.TemplateTodo, 0, len(todos))
+	for _, todo := range todos {
+		tt := core.TemplateTodo{
+			Title:       todo.Title,
+			Description: todo.Description,
+			Priority:    todo.Priority,
+			Labels:      todo.Labels,
+			Recurrence:  todo.Recurrence,
+		}
+		if todo.Due != nil {
+			offset := dueOffset(start, *todo.Due)
+			tt.DueOffset = &offset
+		}
+		t.Todos = append(t.Todos, tt)
+	}
+	if err := validateTemplate(&t); err != nil {
+		return core.ListTemplate{}, err
+	}
+	t, err = s.storage.CreateTemplate(t)
+	t.Variables = templateVariables(t)
+	return t, err
+}
+
+// UpdateTemplate changes the template, given todos replace all its todos
+func (s *TemplateService) UpdateTemplate(userID int, t core.ListTemplate, data core.UpdateTemplateData) (core.ListTemplate, error) {
+	role, err := s.authorize(userID, t)
+	if err != nil {
+		return core.ListTemplate{}, err
+	}
+	if data.Title != nil {
+		t.Title = *data.Title
+	}
+	if data.Description != nil {
+		t.Description = *data.Description
+	}
+	if data.Visibility != nil {
+		if *data.Visibility == core.TemplateVisibilityWorkspace && !core.CanWrite(role) {
+			return core.ListTemplate{}, ErrWorkspaceForbidden
+		}
+		t.Visibility = *data.Visibility
+	}
+	if data.Todos != nil {
+		t.Todos = *data.Todos
+	}
+	if err := validateTemplate(&t); err != nil {
+		return core.ListTemplate{}, err
+	}
+	t, err = s.storage.UpdateTemplate(t)
+	t.Variables = templateVariables(t)
+	return t, err
+}
+
+func (s *TemplateService) DeleteTemplate(userID int, t core.ListTemplate) error {
+	if _, err := s.authorize(userID, t); err != nil {
+		return err
+	}
+	return s.storage.DeleteTemplate(t.ID)
+}
+
+// ShareTemplate creates the public link of the template, the previous link
+// stops working. The token is returned only once
+func (s *TemplateService) ShareTemplate(userID int, t core.ListTemplate) (core.ListTemplate, error) {
+	if _, err := s.authorize(userID, t); err != nil {
+		return core.ListTemplate{}, err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return core.ListTemplate{}, err
+	}
+	if err := s.storage.ShareTemplate(t.ID, hashSecret(token)); err != nil {
+		return core.ListTemplate{}, err
+	}
+	t.Shared = true
+	t.ShareToken = token
+	return t, nil
+}
+
+// UnshareTemplate revokes the public link of the template
+func (s *TemplateService) UnshareTemplate(userID int, t core.ListTemplate) error {
+	if _, err := s.authorize(userID, t); err != nil {
+		return err
+	}
+	return s.storage.ShareTemplate(t.ID, "")
+}
+
+// Instantiate creates the list from the template in the given workspace.
+// Variables are substituted in titles and descriptions, {{date}} is the start
+// day by default. Due dates are counted from the start of the day in the
+// user's time zone
+func (s *TemplateService) Instantiate(userID int, t core.ListTemplate, in core.TemplateInstance) (core.ListWithTodos, error) {
+	if in.WorkspaceID != 0 {
+		role, err := s.access.GetMemberRole(in.WorkspaceID, userID)
+		if err != nil || !core.CanWrite(role) {
+			return core.ListWithTodos{}, ErrWorkspaceForbidden
+		}
+	}
+	loc := s.settings.Location(userID)
+	start := startOfDay(time.Now().In(loc))
+	if in.Start != "" {
+		day, err := time.ParseInLocation(dateLayout, in.Start, loc)
+		if err != nil {
+			return core.ListWithTodos{}, ErrInvalidTemplateStart
+		}
+		start = day
+	}
+	vars := map[string]string{"date": start.Format(dateLayout)}
+	for name, value := range in.Variables {
+		vars[name] = value
+	}
+	var missing []string
+	for _, name := range templateVariables(t) {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return core.ListWithTodos{}, fmt.Errorf("%w: %s", ErrMissingTemplateVariable, strings.Join(missing, ", "))
+	}
+	list := core.ListWithTodos{
+		Todolist: core.Todolist{
+			WorkspaceID: in.WorkspaceID,
+			Title:       expandTemplate(t.Title, vars),
+			Description: expandTemplate(t.Description, vars),
+		},
+		Todos: make([]core.TodoItem, 0, len(t.Todos)),
+	}
+	if err := templateField(list.Title, true); err != nil {
+		return core.ListWithTodos{}, err
+	}
+	if err := templateField(list.Description, false); err != nil {
+		return core.ListWithTodos{}, err
+	}
+	for _, tt := range t.Todos {
+		todo := core.TodoItem{
+			Title:       expandTemplate(tt.Title, vars),
+			Description: expandTemplate(tt.Description, vars),
+			Priority:    tt.Priority,
+			Labels:      tt.Labels,
+			Recurrence:  tt.Recurrence,
+		}
+		if err := templateField(todo.Title, true); err != nil {
+			return core.ListWithTodos{}, err
+		}
+		if err := templateField(todo.Description, false); err != nil {
+			return core.ListWithTodos{}, err
+		}
+		if tt.DueOffset != nil {
+			due := dueFromOffset(start, *tt.DueOffset)
+			todo.Due = &due
+		}
+		list.Todos = append(list.Todos, todo)
+	}
+	return s.storage.InstantiateTemplate(userID, list)
+}
+
+// authorize checks the user can change the template, authors can change their
+// templates and workspace admins can change templates visible to the workspace.
+// It returns role of the user in the template's workspace
+func (s *TemplateService) authorize(userID int, t core.ListTemplate) (string, error) {
+	role, err := s.access.GetMemberRole(t.WorkspaceID, userID)
+	if err != nil || role == "" {
+		return "", ErrWorkspaceForbidden
+	}
+	if t.AuthorID != userID && !(t.Visibility == core.TemplateVisibilityWorkspace && core.CanManage(role)) {
+		return "", ErrTemplateAccess
+	}
+	return role, nil
+}
+
+// validateTemplate checks attributes of the template and normalizes labels of
+// its todos
+func validateTemplate(t *core.ListTemplate) error {
+	t.Title = strings.TrimSpace(t.Title)
+	if err := templateField(t.Title, true); err != nil {
+		return err
+	}
+	if err := templateField(t.Description, false); err != nil {
+		return err
+	}
+	if t.Visibility != core.TemplateVisibilityPrivate && t.Visibility != core.TemplateVisibilityWorkspace {
+		return ErrInvalidVisibility
+	}
+	if len(t.Todos) > maxTemplateTodos {
+		return ErrTooManyTemplateTodos
+	}
+	for i := range t.Todos {
+		todo := &t.Todos[i]
+		todo.Title = strings.TrimSpace(todo.Title)
+		if err := templateField(todo.Title, true); err != nil {
+			return err
+		}
+		if err := templateField(todo.Description, false); err != nil {
+			return err
+		}
+		if err := validateDetails(todo.Priority, todo.Recurrence); err != nil {
+			return err
+		}
+		labels, err := normalizeLabels(todo.Labels)
+		if err != nil {
+			return err
+		}
+		todo.Labels = labels
+		if todo.DueOffset != nil && *todo.DueOffset < 0 {
+			return ErrInvalidDueOffset
+		}
+	}
+	return nil
+}
+
+func templateField(value string, required bool) error {
+	if required && value == "" {
+		return ErrEmptyTemplateTitle
+	}
+	if utf8.RuneCountInString(value) > maxTemplateFieldLen {
+		return ErrTemplateFieldTooLong
+	}
+	return nil
+}
+
+// templateVariables returns unique names of the variables used by the
+// template, built-in variables are omitted
+func templateVariables(t core.ListTemplate) []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	texts := []string{t.Title, t.Description}
+	for _, todo := range t.Todos {
+		texts = append(texts, todo.Title, todo.Description)
+	}
+	for _, text := range texts {
+		for _, m := range templateVariablePattern.FindAllStringSubmatch(text, -1) {
+			if !seen[m[1]] && !builtinTemplateVariables[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// expandTemplate substitutes variables of the text
+func expandTemplate(text string, vars map[string]string) string {
+	return templateVariablePattern.ReplaceAllStringFunc(text, func(m string) string {
+		return vars[templateVariablePattern.FindStringSubmatch(m)[1]]
+	})
+}
+
+// dueOffset returns seconds between the start of the day and the due time,
+// days are counted by the calendar, so offsets keep the time of the day across
+// DST changes. Overdue todos are due at the start of the day
+func dueOffset(start, due time.Time) int64 {
+	due = due.In(start.Location())
+	if due.Before(start) {
+		return 0
+	}
+	day := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+	days := int64(day.Sub(time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	return days*secondsPerDay + int64(due.Hour()*3600+due.Minute()*60+due.Second())
+}
+
+// dueFromOffset returns the due time which is the offset after the start of the day
+func dueFromOffset(start time.Time, offset int64) time.Time {
+	day := start.AddDate(0, 0, int(offset/secondsPerDay))
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, int(offset%secondsPerDay), 0, start.Location())
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
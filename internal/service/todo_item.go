@@ -1,41 +1,168 @@
 package service
 
 import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/vbetsun/todo-app/internal/core"
 )
 
+const (
+	maxLabels   = 20
+	maxLabelLen = 50
+)
+
+var (
+	ErrAssigneeNotMember = errors.New("todo can be assigned only to members of its list")
+	ErrInvalidPriority   = errors.New("priority must be between 0 and 3")
+	ErrInvalidLabels     = errors.New("todo can have up to 20 labels of up to 50 characters")
+	ErrInvalidRecurrence = errors.New("recurrence must be RRULE with FREQ, INTERVAL and BYDAY parts only")
+)
+
 type TodoItemStorage interface {
-	CreateTodo(listID int, todo core.TodoItem) (core.TodoItem, error)
+	CreateTodo(userID, listID int, todo core.TodoItem) (core.TodoItem, error)
 	GetAllTodos(listID int) ([]core.TodoItem, error)
+	GetTodosByListIDs(listIDs []int) (map[int][]core.TodoItem, error)
+	GetAssignedTodos(userID int, done *bool) ([]core.AssignedTodo, error)
 	GetTodoByID(listID, todoID int) (core.TodoItem, error)
-	UpdateTodo(todoID int, data core.UpdateItemData) (core.TodoItem, error)
-	DeleteTodo(todoID int) error
+	GetTodoByUID(listID int, uid string) (core.TodoItem, error)
+	UpdateTodo(userID, listID, todoID int, data core.UpdateItemData) (core.TodoItem, error)
+	DeleteTodo(userID, listID, todoID int) error
 }
 
 type TodoItemService struct {
-	storage TodoItemStorage
+	storage  TodoItemStorage
+	access   WorkspaceAccess
+	statuses StatusStorage
 }
 
-func NewTodoItemService(storage TodoItemStorage) *TodoItemService {
-	return &TodoItemService{storage}
+func NewTodoItemService(storage TodoItemStorage, access WorkspaceAccess, statuses StatusStorage) *TodoItemService {
+	return &TodoItemService{storage, access, statuses}
 }
 
-func (s *TodoItemService) CreateTodo(listID int, todo core.TodoItem) (core.TodoItem, error) {
-	return s.storage.CreateTodo(listID, todo)
+func (s *TodoItemService) CreateTodo(userID, listID int, todo core.TodoItem) (core.TodoItem, error) {
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return core.TodoItem{}, err
+	}
+	if err := s.validateAssignee(listID, todo.AssigneeID); err != nil {
+		return core.TodoItem{}, err
+	}
+	if err := validateDetails(todo.Priority, todo.Recurrence); err != nil {
+		return core.TodoItem{}, err
+	}
+	labels, err := normalizeLabels(todo.Labels)
+	if err != nil {
+		return core.TodoItem{}, err
+	}
+	todo.Labels = labels
+	if err := s.placeNewTodo(listID, &todo); err != nil {
+		return core.TodoItem{}, err
+	}
+	return s.storage.CreateTodo(userID, listID, todo)
 }
 
 func (s *TodoItemService) GetAllTodos(listID int) ([]core.TodoItem, error) {
 	return s.storage.GetAllTodos(listID)
 }
 
+func (s *TodoItemService) GetTodosByListIDs(listIDs []int) (map[int][]core.TodoItem, error) {
+	return s.storage.GetTodosByListIDs(listIDs)
+}
+
+// GetAssignedTodos returns todos assigned to the user across all the user's
+// lists, done filters them by completion when it's set
+func (s *TodoItemService) GetAssignedTodos(userID int, done *bool) ([]core.AssignedTodo, error) {
+	return s.storage.GetAssignedTodos(userID, done)
+}
+
 func (s *TodoItemService) GetTodoByID(listID, todoID int) (core.TodoItem, error) {
 	return s.storage.GetTodoByID(listID, todoID)
 }
 
-func (s *TodoItemService) UpdateTodo(todoID int, data core.UpdateItemData) (core.TodoItem, error) {
-	return s.storage.UpdateTodo(todoID, data)
+func (s *TodoItemService) GetTodoByUID(listID int, uid string) (core.TodoItem, error) {
+	return s.storage.GetTodoByUID(listID, uid)
+}
+
+func (s *TodoItemService) UpdateTodo(userID, listID, todoID int, data core.UpdateItemData) (core.TodoItem, error) {
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return core.TodoItem{}, err
+	}
+	if data.AssigneeID != nil {
+		if err := s.validateAssignee(listID, *data.AssigneeID); err != nil {
+			return core.TodoItem{}, err
+		}
+	}
+	var priority int
+	if data.Priority != nil {
+		priority = *data.Priority
+	}
+	var recurrence string
+	if data.Recurrence != nil {
+		recurrence = *data.Recurrence
+	}
+	if err := validateDetails(priority, recurrence); err != nil {
+		return core.TodoItem{}, err
+	}
+	if data.Labels != nil {
+		labels, err := normalizeLabels(*data.Labels)
+		if err != nil {
+			return core.TodoItem{}, err
+		}
+		data.Labels = &labels
+	}
+	if err := s.placeTodo(listID, todoID, &data); err != nil {
+		return core.TodoItem{}, err
+	}
+	return s.storage.UpdateTodo(userID, listID, todoID, data)
+}
+
+func (s *TodoItemService) DeleteTodo(userID, listID, todoID int) error {
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return err
+	}
+	return s.storage.DeleteTodo(userID, listID, todoID)
+}
+
+// validateAssignee checks that the assignee is member of the list's workspace,
+// zero assignee means the todo isn't assigned
+func (s *TodoItemService) validateAssignee(listID, assigneeID int) error {
+	if assigneeID == 0 {
+		return nil
+	}
+	if _, err := s.access.GetListRole(listID, assigneeID); err != nil {
+		return ErrAssigneeNotMember
+	}
+	return nil
+}
+
+// validateDetails checks priority and recurrence of the todo
+func validateDetails(priority int, recurrence string) error {
+	if priority < core.PriorityNone || priority > core.PriorityHigh {
+		return ErrInvalidPriority
+	}
+	if !core.ValidRecurrence(recurrence) {
+		return ErrInvalidRecurrence
+	}
+	return nil
 }
 
-func (s *TodoItemService) DeleteTodo(todoID int) error {
-	return s.storage.DeleteTodo(todoID)
+// normalizeLabels returns labels lowercased and without duplicates
+func normalizeLabels(labels []string) ([]string, error) {
+	if len(labels) > maxLabels {
+		return nil, ErrInvalidLabels
+	}
+	normalized := make([]string, 0, len(labels))
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || utf8.RuneCountInString(label) > maxLabelLen {
+			return nil, ErrInvalidLabels
+		}
+		if !seen[label] {
+			seen[label] = true
+			normalized = append(normalized, label)
+		}
+	}
+	return normalized, nil
 }
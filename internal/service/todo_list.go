@@ -8,33 +8,59 @@ type TodoListStorage interface {
 	CreateList(userID int, list core.Todolist) (core.Todolist, error)
 	GetAllLists(userID int) ([]core.Todolist, error)
 	GetListByID(userID, listID int) (core.Todolist, error)
-	UpdateList(listID int, data core.UpdateListData) (core.Todolist, error)
-	DeleteList(listID int) error
+	UpdateList(userID, listID int, data core.UpdateListData) (core.Todolist, error)
+	DeleteList(userID, listID int) error
+	GetWorkspaceLists(workspaceID int) ([]core.Todolist, error)
 }
+
 type TodoListService struct {
 	storage TodoListStorage
+	access  WorkspaceAccess
 }
 
-func NewTodoListService(storage TodoListStorage) *TodoListService {
-	return &TodoListService{storage}
+func NewTodoListService(storage TodoListStorage, access WorkspaceAccess) *TodoListService {
+	return &TodoListService{storage, access}
 }
 
+// CreateList creates the list in its workspace, the list without workspace
+// goes to the user's personal workspace
 func (s *TodoListService) CreateList(userID int, list core.Todolist) (core.Todolist, error) {
+	if list.WorkspaceID != 0 {
+		role, err := s.access.GetMemberRole(list.WorkspaceID, userID)
+		if err != nil || !core.CanWrite(role) {
+			return core.Todolist{}, ErrWorkspaceForbidden
+		}
+	}
 	return s.storage.CreateList(userID, list)
 }
 
+// GetAllLists returns lists of all workspaces the user is member of
 func (s *TodoListService) GetAllLists(userID int) ([]core.Todolist, error) {
 	return s.storage.GetAllLists(userID)
 }
 
+// GetWorkspaceLists returns lists of the workspace, the user has to be its member
+func (s *TodoListService) GetWorkspaceLists(userID, workspaceID int) ([]core.Todolist, error) {
+	if _, err := s.access.GetMemberRole(workspaceID, userID); err != nil {
+		return nil, ErrWorkspaceForbidden
+	}
+	return s.storage.GetWorkspaceLists(workspaceID)
+}
+
 func (s *TodoListService) GetListByID(userID, listID int) (core.Todolist, error) {
 	return s.storage.GetListByID(userID, listID)
 }
 
-func (s *TodoListService) UpdateList(listID int, data core.UpdateListData) (core.Todolist, error) {
-	return s.storage.UpdateList(listID, data)
+func (s *TodoListService) UpdateList(userID, listID int, data core.UpdateListData) (core.Todolist, error) {
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return core.Todolist{}, err
+	}
+	return s.storage.UpdateList(userID, listID, data)
 }
 
-func (s *TodoListService) DeleteList(listID int) error {
-	return s.storage.DeleteList(listID)
+func (s *TodoListService) DeleteList(userID, listID int) error {
+	if err := authorizeList(s.access, userID, listID, core.CanWrite); err != nil {
+		return err
+	}
+	return s.storage.DeleteList(userID, listID)
 }
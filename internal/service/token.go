@@ -0,0 +1,116 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+const (
+	// AccessTokenPrefix distinguishes personal access tokens from JWT
+	AccessTokenPrefix = "tdp_"
+	accessTokenSize   = 32
+	// accessTokenShownSize is a length of the token's beginning which is shown
+	// to the user for recognizing the token
+	accessTokenShownSize = len(AccessTokenPrefix) + 8
+)
+
+var (
+	ErrMissingTokenName   = errors.New("missing required name field")
+	ErrMissingScopes      = errors.New("token must have at least one scope")
+	ErrUnknownScope       = errors.New("unknown scope")
+	ErrTokenExpiresInPast = errors.New("expires_at must be in the future")
+	ErrInvalidAccessToken = errors.New("invalid or expired access token")
+)
+
+type AccessTokenStorage interface {
+	CreateAccessToken(t core.AccessToken) (core.AccessToken, error)
+	GetAccessTokens(userID int) ([]core.AccessToken, error)
+	DeleteAccessToken(userID, tokenID int) error
+	UseAccessToken(hash string) (core.AccessToken, error)
+}
+
+// AccessTokenService manages personal access tokens used by scripts instead of
+// user's credentials
+type AccessTokenService struct {
+	storage AccessTokenStorage
+}
+
+func NewAccessTokenService(storage AccessTokenStorage) *AccessTokenService {
+	return &AccessTokenService{storage}
+}
+
+// CreateAccessToken generates new token of the User, the returned token is the
+// only place where its secret is available
+func (s *AccessTokenService) CreateAccessToken(userID int, t core.AccessToken) (core.AccessToken, error) {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return t, ErrMissingTokenName
+	}
+	scopes, err := normalizeScopes(t.Scopes)
+	if err != nil {
+		return t, err
+	}
+	if t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now()) {
+		return t, ErrTokenExpiresInPast
+	}
+	b := make([]byte, accessTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return t, err
+	}
+	secret := AccessTokenPrefix + hex.EncodeToString(b)
+	t.UserID = userID
+	t.Scopes = scopes
+	t.Prefix = secret[:accessTokenShownSize]
+	t.Hash = hashSecret(secret)
+	t, err = s.storage.CreateAccessToken(t)
+	if err != nil {
+		return t, err
+	}
+	t.Token = secret
+	return t, nil
+}
+
+func (s *AccessTokenService) GetAccessTokens(userID int) ([]core.AccessToken, error) {
+	return s.storage.GetAccessTokens(userID)
+}
+
+func (s *AccessTokenService) DeleteAccessToken(userID, tokenID int) error {
+	return s.storage.DeleteAccessToken(userID, tokenID)
+}
+
+// IsAccessToken reports whether the bearer token is a personal access token
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// hashSecret returns hash of the random secret, such secrets don't need salt
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes validates scopes and removes duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrMissingScopes
+	}
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !containsString(core.Scopes, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
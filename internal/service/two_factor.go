@@ -0,0 +1,229 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/totp"
+)
+
+const (
+	totpIssuer   = "TodoApp"
+	challengeKey = "123challenge123"
+	challengeTTL = 5 * time.Minute
+	// maxChallengeAttempts limits codes which can be tried with the challenge
+	maxChallengeAttempts = 5
+	recoveryCodesCount   = 10
+	recoveryCodeSize     = 10
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorDisabled    = errors.New("two-factor authentication isn't enabled")
+	ErrTOTPNotEnrolled      = errors.New("two-factor authentication isn't enrolled")
+	ErrInvalidCode          = errors.New("invalid one-time password or recovery code")
+	ErrInvalidChallenge     = errors.New("challenge token is invalid or expired")
+	recoveryCodeEncoding    = base32.StdEncoding.WithPadding(base32.NoPadding)
+	recoveryCodeReplacement = strings.NewReplacer("-", "", " ", "")
+)
+
+// challengeClaims identifies the user who passed the password check
+type challengeClaims struct {
+	jwt.RegisteredClaims
+	UserID  int `json:"user_id"`
+	Version int `json:"ver,omitempty"`
+}
+
+// VerifyChallenge exchanges the challenge token and the second factor for access
+// token. Every challenge can be used once and allows a few attempts, failed
+// attempts count towards the lockout of the account as failed passwords do
+func (s *AuthService) VerifyChallenge(challenge, code string) (string, error) {
+	var claims challengeClaims
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	_, err := parser.ParseWithClaims(challenge, &claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(challengeKey), nil
+	})
+	if err != nil || claims.UserID == 0 || claims.ID == "" {
+		return "", ErrInvalidChallenge
+	}
+	// the password could be changed since the challenge was issued
+	user, err := s.storage.GetUserByID(claims.UserID)
+	if err != nil || user.TokenVersion != claims.Version {
+		return "", ErrInvalidChallenge
+	}
+	if err := s.checkLockout(user.Username); err != nil {
+		return "", err
+	}
+	ok, err := s.storage.AttemptChallenge(claims.ID, maxChallengeAttempts)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrInvalidChallenge
+	}
+	if err := s.checkSecondFactor(claims.UserID, code); err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			s.loginFailed(user.Username)
+		}
+		return "", err
+	}
+	used, err := s.storage.UseChallenge(claims.ID)
+	if err != nil {
+		return "", err
+	}
+	if !used {
+		return "", ErrInvalidChallenge
+	}
+	if s.lockout.Enabled() {
+		if err := s.storage.ResetLoginFailures(claims.UserID); err != nil {
+			return "", err
+		}
+	}
+	return s.newToken(claims.UserID)
+}
+
+// newChallenge returns the challenge token which identifies the user who passed
+// the password check, the token is recorded so it can be used only once
+func (s *AuthService) newChallenge(user core.User) (string, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().Add(challengeTTL)
+	if err := s.storage.CreateChallenge(id, user.ID, expiresAt); err != nil {
+		return "", err
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, challengeClaims{
+		jwt.RegisteredClaims{ID: id, ExpiresAt: jwt.NewNumericDate(expiresAt)},
+		user.ID,
+		user.TokenVersion,
+	}).SignedString([]byte(challengeKey))
+}
+
+// EnrollTOTP generates the new secret, it's enabled only after the user confirms
+// it with the code from the authenticator app
+func (s *AuthService) EnrollTOTP(userID int) (core.TOTPEnrollment, error) {
+	t, err := s.storage.GetTOTP(userID)
+	if err != nil {
+		return core.TOTPEnrollment{}, err
+	}
+	if t.Enabled {
+		return core.TOTPEnrollment{}, ErrTwoFactorEnabled
+	}
+	user, err := s.storage.GetUserByID(userID)
+	if err != nil {
+		return core.TOTPEnrollment{}, err
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return core.TOTPEnrollment{}, err
+	}
+	if err := s.storage.SaveTOTPSecret(userID, secret); err != nil {
+		return core.TOTPEnrollment{}, err
+	}
+	return core.TOTPEnrollment{Secret: secret, URI: totp.URI(totpIssuer, user.Username, secret)}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication and returns recovery codes,
+// they're shown to the user only once
+func (s *AuthService) ConfirmTOTP(userID int, code string) ([]string, error) {
+	t, err := s.storage.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if t.Secret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+	step, ok := totp.Validate(t.Secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	return codes, s.storage.EnableTOTP(userID, step, hashes)
+}
+
+// DisableTOTP turns off two-factor authentication, it requires the second factor
+func (s *AuthService) DisableTOTP(userID int, code string) error {
+	if err := s.checkSecondFactor(userID, code); err != nil {
+		return err
+	}
+	return s.storage.DisableTOTP(userID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, it requires the second factor
+func (s *AuthService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	if err := s.checkSecondFactor(userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	return codes, s.storage.ReplaceRecoveryCodes(userID, hashes)
+}
+
+// checkSecondFactor accepts one-time password or unused recovery code. Every
+// code can be used only once
+func (s *AuthService) checkSecondFactor(userID int, code string) error {
+	t, err := s.storage.GetTOTP(userID)
+	if err != nil {
+		return err
+	}
+	if !t.Enabled {
+		return ErrTwoFactorDisabled
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(t.Secret, code, time.Now())
+		if !ok || step <= t.LastUsedStep {
+			return ErrInvalidCode
+		}
+		used, err := s.storage.UseTOTPStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidCode
+		}
+		return nil
+	}
+	used, err := s.storage.UseRecoveryCode(userID, hashSecret(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// generateRecoveryCodes returns codes formatted for the user and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	b := make([]byte, recoveryCodeSize*5/8)
+	for i := 0; i < recoveryCodesCount; i++ {
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes = append(codes, raw[:recoveryCodeSize/2]+"-"+raw[recoveryCodeSize/2:])
+		hashes = append(hashes, hashSecret(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(recoveryCodeReplacement.Replace(code))
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/totp"
+)
+
+// challenge represents the recorded challenge of the sign in
+type challenge struct {
+	attempts  int
+	used      bool
+	expiresAt time.Time
+}
+
+// twoFactorStorage keeps the only user with enabled TOTP in memory
+type twoFactorStorage struct {
+	AuthStorage
+	user          core.User
+	totp          core.TOTP
+	recoveryCodes map[string]bool
+	challenges    map[string]*challenge
+	failures      int
+	lockedUntil   time.Time
+}
+
+func newTwoFactorStorage(t *testing.T) *twoFactorStorage {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &twoFactorStorage{
+		user:          core.User{ID: 1, Username: "alice"},
+		totp:          core.TOTP{Secret: secret, Enabled: true},
+		recoveryCodes: map[string]bool{hashSecret("abcdefghij"): true},
+		challenges:    make(map[string]*challenge),
+	}
+}
+
+func (m *twoFactorStorage) GetUser(username, password string) (core.User, error) {
+	if username != m.user.Username || password != generateHash("password") {
+		return core.User{}, errors.New("invalid credentials")
+	}
+	return m.user, nil
+}
+
+func (m *twoFactorStorage) GetUserByID(userID int) (core.User, error) {
+	if userID != m.user.ID {
+		return core.User{}, errors.New("user not found")
+	}
+	return m.user, nil
+}
+
+func (m *twoFactorStorage) GetTOTP(userID int) (core.TOTP, error) {
+	return m.totp, nil
+}
+
+func (m *twoFactorStorage) UseTOTPStep(userID int, step int64) (bool, error) {
+	if step <= m.totp.LastUsedStep {
+		return false, nil
+	}
+	m.totp.LastUsedStep = step
+	return true, nil
+}
+
+func (m *twoFactorStorage) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	if !m.recoveryCodes[codeHash] {
+		return false, nil
+	}
+	m.recoveryCodes[codeHash] = false
+	return true, nil
+}
+
+func (m *twoFactorStorage) CreateChallenge(id string, userID int, expiresAt time.Time) error {
+	m.challenges[id] = &challenge{expiresAt: expiresAt}
+	return nil
+}
+
+func (m *twoFactorStorage) AttemptChallenge(id string, maxAttempts int) (bool, error) {
+	c, ok := m.challenges[id]
+	if !ok || c.used || c.attempts >= maxAttempts || time.Now().After(c.expiresAt) {
+		return false, nil
+	}
+	c.attempts++
+	return true, nil
+}
+
+func (m *twoFactorStorage) UseChallenge(id string) (bool, error) {
+	c, ok := m.challenges[id]
+	if !ok || c.used {
+		return false, nil
+	}
+	c.used = true
+	return true, nil
+}
+
+func (m *twoFactorStorage) LoginLockedUntil(username string) (time.Time, error) {
+	return m.lockedUntil, nil
+}
+
+func (m *twoFactorStorage) RecordLoginFailure(username string) (int, error) {
+	m.failures++
+	return m.failures, nil
+}
+
+func (m *twoFactorStorage) LockLogin(username string, until time.Time) error {
+	m.lockedUntil = until
+	return nil
+}
+
+func (m *twoFactorStorage) ResetLoginFailures(userID int) error {
+	m.failures = 0
+	return nil
+}
+
+func signInChallenge(t *testing.T, s *AuthService) string {
+	t.Helper()
+	res, err := s.SignIn("alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ChallengeToken == "" || res.Token != "" {
+		t.Fatalf("sign in = %+v, want challenge", res)
+	}
+	return res.ChallengeToken
+}
+
+func currentCode(t *testing.T, storage *twoFactorStorage) string {
+	t.Helper()
+	code, err := totp.Code(storage.totp.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestVerifyChallengeUsedOnce(t *testing.T) {
+	storage := newTwoFactorStorage(t)
+	s := NewAuthService(storage, nil, nil, LockoutPolicy{})
+	challenge := signInChallenge(t, s)
+
+	if _, err := s.VerifyChallenge(challenge, currentCode(t, storage)); err != nil {
+		t.Fatal(err)
+	}
+	// the unused recovery code doesn't revive the used challenge
+	if _, err := s.VerifyChallenge(challenge, "abcde-fghij"); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("second use error = %v, want %v", err, ErrInvalidChallenge)
+	}
+	if !storage.recoveryCodes[hashSecret("abcdefghij")] {
+		t.Error("recovery code was spent on the used challenge")
+	}
+}
+
+func TestVerifyChallengeAttemptsLimit(t *testing.T) {
+	storage := newTwoFactorStorage(t)
+	s := NewAuthService(storage, nil, nil, LockoutPolicy{})
+	challenge := signInChallenge(t, s)
+
+	for i := 0; i < maxChallengeAttempts; i++ {
+		if _, err := s.VerifyChallenge(challenge, "000000"); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("attempt %d: error = %v, want %v", i+1, err, ErrInvalidCode)
+		}
+	}
+	if _, err := s.VerifyChallenge(challenge, currentCode(t, storage)); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("valid code after %d failures: error = %v, want %v", maxChallengeAttempts, err, ErrInvalidChallenge)
+	}
+	// the new challenge works, since the lockout is disabled
+	if _, err := s.VerifyChallenge(signInChallenge(t, s), currentCode(t, storage)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyChallengeLockout(t *testing.T) {
+	storage := newTwoFactorStorage(t)
+	s := NewAuthService(storage, nil, nil, LockoutPolicy{Threshold: 3, Duration: time.Minute})
+	challenge := signInChallenge(t, s)
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.VerifyChallenge(challenge, "000000"); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("attempt %d: error = %v, want %v", i+1, err, ErrInvalidCode)
+		}
+	}
+	var lerr *LockedError
+	if _, err := s.VerifyChallenge(challenge, currentCode(t, storage)); !errors.As(err, &lerr) {
+		t.Fatalf("error after failed codes = %v, want LockedError", err)
+	}
+	if _, err := s.SignIn("alice", "password"); !errors.As(err, &lerr) {
+		t.Errorf("sign in error = %v, want LockedError", err)
+	}
+
+	// the successful second factor resets the failures
+	storage.lockedUntil = time.Time{}
+	challenge = signInChallenge(t, s)
+	storage.failures = 2
+	if _, err := s.VerifyChallenge(challenge, currentCode(t, storage)); err != nil {
+		t.Fatal(err)
+	}
+	if storage.failures != 0 {
+		t.Errorf("failures = %d after sign in, want 0", storage.failures)
+	}
+}
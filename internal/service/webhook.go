@@ -0,0 +1,313 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// Headers which are sent with every webhook delivery
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookSignatureHeader = "X-Webhook-Signature-256"
+)
+
+const (
+	webhookSecretSize   = 32
+	webhookBatchSize    = 20
+	webhookPollInterval = 2 * time.Second
+	webhookTimeout      = 10 * time.Second
+	webhookLease        = 3 * webhookTimeout
+	webhookMaxAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	webhookMaxBackoff   = 6 * time.Hour
+	maxErrorBodySize    = 512
+)
+
+var (
+	ErrInvalidWebhookURL = errors.New("webhook url must be an absolute http(s) url")
+	ErrWebhookAddress    = errors.New("webhook url must resolve to public addresses only")
+	ErrUnknownEventType  = errors.New("unknown event type")
+)
+
+// reservedNetworks aren't reachable from the internet, though they aren't
+// reported by net.IP methods
+var reservedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"), // carrier-grade NAT, used by some clouds internally
+}
+
+type WebhookStorage interface {
+	CreateWebhook(userID int, w core.Webhook) (core.Webhook, error)
+	GetAllWebhooks(userID int) ([]core.Webhook, error)
+	GetWebhookByID(userID, webhookID int) (core.Webhook, error)
+	DeleteWebhook(webhookID int) error
+	EnqueueDeliveries(e core.Event, payload []byte) (int64, error)
+	ClaimDeliveries(limit int, lease time.Duration) ([]core.WebhookDelivery, error)
+	SaveDeliveryAttempt(d core.WebhookDelivery) error
+	GetDeliveries(webhookID, limit int) ([]core.WebhookDelivery, error)
+}
+
+type WebhookService struct {
+	storage WebhookStorage
+	client  *http.Client
+	// lookupIP and allowIP guard internal services of the deployment from
+	// requests of webhooks, they're replaced in tests only
+	lookupIP func(ctx context.Context, host string) ([]net.IPAddr, error)
+	allowIP  func(ip net.IP) bool
+	log      *zap.Logger
+}
+
+func NewWebhookService(storage WebhookStorage, log *zap.Logger) *WebhookService {
+	s := &WebhookService{
+		storage:  storage,
+		lookupIP: net.DefaultResolver.LookupIPAddr,
+		allowIP:  publicIP,
+		log:      log,
+	}
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: s.controlDial}
+	s.client = &http.Client{
+		Timeout: webhookTimeout,
+		// the proxy from the environment would connect instead of the dialer,
+		// so addresses of webhooks couldn't be checked
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+			MaxIdleConns:        webhookBatchSize,
+			IdleConnTimeout:     webhookLease,
+		},
+	}
+	return s
+}
+
+// CreateWebhook validates subscription and generates secret for signing its payloads
+func (s *WebhookService) CreateWebhook(userID int, w core.Webhook) (core.Webhook, error) {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return w, ErrInvalidWebhookURL
+	}
+	if err := s.checkHost(u.Hostname()); err != nil {
+		return w, err
+	}
+	for _, t := range w.Events {
+		if !isKnownEventType(t) {
+			return w, fmt.Errorf("%w: %s", ErrUnknownEventType, t)
+		}
+	}
+	if w.Events == nil {
+		w.Events = make([]string, 0)
+	}
+	secret := make([]byte, webhookSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return w, err
+	}
+	w.Secret = hex.EncodeToString(secret)
+	return s.storage.CreateWebhook(userID, w)
+}
+
+func (s *WebhookService) GetAllWebhooks(userID int) ([]core.Webhook, error) {
+	return s.storage.GetAllWebhooks(userID)
+}
+
+func (s *WebhookService) GetWebhookByID(userID, webhookID int) (core.Webhook, error) {
+	return s.storage.GetWebhookByID(userID, webhookID)
+}
+
+func (s *WebhookService) DeleteWebhook(webhookID int) error {
+	return s.storage.DeleteWebhook(webhookID)
+}
+
+func (s *WebhookService) GetDeliveries(webhookID, limit int) ([]core.WebhookDelivery, error) {
+	return s.storage.GetDeliveries(webhookID, limit)
+}
+
+// Publish queues deliveries of the event to all matching webhooks
+func (s *WebhookService) Publish(e core.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.storage.EnqueueDeliveries(e, payload)
+	return err
+}
+
+// Run delivers queued payloads until the context is canceled
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.processDeliveries(ctx)
+		}
+	}
+}
+
+func (s *WebhookService) processDeliveries(ctx context.Context) {
+	deliveries, err := s.storage.ClaimDeliveries(webhookBatchSize, webhookLease)
+	if err != nil {
+		s.log.Error("can't claim webhook deliveries", zap.Error(err))
+		return
+	}
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func(d core.WebhookDelivery) {
+			defer wg.Done()
+			d = s.deliver(ctx, d)
+			if err := s.storage.SaveDeliveryAttempt(d); err != nil {
+				s.log.Error("can't save webhook delivery", zap.Int("deliveryID", d.ID), zap.Error(err))
+			}
+		}(d)
+	}
+	wg.Wait()
+}
+
+// deliver sends the payload once and returns delivery with the updated state
+func (s *WebhookService) deliver(ctx context.Context, d core.WebhookDelivery) core.WebhookDelivery {
+	d.Attempts++
+	d.ResponseCode = 0
+	d.LastError = ""
+	code, err := s.send(ctx, d)
+	d.ResponseCode = code
+	now := time.Now()
+	switch {
+	case err == nil:
+		d.Status = core.DeliveryDelivered
+		d.DeliveredAt = &now
+		d.NextAttemptAt = now
+	case d.Attempts >= webhookMaxAttempts:
+		d.Status = core.DeliveryFailed
+		d.LastError = err.Error()
+		d.NextAttemptAt = now
+	default:
+		d.Status = core.DeliveryPending
+		d.LastError = err.Error()
+		d.NextAttemptAt = now.Add(webhookBackoff(d.Attempts))
+	}
+	return d
+}
+
+func (s *WebhookService) send(ctx context.Context, d core.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-app-webhooks")
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.Itoa(d.ID))
+	req.Header.Set(WebhookSignatureHeader, SignPayload(d.Secret, d.Payload))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	return resp.StatusCode, nil
+}
+
+// checkHost rejects hosts which resolve to addresses of internal networks.
+// DNS of the host can change later, so addresses are checked on delivery too
+func (s *WebhookService) checkHost(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	addrs, err := s.lookupIP(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWebhookAddress, err)
+	}
+	for _, addr := range addrs {
+		if !s.allowIP(addr.IP) {
+			return fmt.Errorf("%w: %s is %s", ErrWebhookAddress, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// controlDial rejects connections to addresses of internal networks right
+// before they're made, so neither DNS changes nor redirects can reach them
+func (s *WebhookService) controlDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !s.allowIP(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddress, host)
+	}
+	return nil
+}
+
+// publicIP reports whether the address belongs to the internet, webhooks
+// aren't sent to loopback, link-local, private and other reserved networks
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, n := range reservedNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// SignPayload returns HMAC-SHA256 signature of the payload, receivers should
+// compare it with the value of WebhookSignatureHeader
+func SignPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns exponential delay before the next attempt
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return delay
+}
+
+func isKnownEventType(t string) bool {
+	for _, known := range core.EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// webhookStorage keeps deliveries in memory, claimed deliveries are leased
+// until their attempt is saved
+type webhookStorage struct {
+	WebhookStorage
+	mu       sync.Mutex
+	pending  []core.WebhookDelivery
+	leased   map[int]bool
+	saved    []core.WebhookDelivery
+	webhooks []core.Webhook
+}
+
+func newWebhookStorage(deliveries ...core.WebhookDelivery) *webhookStorage {
+	return &webhookStorage{pending: deliveries, leased: make(map[int]bool)}
+}
+
+func (m *webhookStorage) ClaimDeliveries(limit int, lease time.Duration) ([]core.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	claimed := m.pending
+	m.pending = nil
+	for _, d := range claimed {
+		m.leased[d.ID] = true
+	}
+	return claimed, nil
+}
+
+func (m *webhookStorage) SaveDeliveryAttempt(d core.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.leased, d.ID)
+	m.saved = append(m.saved, d)
+	if d.Status == core.DeliveryPending {
+		m.pending = append(m.pending, d)
+	}
+	return nil
+}
+
+func (m *webhookStorage) CreateWebhook(userID int, w core.Webhook) (core.Webhook, error) {
+	m.webhooks = append(m.webhooks, w)
+	return w, nil
+}
+
+// receiver records requests to the webhook and replies with the given statuses
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := http.StatusOK
+	if len(rc.statuses) != 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte("receiver says " + http.StatusText(status)))
+}
+
+// newTestWebhookService returns the service which may deliver to the receiver
+// on the loopback address
+func newTestWebhookService(storage WebhookStorage) *WebhookService {
+	s := NewWebhookService(storage, zap.NewNop())
+	s.allowIP = func(net.IP) bool { return true }
+	return s
+}
+
+func testDelivery(url string) core.WebhookDelivery {
+	return core.WebhookDelivery{
+		ID:        7,
+		WebhookID: 3,
+		EventType: core.EventTodoCreated,
+		Payload:   []byte(`{"type":"todo.created","list_id":1}`),
+		Status:    core.DeliveryPending,
+		URL:       url,
+		Secret:    "s3cret",
+	}
+}
+
+func TestWebhookDeliverySignature(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	d := testDelivery(srv.URL)
+	storage := newWebhookStorage(d)
+	s := newTestWebhookService(storage)
+
+	before := time.Now()
+	s.processDeliveries(context.Background())
+
+	if len(rc.requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(rc.requests))
+	}
+	req, body := rc.requests[0], rc.bodies[0]
+	if string(body) != string(d.Payload) {
+		t.Errorf("body = %s, want %s", body, d.Payload)
+	}
+	mac := hmac.New(sha256.New, []byte(d.Secret))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := req.Header.Get(WebhookSignatureHeader); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := req.Header.Get(WebhookEventHeader); got != d.EventType {
+		t.Errorf("event header = %q, want %q", got, d.EventType)
+	}
+	if got := req.Header.Get(WebhookDeliveryHeader); got != "7" {
+		t.Errorf("delivery header = %q, want 7", got)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("content type = %q, want application/json", got)
+	}
+
+	if len(storage.leased) != 0 {
+		t.Errorf("deliveries %v are still leased", storage.leased)
+	}
+	if len(storage.saved) != 1 {
+		t.Fatalf("saved %d attempts, want 1", len(storage.saved))
+	}
+	saved := storage.saved[0]
+	if saved.Status != core.DeliveryDelivered || saved.Attempts != 1 || saved.ResponseCode != http.StatusOK {
+		t.Errorf("saved delivery = %+v, want delivered at the first attempt", saved)
+	}
+	if saved.DeliveredAt == nil || saved.DeliveredAt.Before(before) {
+		t.Errorf("delivered at = %v, want time of the attempt", saved.DeliveredAt)
+	}
+}
+
+func TestWebhookRetrySchedule(t *testing.T) {
+	// the receiver fails every attempt, so the delivery is retried with
+	// exponential backoff until it runs out of attempts
+	statuses := make([]int, webhookMaxAttempts)
+	for i := range statuses {
+		statuses[i] = http.StatusBadGateway
+	}
+	rc := &receiver{statuses: statuses}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	storage := newWebhookStorage(testDelivery(srv.URL))
+	s := newTestWebhookService(storage)
+
+	backoffs := []time.Duration{
+		30 * time.Second,
+		time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		8 * time.Minute,
+		16 * time.Minute,
+		32 * time.Minute,
+	}
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		start := time.Now()
+		s.processDeliveries(context.Background())
+		end := time.Now()
+
+		if len(storage.leased) != 0 {
+			t.Fatalf("attempt %d: deliveries %v are still leased", attempt, storage.leased)
+		}
+		if len(storage.saved) != attempt {
+			t.Fatalf("attempt %d: saved %d attempts", attempt, len(storage.saved))
+		}
+		d := storage.saved[attempt-1]
+		if d.Attempts != attempt || d.ResponseCode != http.StatusBadGateway {
+			t.Errorf("attempt %d: saved %+v", attempt, d)
+		}
+		if !strings.Contains(d.LastError, "unexpected status 502") || !strings.Contains(d.LastError, "receiver says") {
+			t.Errorf("attempt %d: last error = %q", attempt, d.LastError)
+		}
+		if attempt == webhookMaxAttempts {
+			if d.Status != core.DeliveryFailed {
+				t.Errorf("last attempt: status = %s, want %s", d.Status, core.DeliveryFailed)
+			}
+			continue
+		}
+		if d.Status != core.DeliveryPending {
+			t.Errorf("attempt %d: status = %s, want %s", attempt, d.Status, core.DeliveryPending)
+		}
+		backoff := backoffs[attempt-1]
+		if d.NextAttemptAt.Before(start.Add(backoff)) || d.NextAttemptAt.After(end.Add(backoff)) {
+			t.Errorf("attempt %d: next attempt in %v, want %v", attempt, d.NextAttemptAt.Sub(start), backoff)
+		}
+	}
+	if len(rc.requests) != webhookMaxAttempts {
+		t.Errorf("receiver got %d requests, want %d", len(rc.requests), webhookMaxAttempts)
+	}
+	if len(storage.pending) != 0 {
+		t.Errorf("failed delivery is still pending")
+	}
+}
+
+func TestWebhookBackoffCap(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{10, 256 * time.Minute},
+		{11, webhookMaxBackoff},
+		{100, webhookMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookUnreachableReleasesLease(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	storage := newWebhookStorage(testDelivery(url))
+	s := newTestWebhookService(storage)
+
+	s.processDeliveries(context.Background())
+
+	if len(storage.leased) != 0 {
+		t.Fatalf("deliveries %v are still leased", storage.leased)
+	}
+	d := storage.saved[0]
+	if d.Status != core.DeliveryPending || d.ResponseCode != 0 || d.LastError == "" {
+		t.Errorf("saved delivery = %+v, want pending with the connection error", d)
+	}
+}
+
+func TestWebhookDeliveryBlocksInternalAddresses(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	storage := newWebhookStorage(testDelivery(srv.URL))
+	// the default service doesn't connect to the receiver on the loopback address
+	s := NewWebhookService(storage, zap.NewNop())
+
+	s.processDeliveries(context.Background())
+
+	if len(rc.requests) != 0 {
+		t.Fatalf("receiver on the loopback address got %d requests", len(rc.requests))
+	}
+	d := storage.saved[0]
+	if d.Status != core.DeliveryPending || !strings.Contains(d.LastError, ErrWebhookAddress.Error()) {
+		t.Errorf("saved delivery = %+v, want it rejected by the dialer", d)
+	}
+}
+
+func TestCreateWebhookAddresses(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://example.com/hook", nil},
+		{"http://93.184.216.34:8080/hook", nil},
+		{"ftp://example.com/hook", ErrInvalidWebhookURL},
+		{"/hook", ErrInvalidWebhookURL},
+		{"http://127.0.0.1/hook", ErrWebhookAddress},
+		{"http://localhost:8000/hook", ErrWebhookAddress},
+		{"http://169.254.169.254/latest/meta-data", ErrWebhookAddress},
+		{"http://10.1.2.3/hook", ErrWebhookAddress},
+		{"http://172.16.0.1/hook", ErrWebhookAddress},
+		{"http://192.168.1.10/hook", ErrWebhookAddress},
+		{"http://100.64.0.1/hook", ErrWebhookAddress},
+		{"http://0.0.0.0/hook", ErrWebhookAddress},
+		{"http://[::1]/hook", ErrWebhookAddress},
+		{"http://[fe80::1]/hook", ErrWebhookAddress},
+		{"http://[fd00::1]/hook", ErrWebhookAddress},
+		{"http://[::ffff:127.0.0.1]/hook", ErrWebhookAddress},
+		{"http://internal.example/hook", ErrWebhookAddress},
+		{"http://unknown.example/hook", ErrWebhookAddress},
+	}
+	hosts := map[string][]string{
+		"example.com":      {"93.184.216.34"},
+		"localhost":        {"127.0.0.1", "::1"},
+		"internal.example": {"93.184.216.34", "10.0.0.5"},
+	}
+	s := NewWebhookService(newWebhookStorage(), zap.NewNop())
+	s.lookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		if ip := net.ParseIP(host); ip != nil {
+			return []net.IPAddr{{IP: ip}}, nil
+		}
+		var addrs []net.IPAddr
+		for _, a := range hosts[host] {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(a)})
+		}
+		if addrs == nil {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return addrs, nil
+	}
+	for _, tt := range tests {
+		w, err := s.CreateWebhook(1, core.Webhook{URL: tt.url})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("CreateWebhook(%s) error = %v, want %v", tt.url, err, tt.want)
+		}
+		if err == nil && len(w.Secret) != 2*webhookSecretSize {
+			t.Errorf("CreateWebhook(%s) secret = %q", tt.url, w.Secret)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/mailer"
+	"go.uber.org/zap"
+)
+
+const (
+	workspaceInviteTTL  = 7 * 24 * time.Hour
+	maxWorkspaceNameLen = 255
+)
+
+var (
+	ErrWorkspaceForbidden   error = AccessError("you don't have access to this action in the workspace")
+	ErrPersonalWorkspace          = errors.New("personal workspace can't be deleted or shared")
+	ErrLastOwner                  = errors.New("workspace must have at least one owner")
+	ErrUnknownWorkspaceRole       = errors.New("role must be one of owner, admin, member or viewer")
+	ErrMissingWorkspaceName       = errors.New("missing required name field")
+	ErrInvalidInvite              = errors.New("invitation is invalid, expired or already used")
+	ErrInviteEmailMismatch        = errors.New("invitation was sent to another email, verify that email on your account first")
+	errWorkspaceNameTooLong       = errors.New("name can't be longer than 255 characters")
+)
+
+// AccessError is returned when the user's role doesn't allow the action
+type AccessError string
+
+func (e AccessError) Error() string {
+	return string(e)
+}
+
+// Forbidden reports that the error is caused by missing permissions
+func (e AccessError) Forbidden() bool {
+	return true
+}
+
+// WorkspaceAccess resolves roles of users in workspaces, lists and todos are
+// authorized through it
+type WorkspaceAccess interface {
+	GetMemberRole(workspaceID, userID int) (string, error)
+	GetListRole(listID, userID int) (string, error)
+}
+
+type WorkspaceStorage interface {
+	WorkspaceAccess
+	CreateWorkspace(userID int, w core.Workspace) (core.Workspace, error)
+	GetWorkspaces(userID int) ([]core.Workspace, error)
+	GetWorkspace(userID, workspaceID int) (core.Workspace, error)
+	UpdateWorkspace(workspaceID int, data core.UpdateWorkspaceData) (core.Workspace, error)
+	DeleteWorkspace(workspaceID int) error
+	GetMembers(workspaceID int) ([]core.WorkspaceMember, error)
+	CountOwners(workspaceID int) (int, error)
+	UpdateMemberRole(workspaceID, userID int, role string) error
+	RemoveMember(actorID, workspaceID, userID int) error
+	CreateInvite(i core.WorkspaceInvite) (core.WorkspaceInvite, error)
+	GetInvites(workspaceID int) ([]core.WorkspaceInvite, error)
+	GetInviteByHash(hash string) (core.WorkspaceInvite, error)
+	DeleteInvite(workspaceID, inviteID int) error
+	AcceptInvite(inviteID, userID int) error
+	GetUserByID(userID int) (core.User, error)
+}
+
+// WorkspaceService manages workspaces, their members and invitations. Every
+// user has the personal workspace, which can't be shared
+type WorkspaceService struct {
+	storage WorkspaceStorage
+	mailer  mailer.Mailer
+	appURL  string
+	log     *zap.Logger
+}
+
+func NewWorkspaceService(storage WorkspaceStorage, m mailer.Mailer, appURL string, log *zap.Logger) *WorkspaceService {
+	return &WorkspaceService{storage, m, strings.TrimSuffix(appURL, "/"), log}
+}
+
+// CreateWorkspace creates the shared workspace owned by the user
+func (s *WorkspaceService) CreateWorkspace(userID int, w core.Workspace) (core.Workspace, error) {
+	name, err := workspaceName(w.Name)
+	if err != nil {
+		return core.Workspace{}, err
+	}
+	return s.storage.CreateWorkspace(userID, core.Workspace{Name: name})
+}
+
+// GetWorkspaces returns workspaces the user is member of
+func (s *WorkspaceService) GetWorkspaces(userID int) ([]core.Workspace, error) {
+	return s.storage.GetWorkspaces(userID)
+}
+
+// GetWorkspace returns the workspace with the user's role in it
+func (s *WorkspaceService) GetWorkspace(userID, workspaceID int) (core.Workspace, error) {
+	return s.storage.GetWorkspace(userID, workspaceID)
+}
+
+func (s *WorkspaceService) UpdateWorkspace(userID, workspaceID int, data core.UpdateWorkspaceData) (core.Workspace, error) {
+	if err := s.authorize(userID, workspaceID, core.CanManage); err != nil {
+		return core.Workspace{}, err
+	}
+	if data.Name != nil {
+		name, err := workspaceName(*data.Name)
+		if err != nil {
+			return core.Workspace{}, err
+		}
+		data.Name = &name
+	}
+	ws, err := s.storage.UpdateWorkspace(workspaceID, data)
+	if err != nil {
+		return ws, err
+	}
+	return s.storage.GetWorkspace(userID, ws.ID)
+}
+
+// DeleteWorkspace removes the shared workspace with all its lists, only owners
+// can do it
+func (s *WorkspaceService) DeleteWorkspace(userID, workspaceID int) error {
+	ws, err := s.storage.GetWorkspace(userID, workspaceID)
+	if err != nil {
+		return ErrWorkspaceForbidden
+	}
+	if ws.Role != core.WorkspaceRoleOwner {
+		return ErrWorkspaceForbidden
+	}
+	if ws.Personal {
+		return ErrPersonalWorkspace
+	}
+	return s.storage.DeleteWorkspace(workspaceID)
+}
+
+func (s *WorkspaceService) GetMembers(userID, workspaceID int) ([]core.WorkspaceMember, error) {
+	if err := s.authorize(userID, workspaceID, isMember); err != nil {
+		return nil, err
+	}
+	return s.storage.GetMembers(workspaceID)
+}
+
+// UpdateMemberRole changes role of the member. Only owners can grant or revoke
+// the owner role, and the last owner can't be demoted
+func (s *WorkspaceService) UpdateMemberRole(userID, workspaceID, memberID int, role string) error {
+	if !validWorkspaceRole(role) {
+		return ErrUnknownWorkspaceRole
+	}
+	actorRole, err := s.role(userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if !core.CanManage(actorRole) {
+		return ErrWorkspaceForbidden
+	}
+	memberRole, err := s.storage.GetMemberRole(workspaceID, memberID)
+	if err != nil {
+		return err
+	}
+	if (role == core.WorkspaceRoleOwner || memberRole == core.WorkspaceRoleOwner) && actorRole != core.WorkspaceRoleOwner {
+		return ErrWorkspaceForbidden
+	}
+	if memberRole == core.WorkspaceRoleOwner && role != core.WorkspaceRoleOwner {
+		if err := s.keepOwner(workspaceID); err != nil {
+			return err
+		}
+	}
+	return s.storage.UpdateMemberRole(workspaceID, memberID, role)
+}
+
+// RemoveMember removes the member from the workspace, members can leave the
+// workspace on their own
+func (s *WorkspaceService) RemoveMember(userID, workspaceID, memberID int) error {
+	ws, err := s.storage.GetWorkspace(userID, workspaceID)
+	if err != nil {
+		return ErrWorkspaceForbidden
+	}
+	if ws.Personal {
+		return ErrPersonalWorkspace
+	}
+	memberRole, err := s.storage.GetMemberRole(workspaceID, memberID)
+	if err != nil {
+		return err
+	}
+	if userID != memberID {
+		if !core.CanManage(ws.Role) {
+			return ErrWorkspaceForbidden
+		}
+		if memberRole == core.WorkspaceRoleOwner && ws.Role != core.WorkspaceRoleOwner {
+			return ErrWorkspaceForbidden
+		}
+	}
+	if memberRole == core.WorkspaceRoleOwner {
+		if err := s.keepOwner(workspaceID); err != nil {
+			return err
+		}
+	}
+	return s.storage.RemoveMember(userID, workspaceID, memberID)
+}
+
+// Invite sends the invitation link to the email. Only owners can invite other
+// owners
+func (s *WorkspaceService) Invite(userID, workspaceID int, email, role string) (core.WorkspaceInvite, error) {
+	var invite core.WorkspaceInvite
+	if role == "" {
+		role = core.WorkspaceRoleMember
+	}
+	if !validWorkspaceRole(role) {
+		return invite, ErrUnknownWorkspaceRole
+	}
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return invite, err
+	}
+	ws, err := s.storage.GetWorkspace(userID, workspaceID)
+	if err != nil || !core.CanManage(ws.Role) {
+		return invite, ErrWorkspaceForbidden
+	}
+	if role == core.WorkspaceRoleOwner && ws.Role != core.WorkspaceRoleOwner {
+		return invite, ErrWorkspaceForbidden
+	}
+	if ws.Personal {
+		return invite, ErrPersonalWorkspace
+	}
+	token, err := randomToken()
+	if err != nil {
+		return invite, err
+	}
+	invite, err = s.storage.CreateInvite(core.WorkspaceInvite{
+		WorkspaceID: workspaceID,
+		Email:       email,
+		Role:        role,
+		Hash:        hashSecret(token),
+		InvitedBy:   userID,
+		ExpiresAt:   time.Now().Add(workspaceInviteTTL),
+	})
+	if err != nil {
+		return invite, err
+	}
+	link := s.appURL + "/invites/accept?token=" + url.QueryEscape(token)
+	s.send(mailer.Message{
+		To:      email,
+		Subject: fmt.Sprintf("You are invited to %s", ws.Name),
+		Body: fmt.Sprintf("Hi,\n\nyou are invited to join the workspace %s as %s.\n"+
+			"Open the link to accept the invitation:\n%s\n\n"+
+			"Or send the token to the API: %s\n\n"+
+			"The invitation expires in %s.\n", ws.Name, role, link, token, workspaceInviteTTL),
+	})
+	invite.Token = token
+	return invite, nil
+}
+
+func (s *WorkspaceService) GetInvites(userID, workspaceID int) ([]core.WorkspaceInvite, error) {
+	if err := s.authorize(userID, workspaceID, core.CanManage); err != nil {
+		return nil, err
+	}
+	return s.storage.GetInvites(workspaceID)
+}
+
+func (s *WorkspaceService) RevokeInvite(userID, workspaceID, inviteID int) error {
+	if err := s.authorize(userID, workspaceID, core.CanManage); err != nil {
+		return err
+	}
+	return s.storage.DeleteInvite(workspaceID, inviteID)
+}
+
+// AcceptInvite adds the user to the invitation's workspace. The invitation can
+// be accepted only by the user whose verified email it was sent to
+func (s *WorkspaceService) AcceptInvite(userID int, token string) (core.Workspace, error) {
+	invite, err := s.storage.GetInviteByHash(hashSecret(token))
+	if err != nil {
+		return core.Workspace{}, ErrInvalidInvite
+	}
+	u, err := s.storage.GetUserByID(userID)
+	if err != nil {
+		return core.Workspace{}, err
+	}
+	if !u.EmailVerified || !strings.EqualFold(u.Email, invite.Email) {
+		return core.Workspace{}, ErrInviteEmailMismatch
+	}
+	if err := s.storage.AcceptInvite(invite.ID, userID); err != nil {
+		return core.Workspace{}, ErrInvalidInvite
+	}
+	return s.storage.GetWorkspace(userID, invite.WorkspaceID)
+}
+
+// role returns role of the user in the workspace, users who aren't members
+// are forbidden
+func (s *WorkspaceService) role(userID, workspaceID int) (string, error) {
+	role, err := s.storage.GetMemberRole(workspaceID, userID)
+	if err != nil {
+		return "", ErrWorkspaceForbidden
+	}
+	return role, nil
+}
+
+func (s *WorkspaceService) authorize(userID, workspaceID int, allowed func(role string) bool) error {
+	role, err := s.role(userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if !allowed(role) {
+		return ErrWorkspaceForbidden
+	}
+	return nil
+}
+
+// keepOwner fails when the workspace would be left without owners
+func (s *WorkspaceService) keepOwner(workspaceID int) error {
+	owners, err := s.storage.CountOwners(workspaceID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func (s *WorkspaceService) send(m mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, m); err != nil {
+			s.log.Error("can't send email", zap.String("subject", m.Subject), zap.Error(err))
+		}
+	}()
+}
+
+// authorizeList checks that the user's role in the list's workspace allows the
+// action, lists of other workspaces are forbidden
+func authorizeList(access WorkspaceAccess, userID, listID int, allowed func(role string) bool) error {
+	role, err := access.GetListRole(listID, userID)
+	if err != nil || !allowed(role) {
+		return ErrWorkspaceForbidden
+	}
+	return nil
+}
+
+func isMember(role string) bool {
+	return role != ""
+}
+
+func validWorkspaceRole(role string) bool {
+	for _, r := range core.WorkspaceRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func workspaceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", ErrMissingWorkspaceName
+	}
+	if len(name) > maxWorkspaceNameLen {
+		return "", errWorkspaceNameTooLong
+	}
+	return name, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, oneTimeTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
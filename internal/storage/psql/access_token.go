@@ -0,0 +1,116 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jackc/pgtype"
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// AccessToken represents repository of personal access tokens
+type AccessToken struct {
+	db *sql.DB
+}
+
+// NewAccessToken returns instance of AccessToken repository
+func NewAccessToken(db *sql.DB) *AccessToken {
+	return &AccessToken{db}
+}
+
+// CreateAccessToken stores hash of the new token of the User
+func (r *AccessToken) CreateAccessToken(t core.AccessToken) (core.AccessToken, error) {
+	err := r.db.QueryRow(createAccessTokenQuery(), t.UserID, t.Name, t.Prefix, t.Hash, t.Scopes, t.ExpiresAt).
+		Scan(&t.ID, &t.CreatedAt)
+	return t, err
+}
+
+// GetAccessTokens returns all tokens of the given User
+func (r *AccessToken) GetAccessTokens(userID int) ([]core.AccessToken, error) {
+	var tokens []core.AccessToken
+	rows, err := r.db.Query(accessTokensQuery(), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// DeleteAccessToken revokes token of the given User
+func (r *AccessToken) DeleteAccessToken(userID, tokenID int) error {
+	res, err := r.db.Exec(deleteAccessTokenQuery(), userID, tokenID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UseAccessToken returns the unexpired token by its hash and remembers the time
+// when it was used
+func (r *AccessToken) UseAccessToken(hash string) (core.AccessToken, error) {
+	return scanAccessToken(r.db.QueryRow(useAccessTokenQuery(), hash))
+}
+
+func scanAccessToken(row rowScanner) (core.AccessToken, error) {
+	var (
+		t      core.AccessToken
+		scopes pgtype.TextArray
+	)
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &scopes, &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt)
+	if err != nil {
+		return t, err
+	}
+	return t, scopes.AssignTo(&t.Scopes)
+}
+
+func createAccessTokenQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (user_id, name, prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, accessTokensTable)
+}
+
+func accessTokensQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+		FROM %s
+		WHERE user_id = $1
+		ORDER BY id
+	`, accessTokensTable)
+}
+
+func deleteAccessTokenQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s
+		WHERE user_id = $1
+		AND id = $2
+	`, accessTokensTable)
+}
+
+func useAccessTokenQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET last_used_at = NOW()
+		WHERE token_hash = $1
+		AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+	`, accessTokensTable)
+}
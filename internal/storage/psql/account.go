@@ -0,0 +1,211 @@
+package psql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// UpdateUser updates profile of the User
+func (r *Auth) UpdateUser(userID int, data core.UpdateUserData) (core.User, error) {
+	var (
+		fields []string
+		args   []interface{}
+	)
+	if data.Name != nil {
+		args = append(args, *data.Name)
+		fields = append(fields, fmt.Sprintf("name = $%d", len(args)))
+	}
+	if data.Username != nil {
+		args = append(args, *data.Username)
+		fields = append(fields, fmt.Sprintf("username = $%d", len(args)))
+	}
+	if data.Email != nil {
+		args = append(args, nullableString(*data.Email))
+		fields = append(fields, fmt.Sprintf("email = $%d, email_verified_at = NULL", len(args)))
+	}
+	args = append(args, userID)
+	return scanUser(r.db.QueryRow(updateUserQuery(strings.Join(fields, ", "), len(args)), args...))
+}
+
+// UsernameTaken reports whether the username belongs to another User
+func (r *Auth) UsernameTaken(username string, userID int) (bool, error) {
+	var taken bool
+	err := r.db.QueryRow(usernameTakenQuery(), username, userID).Scan(&taken)
+	return taken, err
+}
+
+// UpdatePassword replaces password of the User and revokes all the User's
+// tokens, including personal access tokens
+func (r *Auth) UpdatePassword(userID int, hash string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(updatePasswordQuery(), userID, hash); err != nil {
+		return rollback(tx, err)
+	}
+	if _, err := tx.Exec(deleteUserAccessTokensQuery(), userID); err != nil {
+		return rollback(tx, err)
+	}
+	return tx.Commit()
+}
+
+// DeleteUser removes the User with everything the User owns. Lists of the
+// workspaces shared with other users stay with them, the rest of the lists are
+// removed together with their todos, history and workspaces
+func (r *Auth) DeleteUser(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	rows, err := tx.Query(ownListsQuery(), userID)
+	if err != nil {
+		return rollback(tx, err)
+	}
+	var listIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return rollback(tx, err)
+		}
+		listIDs = append(listIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return rollback(tx, err)
+	}
+	if len(listIDs) > 0 {
+		for _, query := range []string{
+			deleteListsTodosQuery(),
+			deleteListsEventsQuery(),
+			deleteListsOutboxQuery(),
+			deleteListsQuery(),
+		} {
+			if _, err := tx.Exec(query, listIDs); err != nil {
+				return rollback(tx, err)
+			}
+		}
+	}
+	// shared workspaces shouldn't stay without owner
+	if _, err := tx.Exec(transferOwnershipQuery(), userID); err != nil {
+		return rollback(tx, err)
+	}
+	// memberships, tokens, webhooks and the rest are removed by cascade
+	if _, err := tx.Exec(deleteUserQuery(), userID); err != nil {
+		return rollback(tx, err)
+	}
+	if _, err := tx.Exec(deleteEmptyWorkspacesQuery()); err != nil {
+		return rollback(tx, err)
+	}
+	return tx.Commit()
+}
+
+func updateUserQuery(fields string, idArg int) string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET %s
+		WHERE id = $%d
+		RETURNING %s
+	`, usersTable, fields, idArg, userColumns)
+}
+
+func usernameTakenQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT EXISTS (SELECT 1 FROM %s WHERE username = $1 AND id <> $2)
+	`, usersTable)
+}
+
+func updatePasswordQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET password_hash = $2, token_version = token_version + 1
+		WHERE id = $1
+	`, usersTable)
+}
+
+func deleteUserAccessTokensQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE user_id = $1
+	`, accessTokensTable)
+}
+
+// ownListsQuery returns lists which don't have other members than the User
+func ownListsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT ul.list_id
+		FROM %s AS ul
+		WHERE ul.user_id = $1
+		AND NOT EXISTS (
+			SELECT 1 FROM %s AS other
+			WHERE other.list_id = ul.list_id
+			AND other.user_id <> $1
+		)
+	`, usersListsTable, usersListsTable)
+}
+
+func deleteListsTodosQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s
+		WHERE id IN (SELECT item_id FROM %s WHERE list_id = ANY($1))
+	`, todoItemsTable, listsItemsTable)
+}
+
+func deleteListsEventsQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE list_id = ANY($1)
+	`, listEventsTable)
+}
+
+func deleteListsOutboxQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE list_id = ANY($1)
+	`, outboxTable)
+}
+
+func deleteListsQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE id = ANY($1)
+	`, todoListsTable)
+}
+
+// transferOwnershipQuery makes the earliest member the owner of every workspace
+// where the User is the only owner
+func transferOwnershipQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %[1]s AS wm SET role = 'owner'
+		FROM (
+			SELECT DISTINCT ON (m.workspace_id) m.workspace_id, m.user_id
+			FROM %[1]s AS m
+			WHERE m.user_id <> $1
+			AND m.workspace_id IN (
+				SELECT workspace_id FROM %[1]s
+				WHERE user_id = $1 AND role = 'owner'
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM %[1]s AS o
+				WHERE o.workspace_id = m.workspace_id
+				AND o.role = 'owner'
+				AND o.user_id <> $1
+			)
+			ORDER BY m.workspace_id, m.created_at, m.user_id
+		) AS heir
+		WHERE wm.workspace_id = heir.workspace_id
+		AND wm.user_id = heir.user_id
+	`, workspaceMembersTable)
+}
+
+func deleteEmptyWorkspacesQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s AS w
+		WHERE NOT EXISTS (SELECT 1 FROM %s WHERE workspace_id = w.id)
+	`, workspacesTable, workspaceMembersTable)
+}
+
+func deleteUserQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE id = $1
+	`, usersTable)
+}
@@ -0,0 +1,197 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Admin represents repository for managing users by admins. Every change is
+// recorded in the audit log within the same transaction
+type Admin struct {
+	db *sql.DB
+}
+
+// NewAdmin returns instance of admin repository
+func NewAdmin(db *sql.DB) *Admin {
+	return &Admin{db}
+}
+
+// GetUsers returns users matching the filter ordered by ID
+func (r *Admin) GetUsers(f core.UserFilter) ([]core.User, error) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if f.Query != "" {
+		args = append(args, "%"+escapeLike(f.Query)+"%")
+		conds = append(conds, fmt.Sprintf("(name ILIKE $%[1]d OR username ILIKE $%[1]d OR email ILIKE $%[1]d)", len(args)))
+	}
+	if f.Disabled != nil {
+		args = append(args, *f.Disabled)
+		conds = append(conds, fmt.Sprintf("(disabled_at IS NOT NULL) = $%d", len(args)))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, f.Limit, f.Offset)
+	rows, err := r.db.Query(usersQuery(where, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []core.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// GetUser returns the user by ID
+func (r *Admin) GetUser(userID int) (core.User, error) {
+	return scanUser(r.db.QueryRow(userByIDQuery(), userID))
+}
+
+// SetUserDisabled disables or enables sign in of the user
+func (r *Admin) SetUserDisabled(userID int, disabled bool, e core.AuditEntry) (core.User, error) {
+	return r.updateUser(setUserDisabledQuery(), e, userID, disabled)
+}
+
+// SetUserRole changes role of the user
+func (r *Admin) SetUserRole(userID int, role string, e core.AuditEntry) (core.User, error) {
+	return r.updateUser(setUserRoleQuery(), e, userID, role)
+}
+
+// RevokeSessions revokes all JWT of the user, so the user has to sign in again
+func (r *Admin) RevokeSessions(userID int, e core.AuditEntry) error {
+	_, err := r.updateUser(revokeSessionsQuery(), e, userID)
+	return err
+}
+
+// CreateAuditEntry records the admin's action which doesn't change data
+func (r *Admin) CreateAuditEntry(e core.AuditEntry) error {
+	_, err := r.db.Exec(createAuditEntryQuery(), e.AdminID, e.Action, e.TargetUserID, e.Details)
+	return err
+}
+
+// GetAuditLog returns the latest entries of the audit log, only entries of the
+// target user if it's set
+func (r *Admin) GetAuditLog(targetUserID, limit int) ([]core.AuditEntry, error) {
+	rows, err := r.db.Query(auditLogQuery(), targetUserID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []core.AuditEntry
+	for rows.Next() {
+		var e core.AuditEntry
+		err := rows.Scan(&e.ID, &e.AdminID, &e.Action, &e.TargetUserID, &e.Details, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetUsageStats returns counters of users and their content
+func (r *Admin) GetUsageStats() (core.UsageStats, error) {
+	var s core.UsageStats
+	err := r.db.QueryRow(usageStatsQuery()).Scan(&s.Users, &s.Admins, &s.DisabledUsers, &s.NewUsers,
+		&s.Lists, &s.Todos, &s.DoneTodos, &s.AccessTokens, &s.Webhooks)
+	return s, err
+}
+
+// updateUser runs the query which returns the updated user and records the
+// audit entry in the same transaction
+func (r *Admin) updateUser(query string, e core.AuditEntry, args ...interface{}) (core.User, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return core.User{}, err
+	}
+	user, err := scanUser(tx.QueryRow(query, args...))
+	if err != nil {
+		return user, rollback(tx, err)
+	}
+	if _, err := tx.Exec(createAuditEntryQuery(), e.AdminID, e.Action, e.TargetUserID, e.Details); err != nil {
+		return user, rollback(tx, err)
+	}
+	return user, tx.Commit()
+}
+
+// escapeLike escapes wildcards of LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func usersQuery(where string, limitArg int) string {
+	return fmt.Sprintf(`--sql
+		SELECT %s FROM %s
+		%s
+		ORDER BY id
+		LIMIT $%d OFFSET $%d
+	`, userColumns, usersTable, where, limitArg-1, limitArg)
+}
+
+func setUserDisabledQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, NOW()) END
+		WHERE id = $1
+		RETURNING %s
+	`, usersTable, userColumns)
+}
+
+func setUserRoleQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET role = $2
+		WHERE id = $1
+		RETURNING %s
+	`, usersTable, userColumns)
+}
+
+func revokeSessionsQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET token_version = token_version + 1
+		WHERE id = $1
+		RETURNING %s
+	`, usersTable, userColumns)
+}
+
+func createAuditEntryQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (admin_id, action, target_user_id, details)
+		VALUES ($1, $2, $3, $4)
+	`, adminAuditLogTable)
+}
+
+func auditLogQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, COALESCE(admin_id, 0), action, COALESCE(target_user_id, 0), details, created_at
+		FROM %s
+		WHERE $1 = 0 OR target_user_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, adminAuditLogTable)
+}
+
+func usageStatsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT
+			(SELECT COUNT(*) FROM %[1]s),
+			(SELECT COUNT(*) FROM %[1]s WHERE role = 'admin'),
+			(SELECT COUNT(*) FROM %[1]s WHERE disabled_at IS NOT NULL),
+			(SELECT COUNT(*) FROM %[1]s WHERE created_at > NOW() - INTERVAL '30 days'),
+			(SELECT COUNT(*) FROM %[2]s),
+			(SELECT COUNT(*) FROM %[3]s),
+			(SELECT COUNT(*) FROM %[3]s WHERE done),
+			(SELECT COUNT(*) FROM %[4]s),
+			(SELECT COUNT(*) FROM %[5]s)
+	`, usersTable, todoListsTable, todoItemsTable, accessTokensTable, webhooksTable)
+}
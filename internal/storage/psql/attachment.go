@@ -0,0 +1,169 @@
+package psql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Attachment represents repository of todos' attachments
+type Attachment struct {
+	db *sql.DB
+}
+
+// NewAttachment returns instance of Attachment repository
+func NewAttachment(db *sql.DB) *Attachment {
+	return &Attachment{db}
+}
+
+// GetAttachments returns attachments of the Todo
+func (r *Attachment) GetAttachments(todoID int) ([]core.Attachment, error) {
+	rows, err := r.db.Query(attachmentsQuery(), todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var attachments []core.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// GetAttachment returns attachment of the Todo by ID
+func (r *Attachment) GetAttachment(todoID, attachmentID int) (core.Attachment, error) {
+	return scanAttachment(r.db.QueryRow(attachmentByIDQuery(), todoID, attachmentID))
+}
+
+// UsedSpace returns total size of attachments uploaded by the User
+func (r *Attachment) UsedSpace(userID int) (int64, error) {
+	var used int64
+	err := r.db.QueryRow(usedSpaceQuery(), userID).Scan(&used)
+	return used, err
+}
+
+// CreateAttachment saves the attachment unless it exceeds the quota of the
+// uploader, zero quota is unlimited. Uploads of the same user are serialized,
+// so the quota can't be exceeded by concurrent uploads, false is returned when
+// it would be
+func (r *Attachment) CreateAttachment(a core.Attachment, quota int64) (core.Attachment, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return a, false, err
+	}
+	if _, err := tx.Exec(lockUserQuery(), a.UserID); err != nil {
+		return a, false, rollback(tx, err)
+	}
+	err = tx.QueryRow(createAttachmentQuery(), a.TodoID, a.UserID, a.Filename, a.ContentType, a.Size, a.Key, quota).
+		Scan(&a.ID, &a.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, false, rollback(tx, nil)
+	}
+	if err != nil {
+		return a, false, rollback(tx, err)
+	}
+	return a, true, tx.Commit()
+}
+
+// DeleteAttachment removes the attachment, its blob is queued for deletion
+func (r *Attachment) DeleteAttachment(attachmentID int) error {
+	ok, err := affected(r.db.Exec(deleteAttachmentQuery(), attachmentID))
+	if err == nil && !ok {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// GetBlobDeletions returns keys of the blobs which have to be deleted
+func (r *Attachment) GetBlobDeletions(limit int) ([]string, error) {
+	rows, err := r.db.Query(blobDeletionsQuery(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// DeleteBlobDeletion forgets the blob which is deleted from the store
+func (r *Attachment) DeleteBlobDeletion(key string) error {
+	_, err := r.db.Exec(deleteBlobDeletionQuery(), key)
+	return err
+}
+
+func scanAttachment(row rowScanner) (core.Attachment, error) {
+	var a core.Attachment
+	err := row.Scan(&a.ID, &a.TodoID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, &a.Key, &a.CreatedAt)
+	return a, err
+}
+
+func attachmentsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, todo_id, COALESCE(user_id, 0), filename, content_type, size, storage_key, created_at
+		FROM %s
+		WHERE todo_id = $1
+		ORDER BY id
+	`, todoAttachmentsTable)
+}
+
+func attachmentByIDQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, todo_id, COALESCE(user_id, 0), filename, content_type, size, storage_key, created_at
+		FROM %s
+		WHERE todo_id = $1
+		AND id = $2
+	`, todoAttachmentsTable)
+}
+
+func usedSpaceQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT COALESCE(SUM(size), 0)::BIGINT FROM %s WHERE user_id = $1
+	`, todoAttachmentsTable)
+}
+
+func lockUserQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id FROM %s WHERE id = $1 FOR UPDATE
+	`, usersTable)
+}
+
+func createAttachmentQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (todo_id, user_id, filename, content_type, size, storage_key)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE $7::BIGINT = 0
+		OR (SELECT COALESCE(SUM(size), 0) FROM %s WHERE user_id = $2) + $5 <= $7::BIGINT
+		RETURNING id, created_at
+	`, todoAttachmentsTable, todoAttachmentsTable)
+}
+
+func deleteAttachmentQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE id = $1
+	`, todoAttachmentsTable)
+}
+
+func blobDeletionsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT storage_key FROM %s ORDER BY created_at LIMIT $1
+	`, blobDeletionsTable)
+}
+
+func deleteBlobDeletionQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE storage_key = $1
+	`, blobDeletionsTable)
+}
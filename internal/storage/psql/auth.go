@@ -7,6 +7,10 @@ import (
 	"github.com/vbetsun/todo-app/internal/core"
 )
 
+// userColumns are columns of users table which are read by scanUser
+const userColumns = "id, name, username, COALESCE(email, ''), email_verified_at IS NOT NULL, token_version, " +
+	"role, disabled_at IS NOT NULL, created_at"
+
 // Auth represents repository for authorization and authentication
 type Auth struct {
 	db *sql.DB
@@ -17,34 +21,64 @@ func NewAuth(db *sql.DB) *Auth {
 	return &Auth{db}
 }
 
-// CreateUser creates new user in DB
+// CreateUser creates new user in DB together with the user's personal workspace
+// and default settings
 func (r *Auth) CreateUser(u core.User) (core.User, error) {
 	var user core.User
-	err := r.db.QueryRow(createUserQuery(), u.Name, u.Username, u.Password).
-		Scan(&user.ID, &user.Name, &user.Username)
-	return user, err
+	tx, err := r.db.Begin()
+	if err != nil {
+		return user, err
+	}
+	err = tx.QueryRow(createUserQuery(), u.Name, u.Username, u.Password, nullableString(u.Email)).
+		Scan(&user.ID, &user.Name, &user.Username, &user.Email)
+	if err != nil {
+		return user, rollback(tx, err)
+	}
+	if err := createPersonalWorkspace(tx, user.ID); err != nil {
+		return user, rollback(tx, err)
+	}
+	if err := createUserSettings(tx, user.ID); err != nil {
+		return user, rollback(tx, err)
+	}
+	return user, tx.Commit()
 }
 
 // GetUser returns user from DB by username and password
 func (r *Auth) GetUser(username, pwd string) (core.User, error) {
-	var user core.User
-	err := r.db.QueryRow(getUserQuery(), username, pwd).Scan(&user.ID)
+	return scanUser(r.db.QueryRow(getUserQuery(), username, pwd))
+}
+
+// GetUserByID returns user from DB by ID
+func (r *Auth) GetUserByID(userID int) (core.User, error) {
+	return scanUser(r.db.QueryRow(userByIDQuery(), userID))
+}
 
+func scanUser(row rowScanner) (core.User, error) {
+	var user core.User
+	err := row.Scan(&user.ID, &user.Name, &user.Username, &user.Email, &user.EmailVerified, &user.TokenVersion,
+		&user.Role, &user.Disabled, &user.CreatedAt)
 	return user, err
 }
 
 func createUserQuery() string {
 	return fmt.Sprintf(`--sql
-		INSERT INTO %s (name, username, password_hash) 
-		VALUES ($1, $2, $3) 
-		RETURNING id, name, username
+		INSERT INTO %s (name, username, password_hash, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, name, username, COALESCE(email, '')
 	`, usersTable)
 }
 
 func getUserQuery() string {
 	return fmt.Sprintf(`--sql
-		SELECT id FROM %s 
-		WHERE username = $1 
+		SELECT %s FROM %s
+		WHERE username = $1
 		AND password_hash = $2
-	`, usersTable)
+	`, userColumns, usersTable)
+}
+
+func userByIDQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT %s FROM %s
+		WHERE id = $1
+	`, userColumns, usersTable)
 }
@@ -0,0 +1,217 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Comment represents repository of todos' comments
+type Comment struct {
+	db *sql.DB
+}
+
+// NewComment returns instance of Comment repository
+func NewComment(db *sql.DB) *Comment {
+	return &Comment{db}
+}
+
+// GetComments returns page of the Todo's comments, oldest go first
+func (r *Comment) GetComments(todoID, limit, offset int) ([]core.Comment, error) {
+	rows, err := r.db.Query(commentsQuery(), todoID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	var comments []core.Comment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadMentions(comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// GetComment returns the Todo's comment by ID
+func (r *Comment) GetComment(todoID, commentID int) (core.Comment, error) {
+	c, err := scanComment(r.db.QueryRow(commentByIDQuery(), todoID, commentID))
+	if err != nil {
+		return c, err
+	}
+	comments := []core.Comment{c}
+	err = r.loadMentions(comments)
+	return comments[0], err
+}
+
+// CreateComment saves the comment, mentioned usernames are linked when they
+// belong to members of the List
+func (r *Comment) CreateComment(listID int, c core.Comment, usernames []string) (core.Comment, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return c, err
+	}
+	var commentID int
+	if err := tx.QueryRow(createCommentQuery(), c.TodoID, c.AuthorID, c.Body).Scan(&commentID); err != nil {
+		return c, rollback(tx, err)
+	}
+	if _, err := tx.Exec(createMentionsQuery(), commentID, listID, usernames); err != nil {
+		return c, rollback(tx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return c, err
+	}
+	return r.GetComment(c.TodoID, commentID)
+}
+
+// UpdateComment replaces body and mentions of the comment
+func (r *Comment) UpdateComment(listID int, c core.Comment, usernames []string) (core.Comment, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return c, err
+	}
+	ok, err := affected(tx.Exec(updateCommentQuery(), c.ID, c.Body))
+	if err != nil {
+		return c, rollback(tx, err)
+	}
+	if !ok {
+		return c, rollback(tx, sql.ErrNoRows)
+	}
+	if _, err := tx.Exec(deleteMentionsQuery(), c.ID); err != nil {
+		return c, rollback(tx, err)
+	}
+	if _, err := tx.Exec(createMentionsQuery(), c.ID, listID, usernames); err != nil {
+		return c, rollback(tx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return c, err
+	}
+	return r.GetComment(c.TodoID, c.ID)
+}
+
+// DeleteComment removes the comment with its mentions
+func (r *Comment) DeleteComment(commentID int) error {
+	ok, err := affected(r.db.Exec(deleteCommentQuery(), commentID))
+	if err == nil && !ok {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// loadMentions fills mentions of the comments
+func (r *Comment) loadMentions(comments []core.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	ids := make([]int, len(comments))
+	byID := make(map[int]*core.Comment, len(comments))
+	for i := range comments {
+		comments[i].Mentions = make([]core.Mention, 0)
+		ids[i] = comments[i].ID
+		byID[comments[i].ID] = &comments[i]
+	}
+	rows, err := r.db.Query(mentionsQuery(), ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			commentID int
+			m         core.Mention
+		)
+		if err := rows.Scan(&commentID, &m.UserID, &m.Username); err != nil {
+			return err
+		}
+		if c, ok := byID[commentID]; ok {
+			c.Mentions = append(c.Mentions, m)
+		}
+	}
+	return rows.Err()
+}
+
+func scanComment(row rowScanner) (core.Comment, error) {
+	var c core.Comment
+	err := row.Scan(&c.ID, &c.TodoID, &c.AuthorID, &c.AuthorUsername, &c.Body, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+func commentsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT c.id, c.todo_id, COALESCE(c.author_id, 0), COALESCE(u.username, ''), c.body, c.created_at, c.updated_at
+		FROM %s AS c
+		LEFT JOIN %s AS u ON u.id = c.author_id
+		WHERE c.todo_id = $1
+		ORDER BY c.id
+		LIMIT $2 OFFSET $3
+	`, todoCommentsTable, usersTable)
+}
+
+func commentByIDQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT c.id, c.todo_id, COALESCE(c.author_id, 0), COALESCE(u.username, ''), c.body, c.created_at, c.updated_at
+		FROM %s AS c
+		LEFT JOIN %s AS u ON u.id = c.author_id
+		WHERE c.todo_id = $1
+		AND c.id = $2
+	`, todoCommentsTable, usersTable)
+}
+
+func createCommentQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (todo_id, author_id, body)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, todoCommentsTable)
+}
+
+func updateCommentQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET body = $2, updated_at = NOW()
+		WHERE id = $1
+	`, todoCommentsTable)
+}
+
+func deleteCommentQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE id = $1
+	`, todoCommentsTable)
+}
+
+// createMentionsQuery links the comment to the list members by their usernames
+func createMentionsQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (comment_id, user_id)
+		SELECT $1, u.id
+		FROM %s AS u
+		INNER JOIN %s AS ul ON ul.user_id = u.id
+		WHERE ul.list_id = $2
+		AND u.username = ANY($3)
+		ON CONFLICT DO NOTHING
+	`, commentMentionsTable, usersTable, usersListsTable)
+}
+
+func deleteMentionsQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE comment_id = $1
+	`, commentMentionsTable)
+}
+
+func mentionsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT cm.comment_id, u.id, u.username
+		FROM %s AS cm
+		INNER JOIN %s AS u ON u.id = cm.user_id
+		WHERE cm.comment_id = ANY($1)
+		ORDER BY u.username
+	`, commentMentionsTable, usersTable)
+}
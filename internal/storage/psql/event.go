@@ -0,0 +1,186 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// eventsLimit is a maximum amount of events which can be returned for resuming
+const eventsLimit = 1000
+
+// Event represents repository of lists changes
+type Event struct {
+	db *sql.DB
+}
+
+// NewEvent returns instance of Event repository
+func NewEvent(db *sql.DB) *Event {
+	return &Event{db}
+}
+
+// eventNotification is the payload of notifications about saved events, it
+// doesn't carry the event itself as notifications are limited to 8000 bytes
+type eventNotification struct {
+	ID     int64 `json:"id"`
+	ListID int   `json:"list_id"`
+}
+
+// SaveEvent stores the event with the next sequence number and notifies
+// listeners of all instances about it after the transaction is committed.
+// Already saved events are ignored. Events are saved by the single outbox relay
+// one by one, so they become visible in the order of their sequence numbers
+func (r *Event) SaveEvent(e core.Event) (core.Event, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return e, err
+	}
+	err = tx.QueryRow(createEventQuery(), e.ID, e.Type, e.ListID, e.TodoID, e.UserID, nullableJSON(e.Data), e.CreatedAt).
+		Scan(&e.Seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return e, tx.Rollback()
+	}
+	if err != nil {
+		return e, rollback(tx, err)
+	}
+	payload, err := json.Marshal(eventNotification{ID: e.ID, ListID: e.ListID})
+	if err != nil {
+		return e, rollback(tx, err)
+	}
+	if _, err := tx.Exec(notifyQuery(), eventsChannel, string(payload)); err != nil {
+		return e, rollback(tx, err)
+	}
+	return e, tx.Commit()
+}
+
+// GetEventsSince returns events of the list which were saved after the given
+// sequence number
+func (r *Event) GetEventsSince(listID int, seq int64) ([]core.Event, error) {
+	var events []core.Event
+	rows, err := r.db.Query(eventsSinceQuery(), listID, seq, eventsLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetLastEventSeq returns sequence number of the latest event of the list or
+// zero if there are none
+func (r *Event) GetLastEventSeq(listID int) (int64, error) {
+	var seq int64
+	err := r.db.QueryRow(lastEventSeqQuery(), listID).Scan(&seq)
+	return seq, err
+}
+
+// ListenEvents holds a dedicated connection subscribed to the events channel
+// and passes every received event to fn until the context is canceled
+func (r *Event) ListenEvents(ctx context.Context, fn func(core.Event)) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("listening is supported only by pgx driver")
+		}
+		pgConn := c.Conn()
+		if _, err := pgConn.Exec(ctx, "LISTEN "+eventsChannel); err != nil {
+			return err
+		}
+		for {
+			n, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			var en eventNotification
+			if err := json.Unmarshal([]byte(n.Payload), &en); err != nil {
+				continue
+			}
+			e, err := scanEvent(r.db.QueryRowContext(ctx, eventByIDQuery(), en.ID))
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			fn(e)
+		}
+	})
+}
+
+func scanEvent(row rowScanner) (core.Event, error) {
+	var (
+		e    core.Event
+		data []byte
+	)
+	err := row.Scan(&e.ID, &e.Seq, &e.Type, &e.ListID, &e.TodoID, &e.UserID, &data, &e.CreatedAt)
+	e.Data = data
+	return e, err
+}
+
+func nullableJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+func createEventQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (id, type, list_id, todo_id, user_id, data, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7)
+		ON CONFLICT (id) DO NOTHING
+		RETURNING seq
+	`, listEventsTable)
+}
+
+func notifyQuery() string {
+	return `--sql
+		SELECT pg_notify($1, $2)
+	`
+}
+
+func eventByIDQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, seq, type, list_id, COALESCE(todo_id, 0), user_id, data, created_at
+		FROM %s
+		WHERE id = $1
+	`, listEventsTable)
+}
+
+func eventsSinceQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, seq, type, list_id, COALESCE(todo_id, 0), user_id, data, created_at
+		FROM %s
+		WHERE list_id = $1
+		AND seq > $2
+		ORDER BY seq
+		LIMIT $3
+	`, listEventsTable)
+}
+
+func lastEventSeqQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT COALESCE(MAX(seq), 0)
+		FROM %s
+		WHERE list_id = $1
+	`, listEventsTable)
+}
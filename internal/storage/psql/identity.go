@@ -0,0 +1,161 @@
+package psql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Identity represents repository of users' external identities
+type Identity struct {
+	db *sql.DB
+}
+
+// NewIdentity returns instance of Identity repository
+func NewIdentity(db *sql.DB) *Identity {
+	return &Identity{db}
+}
+
+// maxLinkAttempts limits retries of the sign in which lost the race to the
+// concurrent sign in of the same identity or username
+const maxLinkAttempts = 3
+
+// GetOrCreateUser returns the User linked to the identity. Unknown identity with
+// verified email is linked to the User who has verified the same email, other
+// identities are linked to the new User, which gets username with a random
+// suffix when the username is already taken
+func (r *Identity) GetOrCreateUser(u core.User, i core.Identity) (core.User, error) {
+	for attempt := 1; ; attempt++ {
+		user, ok, err := r.getOrCreateUser(u, i)
+		if err != nil || ok {
+			return user, err
+		}
+		if attempt == maxLinkAttempts {
+			return user, errors.New("identity is being linked concurrently")
+		}
+	}
+}
+
+// getOrCreateUser returns false when the concurrent sign in has linked the
+// identity or taken the username first, the transaction is rolled back then,
+// so the next attempt finds the linked identity or picks another username
+func (r *Identity) getOrCreateUser(u core.User, i core.Identity) (core.User, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return u, false, err
+	}
+	var user core.User
+	err = tx.QueryRow(userByIdentityQuery(), i.Issuer, i.Subject, nullableString(i.Email)).
+		Scan(&user.ID, &user.Name, &user.Username)
+	if err == nil {
+		return user, true, tx.Commit()
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return user, false, rollback(tx, err)
+	}
+	existing := false
+	if i.Email != "" {
+		err = tx.QueryRow(userByVerifiedEmailQuery(), i.Email).Scan(&user.ID, &user.Name, &user.Username)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return user, false, rollback(tx, err)
+		}
+		existing = err == nil
+	}
+	if !existing {
+		err = tx.QueryRow(provisionUserQuery(), u.Name, u.Username, nullableString(i.Email)).
+			Scan(&user.ID, &user.Name, &user.Username)
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, false, tx.Rollback()
+		}
+		if err != nil {
+			return user, false, rollback(tx, err)
+		}
+	}
+	created, err := affected(tx.Exec(createIdentityQuery(), user.ID, i.Issuer, i.Subject, nullableString(i.Email)))
+	if err != nil {
+		return user, false, rollback(tx, err)
+	}
+	if !created {
+		return user, false, tx.Rollback()
+	}
+	if !existing {
+		if err := createPersonalWorkspace(tx, user.ID); err != nil {
+			return user, false, rollback(tx, err)
+		}
+		if err := createUserSettings(tx, user.ID); err != nil {
+			return user, false, rollback(tx, err)
+		}
+	}
+	return user, true, tx.Commit()
+}
+
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// userByIdentityQuery returns the linked user and refreshes email of the identity
+func userByIdentityQuery() string {
+	return fmt.Sprintf(`--sql
+		WITH identity AS (
+			UPDATE %s
+			SET email = COALESCE($3, email)
+			WHERE issuer = $1
+			AND subject = $2
+			RETURNING user_id
+		)
+		SELECT u.id, u.name, u.username
+		FROM %s AS u
+		INNER JOIN identity AS i ON i.user_id = u.id
+	`, userIdentitiesTable, usersTable)
+}
+
+// userByVerifiedEmailQuery returns the user who has verified the email, so
+// accounts aren't taken over through emails which their owners didn't confirm
+func userByVerifiedEmailQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, name, username
+		FROM %s
+		WHERE LOWER(email) = LOWER($1)
+		AND email_verified_at IS NOT NULL
+		FOR UPDATE
+	`, usersTable)
+}
+
+// provisionUserQuery creates user without password, so the user can't sign in
+// with password, since password hashes are never empty. The verified email is
+// saved unless another user has it, the user isn't created when the concurrent
+// sign in has taken the username or the email first
+func provisionUserQuery() string {
+	return fmt.Sprintf(`--sql
+		WITH e AS (
+			SELECT CASE WHEN NOT EXISTS (SELECT 1 FROM %[1]s WHERE LOWER(email) = LOWER($3::VARCHAR))
+				THEN $3::VARCHAR
+			END AS email
+		)
+		INSERT INTO %[1]s (name, username, password_hash, email, email_verified_at)
+		SELECT $1::VARCHAR,
+			CASE WHEN EXISTS (SELECT 1 FROM %[1]s WHERE username = $2::VARCHAR)
+				THEN $2::VARCHAR || '-' || substr(md5(random()::text), 1, 6)
+				ELSE $2::VARCHAR
+			END,
+			'',
+			e.email,
+			CASE WHEN e.email IS NOT NULL THEN NOW() END
+		FROM e
+		ON CONFLICT DO NOTHING
+		RETURNING id, name, username
+	`, usersTable)
+}
+
+func createIdentityQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (user_id, issuer, subject, email)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (issuer, subject) DO NOTHING
+	`, userIdentitiesTable)
+}
@@ -0,0 +1,64 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LoginLockedUntil returns the time until which sign in of the user is locked,
+// it's zero when the user isn't locked
+func (r *Auth) LoginLockedUntil(username string) (time.Time, error) {
+	var until sql.NullTime
+	err := r.db.QueryRow(loginLockedUntilQuery(), username).Scan(&until)
+	return until.Time, err
+}
+
+// RecordLoginFailure counts the failed sign in of the user and returns the
+// number of failures in a row
+func (r *Auth) RecordLoginFailure(username string) (int, error) {
+	var failures int
+	err := r.db.QueryRow(recordLoginFailureQuery(), username).Scan(&failures)
+	return failures, err
+}
+
+// LockLogin locks sign in of the user until the time
+func (r *Auth) LockLogin(username string, until time.Time) error {
+	_, err := r.db.Exec(lockLoginQuery(), username, until)
+	return err
+}
+
+// ResetLoginFailures forgets failed sign ins of the user after the successful one
+func (r *Auth) ResetLoginFailures(userID int) error {
+	_, err := r.db.Exec(resetLoginFailuresQuery(), userID)
+	return err
+}
+
+func loginLockedUntilQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT locked_until FROM %s
+		WHERE username = $1
+	`, usersTable)
+}
+
+func recordLoginFailureQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET failed_logins = failed_logins + 1
+		WHERE username = $1
+		RETURNING failed_logins
+	`, usersTable)
+}
+
+func lockLoginQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET locked_until = $2
+		WHERE username = $1
+	`, usersTable)
+}
+
+func resetLoginFailuresQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET failed_logins = 0, locked_until = NULL
+		WHERE id = $1 AND (failed_logins > 0 OR locked_until IS NOT NULL)
+	`, usersTable)
+}
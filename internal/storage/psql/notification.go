@@ -0,0 +1,56 @@
+package psql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// notifiedEventsRetention is how long delivered events are remembered, the
+// outbox delivers events again within seconds
+const notifiedEventsRetention = "7 days"
+
+// Notification represents repository of recipients of emails about events
+type Notification struct {
+	db *sql.DB
+}
+
+// NewNotification returns instance of Notification repository
+func NewNotification(db *sql.DB) *Notification {
+	return &Notification{db}
+}
+
+// GetUserByID returns the User by ID
+func (r *Notification) GetUserByID(userID int) (core.User, error) {
+	return scanUser(r.db.QueryRow(userByIDQuery(), userID))
+}
+
+// GetSettings returns preferences of the User
+func (r *Notification) GetSettings(userID int) (core.UserSettings, error) {
+	return scanSettings(r.db.QueryRow(settingsQuery(), userID))
+}
+
+// MarkNotified records that users were notified about the event, it reports
+// false when the event has been recorded already
+func (r *Notification) MarkNotified(eventID int64) (bool, error) {
+	var id int64
+	err := r.db.QueryRow(markNotifiedQuery(), eventID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func markNotifiedQuery() string {
+	return fmt.Sprintf(`--sql
+		WITH expired AS (
+			DELETE FROM %[1]s WHERE created_at < NOW() - INTERVAL '%[2]s'
+		)
+		INSERT INTO %[1]s (event_id)
+		VALUES ($1)
+		ON CONFLICT (event_id) DO NOTHING
+		RETURNING event_id
+	`, notifiedEventsTable, notifiedEventsRetention)
+}
@@ -0,0 +1,119 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Outbox represents repository of events waiting for publishing
+type Outbox struct {
+	db *sql.DB
+}
+
+// NewOutbox returns instance of Outbox repository
+func NewOutbox(db *sql.DB) *Outbox {
+	return &Outbox{db}
+}
+
+// outboxLockKey identifies the advisory lock held by the relaying instance
+const outboxLockKey = 0x6f7574626f78 // "outbox"
+
+// ProcessOutbox locks a batch of the oldest events, passes them to fn in order and
+// removes the ones which were handled. Processing stops at the first failed event,
+// so it's retried later. Only one instance relays events at a time, others skip
+// the batch, so events are published in the single order
+func (r *Outbox) ProcessOutbox(limit int, fn func(core.Event) error) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	var locked bool
+	if err := tx.QueryRow(tryOutboxLockQuery(), outboxLockKey).Scan(&locked); err != nil {
+		return 0, rollback(tx, err)
+	}
+	if !locked {
+		return 0, tx.Rollback()
+	}
+	events, err := lockOutbox(tx, limit)
+	if err != nil {
+		return 0, rollback(tx, err)
+	}
+	var (
+		handled = make([]int64, 0, len(events))
+		fnErr   error
+	)
+	for _, e := range events {
+		if fnErr = fn(e); fnErr != nil {
+			break
+		}
+		handled = append(handled, e.ID)
+	}
+	if len(handled) > 0 {
+		if _, err := tx.Exec(deleteOutboxQuery(), handled); err != nil {
+			return 0, rollback(tx, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(handled), fnErr
+}
+
+func lockOutbox(tx *sql.Tx, limit int) ([]core.Event, error) {
+	var events []core.Event
+	rows, err := tx.Query(lockOutboxQuery(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			e    core.Event
+			data []byte
+		)
+		if err := rows.Scan(&e.ID, &e.Type, &e.ListID, &e.TodoID, &e.UserID, &data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Data = data
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// insertOutbox saves the event within the transaction of the change which caused it
+func insertOutbox(tx *sql.Tx, e core.Event) error {
+	_, err := tx.Exec(insertOutboxQuery(), e.Type, e.ListID, e.TodoID, e.UserID, nullableJSON(e.Data), e.CreatedAt)
+	return err
+}
+
+func insertOutboxQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (type, list_id, todo_id, user_id, data, created_at)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6)
+	`, outboxTable)
+}
+
+func tryOutboxLockQuery() string {
+	return `--sql
+		SELECT pg_try_advisory_xact_lock($1)
+	`
+}
+
+func lockOutboxQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, type, list_id, COALESCE(todo_id, 0), user_id, data, created_at
+		FROM %s
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE
+	`, outboxTable)
+}
+
+func deleteOutboxQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s
+		WHERE id = ANY($1)
+	`, outboxTable)
+}
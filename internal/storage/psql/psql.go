@@ -11,13 +11,43 @@ import (
 )
 
 const (
-	usersTable      = "users"
-	todoListsTable  = "todo_lists"
-	usersListsTable = "users_lists"
-	todoItemsTable  = "todo_items"
-	listsItemsTable = "lists_items"
+	usersTable           = "users"
+	todoListsTable       = "todo_lists"
+	usersListsTable      = "users_lists" // view of lists available through workspace memberships
+	todoItemsTable       = "todo_items"
+	listsItemsTable      = "lists_items"
+	listEventsTable      = "list_events"
+	webhooksTable        = "webhooks"
+	outboxTable          = "outbox"
+	syncTombstonesTable  = "sync_tombstones"
+	accessTokensTable    = "access_tokens"
+	userIdentitiesTable  = "user_identities"
+	userTOTPTable        = "user_totp"
+	oneTimeTokensTable   = "one_time_tokens"
+	rateLimitsTable      = "rate_limits"
+	adminAuditLogTable   = "admin_audit_log"
+	workspacesTable      = "workspaces"
+	todoCommentsTable    = "todo_comments"
+	commentMentionsTable = "comment_mentions"
+	todoAttachmentsTable = "todo_attachments"
+	blobDeletionsTable   = "blob_deletions"
+	userSettingsTable    = "user_settings"
+	listTemplatesTable   = "list_templates"
+	templateTodosTable   = "template_todos"
+	listShareLinksTable  = "list_share_links"
+	listStatusesTable    = "list_statuses"
+	notifiedEventsTable  = "notified_events"
+	loginChallengesTable = "login_challenges"
+
+	webhookDeliveriesTable = "webhook_deliveries"
+	userRecoveryCodesTable = "user_recovery_codes"
+	workspaceMembersTable  = "workspace_members"
+	workspaceInvitesTable  = "workspace_invites"
 )
 
+// eventsChannel is a name of the channel used for notifying about lists changes
+const eventsChannel = "list_events"
+
 // Config represents all required fields for connecting to postgres db
 type Config struct {
 	Host     string
@@ -29,11 +59,33 @@ type Config struct {
 	Logger   *zap.Logger
 }
 
+// rowScanner is implemented by both sql.Row and sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 // Storage contains all implemented repositories
 type Storage struct {
-	Auth     *Auth
-	TodoList *TodoList
-	TodoItem *TodoItem
+	Auth         *Auth
+	TodoList     *TodoList
+	TodoItem     *TodoItem
+	Event        *Event
+	Webhook      *Webhook
+	Outbox       *Outbox
+	Sync         *Sync
+	Token        *AccessToken
+	Identity     *Identity
+	RateLimit    *RateLimit
+	Admin        *Admin
+	Workspace    *Workspace
+	Comment      *Comment
+	Attachment   *Attachment
+	Settings     *Settings
+	Template     *Template
+	ShareLink    *ShareLink
+	Stats        *Stats
+	Status       *Status
+	Notification *Notification
 }
 
 // String returns connection string from config
@@ -67,8 +119,33 @@ func NewDB(cfg Config) (*sql.DB, error) {
 // NewStorage returns all implemented repositories
 func NewStorage(db *sql.DB) *Storage {
 	return &Storage{
-		Auth:     NewAuth(db),
-		TodoList: NewTodoList(db),
-		TodoItem: NewTodoItem(db),
+		Auth:         NewAuth(db),
+		TodoList:     NewTodoList(db),
+		TodoItem:     NewTodoItem(db),
+		Event:        NewEvent(db),
+		Webhook:      NewWebhook(db),
+		Outbox:       NewOutbox(db),
+		Sync:         NewSync(db),
+		Token:        NewAccessToken(db),
+		Identity:     NewIdentity(db),
+		RateLimit:    NewRateLimit(db),
+		Admin:        NewAdmin(db),
+		Workspace:    NewWorkspace(db),
+		Comment:      NewComment(db),
+		Attachment:   NewAttachment(db),
+		Settings:     NewSettings(db),
+		Template:     NewTemplate(db),
+		ShareLink:    NewShareLink(db),
+		Stats:        NewStats(db),
+		Status:       NewStatus(db),
+		Notification: NewNotification(db),
+	}
+}
+
+// rollback aborts the transaction and returns the error which caused it
+func rollback(tx *sql.Tx, err error) error {
+	if rbErr := tx.Rollback(); rbErr != nil {
+		return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
 	}
+	return err
 }
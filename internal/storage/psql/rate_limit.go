@@ -0,0 +1,78 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/ratelimit"
+)
+
+// rateLimitPruneInterval is how often the buckets which are full again are removed
+const rateLimitPruneInterval = time.Minute
+
+// RateLimit represents repository of rate limiting buckets, so the limits are
+// shared by all instances of the app
+type RateLimit struct {
+	db *sql.DB
+
+	mu       sync.Mutex
+	prunedAt time.Time
+}
+
+// NewRateLimit returns instance of rate limit repository
+func NewRateLimit(db *sql.DB) *RateLimit {
+	return &RateLimit{db: db}
+}
+
+// Take takes the request from the bucket of the key. The bucket is refilled and
+// taken by a single statement, so concurrent requests can't exceed the limit
+func (r *RateLimit) Take(ctx context.Context, key string, rule ratelimit.Rule) (ratelimit.Result, error) {
+	if err := r.prune(ctx); err != nil {
+		return ratelimit.Result{}, err
+	}
+	var (
+		tokens  float64
+		allowed bool
+	)
+	err := r.db.QueryRowContext(ctx, takeRateLimitQuery(), key, rule.Limit, rule.Rate(), rule.Period.Seconds()).
+		Scan(&tokens, &allowed)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return rule.Result(tokens, allowed), nil
+}
+
+func (r *RateLimit) prune(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.prunedAt) < rateLimitPruneInterval {
+		return nil
+	}
+	r.prunedAt = time.Now()
+	_, err := r.db.ExecContext(ctx, pruneRateLimitsQuery())
+	return err
+}
+
+func takeRateLimitQuery() string {
+	refill := `LEAST($2::DOUBLE PRECISION,
+		b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at)::DOUBLE PRECISION * $3::DOUBLE PRECISION)`
+	return fmt.Sprintf(`--sql
+		INSERT INTO %[1]s AS b (key, tokens, allowed, updated_at, expires_at)
+		VALUES ($1, $2::DOUBLE PRECISION - 1, TRUE, NOW(), NOW() + MAKE_INTERVAL(secs => $4::DOUBLE PRECISION))
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = %[2]s - CASE WHEN %[2]s >= 1 THEN 1 ELSE 0 END,
+			allowed = %[2]s >= 1,
+			updated_at = NOW(),
+			expires_at = NOW() + MAKE_INTERVAL(secs => $4::DOUBLE PRECISION)
+		RETURNING tokens, allowed
+	`, rateLimitsTable, refill)
+}
+
+func pruneRateLimitsQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE expires_at < NOW()
+	`, rateLimitsTable)
+}
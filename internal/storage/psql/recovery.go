@@ -0,0 +1,125 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// GetUserByEmail returns user from DB by email, case is ignored
+func (r *Auth) GetUserByEmail(email string) (core.User, error) {
+	return scanUser(r.db.QueryRow(userByEmailQuery(), email))
+}
+
+// CreateOneTimeToken stores the token, unused tokens of the same purpose are
+// invalidated, so only the latest email works
+func (r *Auth) CreateOneTimeToken(t core.OneTimeToken) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(invalidateOneTimeTokensQuery(), t.UserID, t.Purpose); err != nil {
+		return rollback(tx, err)
+	}
+	if _, err := tx.Exec(createOneTimeTokenQuery(), t.UserID, t.Purpose, t.Hash, t.Email, t.ExpiresAt); err != nil {
+		return rollback(tx, err)
+	}
+	return tx.Commit()
+}
+
+// VerifyEmail uses the verification token and marks the email as verified, if
+// the user hasn't changed it since the token was sent
+func (r *Auth) VerifyEmail(hash string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	var (
+		userID int
+		email  string
+	)
+	if err := tx.QueryRow(useOneTimeTokenQuery(), hash, core.TokenEmailVerification).Scan(&userID, &email); err != nil {
+		return rollback(tx, err)
+	}
+	res, err := tx.Exec(verifyEmailQuery(), userID, email)
+	if err != nil {
+		return rollback(tx, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return rollback(tx, sql.ErrNoRows)
+	}
+	return tx.Commit()
+}
+
+// ResetPassword uses the reset token and replaces password of its user, all
+// tokens of the user are revoked like on password change and the account is
+// unlocked
+func (r *Auth) ResetPassword(hash, passwordHash string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	var (
+		userID int
+		email  string
+	)
+	if err := tx.QueryRow(useOneTimeTokenQuery(), hash, core.TokenPasswordReset).Scan(&userID, &email); err != nil {
+		return rollback(tx, err)
+	}
+	if _, err := tx.Exec(updatePasswordQuery(), userID, passwordHash); err != nil {
+		return rollback(tx, err)
+	}
+	if _, err := tx.Exec(deleteUserAccessTokensQuery(), userID); err != nil {
+		return rollback(tx, err)
+	}
+	if _, err := tx.Exec(resetLoginFailuresQuery(), userID); err != nil {
+		return rollback(tx, err)
+	}
+	return tx.Commit()
+}
+
+func userByEmailQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT %s FROM %s
+		WHERE LOWER(email) = LOWER($1)
+	`, userColumns, usersTable)
+}
+
+func invalidateOneTimeTokensQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET used_at = NOW()
+		WHERE user_id = $1
+		AND purpose = $2
+		AND used_at IS NULL
+	`, oneTimeTokensTable)
+}
+
+func createOneTimeTokenQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (user_id, purpose, token_hash, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, oneTimeTokensTable)
+}
+
+func useOneTimeTokenQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET used_at = NOW()
+		WHERE token_hash = $1
+		AND purpose = $2
+		AND used_at IS NULL
+		AND expires_at > NOW()
+		RETURNING user_id, email
+	`, oneTimeTokensTable)
+}
+
+func verifyEmailQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET email_verified_at = NOW()
+		WHERE id = $1
+		AND LOWER(email) = LOWER($2)
+	`, usersTable)
+}
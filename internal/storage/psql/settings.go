@@ -0,0 +1,66 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Settings represents repository of users' preferences
+type Settings struct {
+	db *sql.DB
+}
+
+// NewSettings returns instance of Settings repository
+func NewSettings(db *sql.DB) *Settings {
+	return &Settings{db}
+}
+
+// GetSettings returns preferences of the User
+func (r *Settings) GetSettings(userID int) (core.UserSettings, error) {
+	return scanSettings(r.db.QueryRow(settingsQuery(), userID))
+}
+
+// UpdateSettings replaces preferences of the User
+func (r *Settings) UpdateSettings(userID int, s core.UserSettings) (core.UserSettings, error) {
+	return scanSettings(r.db.QueryRow(updateSettingsQuery(), userID, s.TimeZone, s.WeekStart, s.DefaultListID,
+		s.Locale, s.Notifications.Assigned, s.Notifications.Mentioned))
+}
+
+// createUserSettings creates default preferences of the new user
+func createUserSettings(tx *sql.Tx, userID int) error {
+	_, err := tx.Exec(createSettingsQuery(), userID)
+	return err
+}
+
+func scanSettings(row rowScanner) (core.UserSettings, error) {
+	var s core.UserSettings
+	err := row.Scan(&s.TimeZone, &s.WeekStart, &s.DefaultListID, &s.Locale,
+		&s.Notifications.Assigned, &s.Notifications.Mentioned, &s.UpdatedAt)
+	return s, err
+}
+
+func settingsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT time_zone, week_start, COALESCE(default_list_id, 0), locale, notify_assigned, notify_mentioned, updated_at
+		FROM %s
+		WHERE user_id = $1
+	`, userSettingsTable)
+}
+
+func createSettingsQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (user_id) VALUES ($1)
+	`, userSettingsTable)
+}
+
+func updateSettingsQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET time_zone = $2, week_start = $3, default_list_id = NULLIF($4, 0), locale = $5,
+			notify_assigned = $6, notify_mentioned = $7, updated_at = NOW()
+		WHERE user_id = $1
+		RETURNING time_zone, week_start, COALESCE(default_list_id, 0), locale, notify_assigned, notify_mentioned, updated_at
+	`, userSettingsTable)
+}
@@ -0,0 +1,147 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// ShareLink represents repository of public links to lists
+type ShareLink struct {
+	db *sql.DB
+}
+
+// NewShareLink returns instance of ShareLink repository
+func NewShareLink(db *sql.DB) *ShareLink {
+	return &ShareLink{db}
+}
+
+// GetShareLinks returns links of the list, expired ones are included
+func (r *ShareLink) GetShareLinks(listID int) ([]core.ShareLink, error) {
+	var links []core.ShareLink
+	rows, err := r.db.Query(shareLinksQuery(), listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		l, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *ShareLink) GetShareLink(listID, linkID int) (core.ShareLink, error) {
+	return scanShareLink(r.db.QueryRow(shareLinkQuery(), listID, linkID))
+}
+
+// GetShareLinkByHash returns the link by hash of its token
+func (r *ShareLink) GetShareLinkByHash(hash string) (core.ShareLink, error) {
+	return scanShareLink(r.db.QueryRow(shareLinkByHashQuery(), hash))
+}
+
+func (r *ShareLink) CreateShareLink(l core.ShareLink) (core.ShareLink, error) {
+	return scanShareLink(r.db.QueryRow(createShareLinkQuery(), l.ListID, l.CreatedBy, l.Hash, l.PasswordHash, l.ExpiresAt))
+}
+
+// DeleteShareLink revokes the link
+func (r *ShareLink) DeleteShareLink(linkID int) error {
+	ok, err := affected(r.db.Exec(deleteShareLinkQuery(), linkID))
+	if err == nil && !ok {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// GetSharedList returns the list together with its todos regardless of users'
+// access, it's used only for lists shared by links
+func (r *ShareLink) GetSharedList(listID int) (core.ListWithTodos, error) {
+	var list core.ListWithTodos
+	l, err := scanList(r.db.QueryRow(sharedListQuery(), listID))
+	if err != nil {
+		return list, err
+	}
+	list.Todolist = l
+	rows, err := r.db.Query(allTodosQuery(), listID)
+	if err != nil {
+		return list, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		todo, err := scanTodoWithComments(rows)
+		if err != nil {
+			return list, err
+		}
+		list.Todos = append(list.Todos, todo)
+	}
+	return list, rows.Err()
+}
+
+func scanShareLink(row rowScanner) (core.ShareLink, error) {
+	var (
+		l       core.ShareLink
+		expires sql.NullTime
+	)
+	err := row.Scan(&l.ID, &l.ListID, &l.CreatedBy, &l.Hash, &l.PasswordHash, &expires, &l.CreatedAt)
+	if expires.Valid {
+		l.ExpiresAt = &expires.Time
+	}
+	l.HasPassword = l.PasswordHash != ""
+	return l, err
+}
+
+const shareLinkColumns = `id, list_id, COALESCE(created_by, 0), token_hash, COALESCE(password_hash, ''), expires_at, created_at`
+
+func shareLinksQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT %s
+		FROM %s
+		WHERE list_id = $1
+		ORDER BY id
+	`, shareLinkColumns, listShareLinksTable)
+}
+
+func shareLinkQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT %s
+		FROM %s
+		WHERE list_id = $1 AND id = $2
+	`, shareLinkColumns, listShareLinksTable)
+}
+
+func shareLinkByHashQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT %s
+		FROM %s
+		WHERE token_hash = $1
+	`, shareLinkColumns, listShareLinksTable)
+}
+
+func createShareLinkQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (list_id, created_by, token_hash, password_hash, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		RETURNING %s
+	`, listShareLinksTable, shareLinkColumns)
+}
+
+func deleteShareLinkQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE id = $1
+	`, listShareLinksTable)
+}
+
+func sharedListQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, workspace_id, title, description, updated_at
+		FROM %s
+		WHERE id = $1
+	`, todoListsTable)
+}
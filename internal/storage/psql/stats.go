@@ -0,0 +1,124 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Stats represents repository of users' productivity reports
+type Stats struct {
+	db *sql.DB
+}
+
+// NewStats returns instance of Stats repository
+func NewStats(db *sql.DB) *Stats {
+	return &Stats{db}
+}
+
+// GetDailyCounts returns counts of todos created and completed in lists of the
+// User between from and to, days are taken in the time zone. Days without
+// todos are omitted
+func (r *Stats) GetDailyCounts(userID int, timeZone string, from, to time.Time) ([]core.DayStats, error) {
+	var days []core.DayStats
+	rows, err := r.db.Query(dailyCountsQuery(), userID, timeZone, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d core.DayStats
+		if err := rows.Scan(&d.Date, &d.Created, &d.Completed); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// GetAvgCompletion returns average seconds from creation to completion of
+// todos completed between from and to
+func (r *Stats) GetAvgCompletion(userID int, from, to time.Time) (float64, error) {
+	var avg float64
+	err := r.db.QueryRow(avgCompletionQuery(), userID, from, to).Scan(&avg)
+	return avg, err
+}
+
+// GetListProgress returns counts of all, done and overdue todos of every list
+// available to the User
+func (r *Stats) GetListProgress(userID int) ([]core.ListProgress, error) {
+	var lists []core.ListProgress
+	rows, err := r.db.Query(listProgressQuery(), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l core.ListProgress
+		if err := rows.Scan(&l.ListID, &l.Title, &l.Total, &l.Done, &l.Overdue); err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// dailyCountsQuery counts creations and completions in a single pass over todos
+// which were created or completed in the range
+func dailyCountsQuery() string {
+	return fmt.Sprintf(`--sql
+		WITH todos AS (
+			SELECT ti.created_at, ti.completed_at
+			FROM %s AS ti
+			INNER JOIN %s AS li ON li.item_id = ti.id
+			INNER JOIN %s AS ul ON ul.list_id = li.list_id
+			WHERE ul.user_id = $1
+			AND ((ti.created_at >= $3 AND ti.created_at < $4) OR (ti.completed_at >= $3 AND ti.completed_at < $4))
+		), events AS (
+			SELECT created_at AS at, 1 AS created, 0 AS completed FROM todos
+			WHERE created_at >= $3 AND created_at < $4
+			UNION ALL
+			SELECT completed_at, 0, 1 FROM todos
+			WHERE completed_at >= $3 AND completed_at < $4
+		)
+		SELECT TO_CHAR(at AT TIME ZONE $2, 'YYYY-MM-DD') AS day, SUM(created), SUM(completed)
+		FROM events
+		GROUP BY day
+		ORDER BY day
+	`, todoItemsTable, listsItemsTable, usersListsTable)
+}
+
+func avgCompletionQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT COALESCE(AVG(EXTRACT(EPOCH FROM ti.completed_at - ti.created_at)), 0)
+		FROM %s AS ti
+		INNER JOIN %s AS li ON li.item_id = ti.id
+		INNER JOIN %s AS ul ON ul.list_id = li.list_id
+		WHERE ul.user_id = $1
+		AND ti.completed_at >= $2 AND ti.completed_at < $3
+	`, todoItemsTable, listsItemsTable, usersListsTable)
+}
+
+func listProgressQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT tl.id, tl.title,
+			COUNT(ti.id),
+			COUNT(ti.id) FILTER (WHERE ti.done),
+			COUNT(ti.id) FILTER (WHERE NOT ti.done AND ti.due < NOW())
+		FROM %s AS tl
+		INNER JOIN %s AS ul ON ul.list_id = tl.id
+		LEFT JOIN %s AS li ON li.list_id = tl.id
+		LEFT JOIN %s AS ti ON ti.id = li.item_id
+		WHERE ul.user_id = $1
+		GROUP BY tl.id, tl.title
+		ORDER BY tl.id
+	`, todoListsTable, usersListsTable, listsItemsTable, todoItemsTable)
+}
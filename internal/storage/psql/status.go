@@ -0,0 +1,260 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Status represents repository of boards' columns
+type Status struct {
+	db *sql.DB
+}
+
+// NewStatus returns instance of Status repository
+func NewStatus(db *sql.DB) *Status {
+	return &Status{db}
+}
+
+// GetStatuses returns statuses of the List in the order of the board's columns
+func (r *Status) GetStatuses(listID int) ([]core.ListStatus, error) {
+	rows, err := r.db.Query(statusesQuery(), listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var statuses []core.ListStatus
+	for rows.Next() {
+		s, err := scanStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, rows.Err()
+}
+
+// enterStatus checks that the todo fits WIP limit of the status it's moved to.
+// The status stays locked until the end of the transaction, so concurrent
+// moves to it are checked one by one
+func enterStatus(tx *sql.Tx, statusID, todoID int) error {
+	var (
+		name  string
+		limit int
+	)
+	if err := tx.QueryRow(lockStatusQuery(), statusID).Scan(&name, &limit); err != nil {
+		return err
+	}
+	if limit == 0 {
+		return nil
+	}
+	var count int
+	if err := tx.QueryRow(countStatusTodosQuery(), statusID, todoID).Scan(&count); err != nil {
+		return err
+	}
+	if count >= limit {
+		return fmt.Errorf("%w: %s allows %d todos", core.ErrWIPLimit, name, limit)
+	}
+	return nil
+}
+
+// ReplaceStatuses saves statuses of the List in the given order, statuses with
+// ID are updated, without it are created and missing ones are removed. Todos
+// which lost their status are put to the terminal one when they're done and to
+// the first column otherwise, then done of every todo is synced with its status
+func (r *Status) ReplaceStatuses(userID, listID int, statuses []core.ListStatus) ([]core.ListStatus, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(lockListQuery(), listID); err != nil {
+		return nil, rollback(tx, err)
+	}
+	keep := make([]int, 0, len(statuses))
+	for _, s := range statuses {
+		if s.ID != 0 {
+			keep = append(keep, s.ID)
+		}
+	}
+	changed := make(map[int]core.TodoItem)
+	if err := updateTodos(tx, changed, releaseStatusTodosQuery(), listID, keep); err != nil {
+		return nil, rollback(tx, err)
+	}
+	if _, err := tx.Exec(deleteStatusesQuery(), listID, keep); err != nil {
+		return nil, rollback(tx, err)
+	}
+	// the terminal flag moves between statuses, so it's cleared before the
+	// update to keep the only terminal status of the list
+	if _, err := tx.Exec(clearTerminalQuery(), listID); err != nil {
+		return nil, rollback(tx, err)
+	}
+	saved := make([]core.ListStatus, 0, len(statuses))
+	var terminalID, firstID int
+	for i, s := range statuses {
+		var row *sql.Row
+		if s.ID != 0 {
+			row = tx.QueryRow(updateStatusQuery(), s.ID, listID, s.Name, i, s.Terminal, s.WIPLimit)
+		} else {
+			row = tx.QueryRow(createStatusQuery(), listID, s.Name, i, s.Terminal, s.WIPLimit)
+		}
+		if s, err = scanStatus(row); err != nil {
+			return nil, rollback(tx, err)
+		}
+		if s.Terminal {
+			terminalID = s.ID
+		} else if firstID == 0 {
+			firstID = s.ID
+		}
+		saved = append(saved, s)
+	}
+	if len(saved) != 0 {
+		if err := updateTodos(tx, changed, assignStatusQuery(), listID, terminalID, firstID); err != nil {
+			return nil, rollback(tx, err)
+		}
+	}
+	completed := make(map[int]bool)
+	for id, t := range changed {
+		completed[id] = t.Done
+	}
+	if err := updateTodos(tx, changed, syncStatusDoneQuery(), listID); err != nil {
+		return nil, rollback(tx, err)
+	}
+	ids := make([]int, 0, len(changed))
+	for id := range changed {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		t := changed[id]
+		if err := insertOutbox(tx, core.NewEvent(core.EventTodoUpdated, userID, listID, t.ID, t)); err != nil {
+			return nil, rollback(tx, err)
+		}
+		if wasDone, ok := completed[id]; t.Done && (!ok || !wasDone) {
+			if err := insertOutbox(tx, core.NewEvent(core.EventTodoCompleted, userID, listID, t.ID, t)); err != nil {
+				return nil, rollback(tx, err)
+			}
+		}
+	}
+	return saved, tx.Commit()
+}
+
+// updateTodos runs the query which returns updated todos and collects them to changed
+func updateTodos(tx *sql.Tx, changed map[int]core.TodoItem, query string, args ...interface{}) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t, err := scanTodo(rows)
+		if err != nil {
+			return err
+		}
+		changed[t.ID] = t
+	}
+	return rows.Err()
+}
+
+func scanStatus(row rowScanner) (core.ListStatus, error) {
+	var s core.ListStatus
+	err := row.Scan(&s.ID, &s.ListID, &s.Name, &s.Position, &s.Terminal, &s.WIPLimit, &s.CreatedAt)
+	return s, err
+}
+
+func statusesQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, list_id, name, position, terminal, wip_limit, created_at
+		FROM %s
+		WHERE list_id = $1
+		ORDER BY position, id
+	`, listStatusesTable)
+}
+
+func lockStatusQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT name, wip_limit FROM %s WHERE id = $1 FOR UPDATE
+	`, listStatusesTable)
+}
+
+func countStatusTodosQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT COUNT(*) FROM %s WHERE status_id = $1 AND id <> $2
+	`, todoItemsTable)
+}
+
+func lockListQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id FROM %s WHERE id = $1 FOR UPDATE
+	`, todoListsTable)
+}
+
+func releaseStatusTodosQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s AS ti
+		SET status_id = NULL, updated_at = NOW(), change_xid = txid_current()
+		FROM %s AS ls
+		WHERE ls.id = ti.status_id
+		AND ls.list_id = $1
+		AND NOT ls.id = ANY($2)
+		RETURNING ti.id, ti.title, ti.description, ti.done, ti.due, COALESCE(ti.uid, ''), ti.updated_at, COALESCE(ti.assignee_id, 0), ti.priority, ti.labels, COALESCE(ti.recurrence, ''), COALESCE(ti.status_id, 0)
+	`, todoItemsTable, listStatusesTable)
+}
+
+func deleteStatusesQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s
+		WHERE list_id = $1
+		AND NOT id = ANY($2)
+	`, listStatusesTable)
+}
+
+func clearTerminalQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET terminal = FALSE WHERE list_id = $1
+	`, listStatusesTable)
+}
+
+func createStatusQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (list_id, name, position, terminal, wip_limit)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, list_id, name, position, terminal, wip_limit, created_at
+	`, listStatusesTable)
+}
+
+func updateStatusQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET name = $3, position = $4, terminal = $5, wip_limit = $6
+		WHERE id = $1
+		AND list_id = $2
+		RETURNING id, list_id, name, position, terminal, wip_limit, created_at
+	`, listStatusesTable)
+}
+
+func assignStatusQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s AS ti
+		SET status_id = CASE WHEN ti.done THEN $2 ELSE $3 END, updated_at = NOW(), change_xid = txid_current()
+		FROM %s AS li
+		WHERE li.item_id = ti.id
+		AND li.list_id = $1
+		AND ti.status_id IS NULL
+		RETURNING ti.id, ti.title, ti.description, ti.done, ti.due, COALESCE(ti.uid, ''), ti.updated_at, COALESCE(ti.assignee_id, 0), ti.priority, ti.labels, COALESCE(ti.recurrence, ''), COALESCE(ti.status_id, 0)
+	`, todoItemsTable, listsItemsTable)
+}
+
+func syncStatusDoneQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s AS ti
+		SET done = ls.terminal, completed_at = CASE WHEN ls.terminal THEN COALESCE(ti.completed_at, NOW()) END,
+			updated_at = NOW(), change_xid = txid_current()
+		FROM %s AS ls
+		WHERE ls.id = ti.status_id
+		AND ls.list_id = $1
+		AND ti.done <> ls.terminal
+		RETURNING ti.id, ti.title, ti.description, ti.done, ti.due, COALESCE(ti.uid, ''), ti.updated_at, COALESCE(ti.assignee_id, 0), ti.priority, ti.labels, COALESCE(ti.recurrence, ''), COALESCE(ti.status_id, 0)
+	`, todoItemsTable, listStatusesTable)
+}
@@ -0,0 +1,160 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Sync represents repository of changes for offline clients.
+// Every changed row stores ID of the transaction which changed it, the token
+// is the oldest transaction still running when changes were read. So rows
+// committed later by older transactions are never missed, they're sent again instead
+type Sync struct {
+	db *sql.DB
+}
+
+// NewSync returns instance of Sync repository
+func NewSync(db *sql.DB) *Sync {
+	return &Sync{db}
+}
+
+// GetChanges returns lists and todos of the User which were changed after the
+// state identified by the token, zero token returns all of them without tombstones
+func (r *Sync) GetChanges(userID int, since int64) (core.SyncChanges, error) {
+	var changes core.SyncChanges
+	// all queries have to see the same snapshot which the token describes
+	tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return changes, err
+	}
+	defer tx.Rollback()
+	var xmin int64
+	if err := tx.QueryRow(snapshotXminQuery()).Scan(&xmin); err != nil {
+		return changes, err
+	}
+	changes.Token = strconv.FormatInt(xmin, 10)
+	if changes.Lists, err = changedLists(tx, userID, since); err != nil {
+		return changes, err
+	}
+	if changes.Todos, err = changedTodos(tx, userID, since); err != nil {
+		return changes, err
+	}
+	if since == 0 {
+		return changes, tx.Commit()
+	}
+	rows, err := tx.Query(tombstonesQuery(), userID, since)
+	if err != nil {
+		return changes, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			entity string
+			t      core.Tombstone
+		)
+		if err := rows.Scan(&entity, &t.ID, &t.ListID, &t.DeletedAt); err != nil {
+			return changes, err
+		}
+		if entity == core.EntityList {
+			changes.DeletedLists = append(changes.DeletedLists, t)
+		} else {
+			changes.DeletedTodos = append(changes.DeletedTodos, t)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return changes, err
+	}
+	return changes, tx.Commit()
+}
+
+func changedLists(tx *sql.Tx, userID int, since int64) ([]core.Todolist, error) {
+	var lists []core.Todolist
+	rows, err := tx.Query(changedListsQuery(), userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		list, err := scanList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+func changedTodos(tx *sql.Tx, userID int, since int64) ([]core.SyncTodo, error) {
+	var todos []core.SyncTodo
+	rows, err := tx.Query(changedTodosQuery(), userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t core.SyncTodo
+		if t.TodoItem, err = scanTodo(rows, &t.ListID); err != nil {
+			return nil, err
+		}
+		todos = append(todos, t)
+	}
+	return todos, rows.Err()
+}
+
+// insertTombstones remembers deletion of the entity for every member of the list
+func insertTombstones(tx *sql.Tx, entity string, entityID, listID int) error {
+	_, err := tx.Exec(createTombstonesQuery(), entity, entityID, listID)
+	return err
+}
+
+func snapshotXminQuery() string {
+	return `--sql
+		SELECT txid_snapshot_xmin(txid_current_snapshot())
+	`
+}
+
+func changedListsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT tl.id, tl.workspace_id, tl.title, tl.description, tl.updated_at
+		FROM %s AS tl
+		INNER JOIN %s AS ul ON tl.id = ul.list_id
+		WHERE ul.user_id = $1
+		AND tl.change_xid >= $2
+		ORDER BY tl.id
+	`, todoListsTable, usersListsTable)
+}
+
+func changedTodosQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT li.list_id, ti.id, ti.title, ti.description, ti.done, ti.due, COALESCE(ti.uid, ''), ti.updated_at, COALESCE(ti.assignee_id, 0), ti.priority, ti.labels, COALESCE(ti.recurrence, ''), COALESCE(ti.status_id, 0)
+		FROM %s AS ti
+		INNER JOIN %s AS li ON li.item_id = ti.id
+		INNER JOIN %s AS ul ON ul.list_id = li.list_id
+		WHERE ul.user_id = $1
+		AND ti.change_xid >= $2
+		ORDER BY ti.id
+	`, todoItemsTable, listsItemsTable, usersListsTable)
+}
+
+func tombstonesQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT entity, entity_id, list_id, deleted_at
+		FROM %s
+		WHERE user_id = $1
+		AND change_xid >= $2
+		ORDER BY id
+	`, syncTombstonesTable)
+}
+
+func createTombstonesQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (user_id, entity, entity_id, list_id)
+		SELECT user_id, $1, $2, $3
+		FROM %s
+		WHERE list_id = $3
+	`, syncTombstonesTable, usersListsTable)
+}
@@ -0,0 +1,274 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jackc/pgtype"
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Template represents repository of list templates
+type Template struct {
+	db *sql.DB
+}
+
+// NewTemplate returns instance of Template repository
+func NewTemplate(db *sql.DB) *Template {
+	return &Template{db}
+}
+
+// GetTemplates returns templates available to the User, they're own templates
+// and templates visible to the whole workspace
+func (r *Template) GetTemplates(userID int) ([]core.ListTemplate, error) {
+	var templates []core.ListTemplate
+	rows, err := r.db.Query(templatesQuery(), userID, core.TemplateVisibilityWorkspace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadTodos(templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// GetTemplate returns the template together with its todos
+func (r *Template) GetTemplate(templateID int) (core.ListTemplate, error) {
+	return r.getTemplate(templateByIDQuery(), templateID)
+}
+
+// GetTemplateByShareHash returns the template shared by the public link
+func (r *Template) GetTemplateByShareHash(hash string) (core.ListTemplate, error) {
+	return r.getTemplate(templateByShareHashQuery(), hash)
+}
+
+func (r *Template) getTemplate(query string, args ...interface{}) (core.ListTemplate, error) {
+	t, err := scanTemplate(r.db.QueryRow(query, args...))
+	if err != nil {
+		return t, err
+	}
+	templates := []core.ListTemplate{t}
+	err = r.loadTodos(templates)
+	return templates[0], err
+}
+
+// CreateTemplate creates the template together with its todos
+func (r *Template) CreateTemplate(t core.ListTemplate) (core.ListTemplate, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return core.ListTemplate{}, err
+	}
+	created, err := scanTemplate(tx.QueryRow(createTemplateQuery(), t.WorkspaceID, t.AuthorID, t.Title, t.Description, t.Visibility))
+	if err != nil {
+		return core.ListTemplate{}, rollback(tx, err)
+	}
+	if err := insertTemplateTodos(tx, created.ID, t.Todos); err != nil {
+		return core.ListTemplate{}, rollback(tx, err)
+	}
+	created.Todos = t.Todos
+	return created, tx.Commit()
+}
+
+// UpdateTemplate replaces attributes and todos of the template
+func (r *Template) UpdateTemplate(t core.ListTemplate) (core.ListTemplate, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return core.ListTemplate{}, err
+	}
+	updated, err := scanTemplate(tx.QueryRow(updateTemplateQuery(), t.ID, t.Title, t.Description, t.Visibility))
+	if err != nil {
+		return core.ListTemplate{}, rollback(tx, err)
+	}
+	if _, err := tx.Exec(deleteTemplateTodosQuery(), t.ID); err != nil {
+		return core.ListTemplate{}, rollback(tx, err)
+	}
+	if err := insertTemplateTodos(tx, t.ID, t.Todos); err != nil {
+		return core.ListTemplate{}, rollback(tx, err)
+	}
+	updated.Todos = t.Todos
+	return updated, tx.Commit()
+}
+
+// DeleteTemplate removes the template
+func (r *Template) DeleteTemplate(templateID int) error {
+	ok, err := affected(r.db.Exec(deleteTemplateQuery(), templateID))
+	if err == nil && !ok {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// ShareTemplate sets hash of the token of the public link, empty hash revokes
+// the link
+func (r *Template) ShareTemplate(templateID int, hash string) error {
+	ok, err := affected(r.db.Exec(shareTemplateQuery(), templateID, hash))
+	if err == nil && !ok {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// InstantiateTemplate creates the list with todos of the template within a
+// single transaction
+func (r *Template) InstantiateTemplate(userID int, l core.ListWithTodos) (core.ListWithTodos, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return core.ListWithTodos{}, err
+	}
+	list, err := createListWithTodos(tx, userID, l)
+	if err != nil {
+		return core.ListWithTodos{}, rollback(tx, err)
+	}
+	return list, tx.Commit()
+}
+
+// loadTodos fills todos of the templates
+func (r *Template) loadTodos(templates []core.ListTemplate) error {
+	if len(templates) == 0 {
+		return nil
+	}
+	ids := make([]int, len(templates))
+	index := make(map[int]int, len(templates))
+	for i, t := range templates {
+		ids[i] = t.ID
+		index[t.ID] = i
+		templates[i].Todos = make([]core.TemplateTodo, 0)
+	}
+	rows, err := r.db.Query(templateTodosQuery(), ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			templateID int
+			todo       core.TemplateTodo
+			offset     sql.NullInt64
+			labels     pgtype.TextArray
+		)
+		if err := rows.Scan(&templateID, &todo.Title, &todo.Description, &todo.Priority, &labels,
+			&todo.Recurrence, &offset); err != nil {
+			return err
+		}
+		if err := labels.AssignTo(&todo.Labels); err != nil {
+			return err
+		}
+		if offset.Valid {
+			todo.DueOffset = &offset.Int64
+		}
+		i := index[templateID]
+		templates[i].Todos = append(templates[i].Todos, todo)
+	}
+	return rows.Err()
+}
+
+func insertTemplateTodos(tx *sql.Tx, templateID int, todos []core.TemplateTodo) error {
+	for i, t := range todos {
+		if _, err := tx.Exec(createTemplateTodoQuery(), templateID, i, t.Title, t.Description, t.Priority,
+			t.Labels, t.Recurrence, t.DueOffset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanTemplate(row rowScanner) (core.ListTemplate, error) {
+	var t core.ListTemplate
+	err := row.Scan(&t.ID, &t.WorkspaceID, &t.AuthorID, &t.Title, &t.Description, &t.Visibility, &t.ShareHash,
+		&t.CreatedAt, &t.UpdatedAt)
+	t.Shared = t.ShareHash != ""
+	return t, err
+}
+
+const templateColumns = `lt.id, lt.workspace_id, COALESCE(lt.author_id, 0), lt.title, lt.description, lt.visibility,
+	COALESCE(lt.share_hash, ''), lt.created_at, lt.updated_at`
+
+func templatesQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT %s
+		FROM %s AS lt
+		INNER JOIN %s AS wm ON wm.workspace_id = lt.workspace_id AND wm.user_id = $1
+		WHERE lt.author_id = $1 OR lt.visibility = $2
+		ORDER BY lt.id
+	`, templateColumns, listTemplatesTable, workspaceMembersTable)
+}
+
+func templateByIDQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT %s
+		FROM %s AS lt
+		WHERE lt.id = $1
+	`, templateColumns, listTemplatesTable)
+}
+
+func templateByShareHashQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT %s
+		FROM %s AS lt
+		WHERE lt.share_hash = $1
+	`, templateColumns, listTemplatesTable)
+}
+
+func templateTodosQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT template_id, title, description, priority, labels, COALESCE(recurrence, ''), due_offset
+		FROM %s
+		WHERE template_id = ANY($1)
+		ORDER BY template_id, position
+	`, templateTodosTable)
+}
+
+func createTemplateQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s AS lt (workspace_id, author_id, title, description, visibility)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING %s
+	`, listTemplatesTable, templateColumns)
+}
+
+func updateTemplateQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s AS lt
+		SET title = $2, description = $3, visibility = $4, updated_at = NOW()
+		WHERE lt.id = $1
+		RETURNING %s
+	`, listTemplatesTable, templateColumns)
+}
+
+func deleteTemplateQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE id = $1
+	`, listTemplatesTable)
+}
+
+func shareTemplateQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET share_hash = NULLIF($2, ''), updated_at = NOW()
+		WHERE id = $1
+	`, listTemplatesTable)
+}
+
+func createTemplateTodoQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (template_id, position, title, description, priority, labels, recurrence, due_offset)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6::TEXT[], '{}'), NULLIF($7, ''), $8)
+	`, templateTodosTable)
+}
+
+func deleteTemplateTodosQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE template_id = $1
+	`, templateTodosTable)
+}
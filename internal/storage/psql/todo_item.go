@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/jackc/pgtype"
 	"github.com/vbetsun/todo-app/internal/core"
 )
 
@@ -18,25 +19,35 @@ func NewTodoItem(db *sql.DB) *TodoItem {
 	return &TodoItem{db}
 }
 
-// CreateTodo creates new Todo in DB and links it to the List
-func (r *TodoItem) CreateTodo(listID int, t core.TodoItem) (core.TodoItem, error) {
+// CreateTodo creates new Todo in DB and links it to the List, the Todo has to
+// fit WIP limit of its status
+func (r *TodoItem) CreateTodo(userID, listID int, t core.TodoItem) (core.TodoItem, error) {
 	var todo core.TodoItem
 	tx, err := r.db.Begin()
 	if err != nil {
 		return todo, err
 	}
-	err = tx.QueryRow(createTodoQuery(), t.Title, t.Description).Scan(&todo.ID, &todo.Title, &todo.Description)
-	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return todo, fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+	if t.StatusID != 0 {
+		if err := enterStatus(tx, t.StatusID, 0); err != nil {
+			return todo, rollback(tx, err)
 		}
-		return todo, err
+	}
+	todo, err = scanTodo(tx.QueryRow(createTodoQuery(), t.Title, t.Description, t.Done, t.Due, t.UID, t.AssigneeID,
+		t.Priority, t.Labels, t.Recurrence, t.StatusID))
+	if err != nil {
+		return todo, rollback(tx, err)
 	}
 	if _, err := tx.Exec(createListItemsQuery(), listID, todo.ID); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return todo, fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		return todo, rollback(tx, err)
+	}
+	if err := insertOutbox(tx, core.NewEvent(core.EventTodoCreated, userID, listID, todo.ID, todo)); err != nil {
+		return todo, rollback(tx, err)
+	}
+	if todo.AssigneeID != 0 {
+		assignment := core.TodoAssignment{TodoItem: todo}
+		if err := insertOutbox(tx, core.NewEvent(core.EventTodoAssigned, userID, listID, todo.ID, assignment)); err != nil {
+			return todo, rollback(tx, err)
 		}
-		return todo, err
 	}
 	return todo, tx.Commit()
 }
@@ -50,8 +61,8 @@ func (r *TodoItem) GetAllTodos(listID int) ([]core.TodoItem, error) {
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var todo core.TodoItem
-		if err := rows.Scan(&todo.ID, &todo.Title, &todo.Description); err != nil {
+		todo, err := scanTodoWithComments(rows)
+		if err != nil {
 			return nil, err
 		}
 		todos = append(todos, todo)
@@ -62,33 +73,151 @@ func (r *TodoItem) GetAllTodos(listID int) ([]core.TodoItem, error) {
 	return todos, nil
 }
 
+// GetTodosByListIDs returns todos of all given lists grouped by list ID
+func (r *TodoItem) GetTodosByListIDs(listIDs []int) (map[int][]core.TodoItem, error) {
+	todos := make(map[int][]core.TodoItem, len(listIDs))
+	rows, err := r.db.Query(todosByListIDsQuery(), listIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var listID int
+		todo, err := scanTodo(rows, &listID)
+		if err != nil {
+			return nil, err
+		}
+		todos[listID] = append(todos[listID], todo)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// GetAssignedTodos returns todos assigned to the User in all lists available to
+// the User, open todos go first
+func (r *TodoItem) GetAssignedTodos(userID int, done *bool) ([]core.AssignedTodo, error) {
+	var todos []core.AssignedTodo
+	rows, err := r.db.Query(assignedTodosQuery(), userID, done)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t core.AssignedTodo
+		if t.TodoItem, err = scanTodo(rows, &t.ListID); err != nil {
+			return nil, err
+		}
+		todos = append(todos, t)
+	}
+	return todos, rows.Err()
+}
+
 // GetTodoByID returns todo by ID which related to the given list
 func (r *TodoItem) GetTodoByID(listID, todoID int) (core.TodoItem, error) {
-	var todo core.TodoItem
-	err := r.db.QueryRow(todoByIDQuery(), listID, todoID).
-		Scan(&todo.ID, &todo.Title, &todo.Description, &todo.Done)
-	return todo, err
+	return scanTodoWithComments(r.db.QueryRow(todoByIDQuery(), listID, todoID))
+}
+
+// GetTodoByUID returns todo of the list by its calendar UID, todos without
+// UID are found by their ID
+func (r *TodoItem) GetTodoByUID(listID int, uid string) (core.TodoItem, error) {
+	return scanTodo(r.db.QueryRow(todoByUIDQuery(), listID, uid))
 }
 
-// UpdateTodo save Todo changes to the db
-func (r *TodoItem) UpdateTodo(todoID int, data core.UpdateItemData) (core.TodoItem, error) {
-	var t core.TodoItem
+// UpdateTodo save Todo changes to the db, the Todo moved to another status has
+// to fit its WIP limit
+func (r *TodoItem) UpdateTodo(userID, listID, todoID int, data core.UpdateItemData) (core.TodoItem, error) {
+	var (
+		t                core.TodoItem
+		wasDone          bool
+		previousAssignee int
+		previousStatus   int
+	)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return t, err
+	}
+	if err := tx.QueryRow(lockTodoQuery(), todoID).Scan(&wasDone, &previousAssignee, &previousStatus); err != nil {
+		return t, rollback(tx, err)
+	}
+	if data.StatusID != nil && *data.StatusID != 0 && *data.StatusID != previousStatus {
+		if err := enterStatus(tx, *data.StatusID, todoID); err != nil {
+			return t, rollback(tx, err)
+		}
+	}
 	query, args := updateTodo(todoID, data)
-	err := r.db.QueryRow(query, args...).Scan(&t.ID, &t.Title, &t.Description, &t.Done)
-	return t, err
+	if t, err = scanTodoWithComments(tx.QueryRow(query, args...)); err != nil {
+		return t, rollback(tx, err)
+	}
+	if err := insertOutbox(tx, core.NewEvent(core.EventTodoUpdated, userID, listID, t.ID, t)); err != nil {
+		return t, rollback(tx, err)
+	}
+	if t.Done && !wasDone {
+		if err := insertOutbox(tx, core.NewEvent(core.EventTodoCompleted, userID, listID, t.ID, t)); err != nil {
+			return t, rollback(tx, err)
+		}
+	}
+	if t.AssigneeID != previousAssignee {
+		assignment := core.TodoAssignment{TodoItem: t, PreviousAssigneeID: previousAssignee}
+		if err := insertOutbox(tx, core.NewEvent(core.EventTodoAssigned, userID, listID, t.ID, assignment)); err != nil {
+			return t, rollback(tx, err)
+		}
+	}
+	return t, tx.Commit()
 }
 
 // DeleteTodo removes todo from DB by ID
-func (r *TodoItem) DeleteTodo(todoID int) error {
-	_, err := r.db.Exec(deleteTodoById(), todoID)
-	return err
+func (r *TodoItem) DeleteTodo(userID, listID, todoID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	todo, err := scanTodo(tx.QueryRow(deleteTodoById(), todoID))
+	if err != nil {
+		return rollback(tx, err)
+	}
+	if err := insertTombstones(tx, core.EntityTodo, todoID, listID); err != nil {
+		return rollback(tx, err)
+	}
+	if err := insertOutbox(tx, core.NewEvent(core.EventTodoDeleted, userID, listID, todoID, todo)); err != nil {
+		return rollback(tx, err)
+	}
+	return tx.Commit()
+}
+
+// scanTodo reads todo from the row, dest are columns which go before the todo's ones
+func scanTodo(row rowScanner, dest ...interface{}) (core.TodoItem, error) {
+	var (
+		todo   core.TodoItem
+		due    sql.NullTime
+		labels pgtype.TextArray
+	)
+	dest = append(dest, &todo.ID, &todo.Title, &todo.Description, &todo.Done, &due, &todo.UID, &todo.UpdatedAt,
+		&todo.AssigneeID, &todo.Priority, &labels, &todo.Recurrence, &todo.StatusID)
+	if err := row.Scan(dest...); err != nil {
+		return todo, err
+	}
+	if due.Valid {
+		todo.Due = &due.Time
+	}
+	return todo, labels.AssignTo(&todo.Labels)
+}
+
+// scanTodoWithComments reads todo which is preceded by number of its comments
+func scanTodoWithComments(row rowScanner) (core.TodoItem, error) {
+	var count int
+	todo, err := scanTodo(row, &count)
+	todo.CommentCount = count
+	return todo, err
 }
 
 func createTodoQuery() string {
 	return fmt.Sprintf(`--sql
-		INSERT INTO %s (title, description)
-		VALUES ($1, $2)
-		RETURNING id, title, description
+		INSERT INTO %s (title, description, done, due, uid, assignee_id, priority, labels, recurrence, status_id, completed_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, 0), $7, COALESCE($8::TEXT[], '{}'), NULLIF($9, ''), NULLIF($10, 0),
+			CASE WHEN $3 THEN NOW() END)
+		RETURNING id, title, description, done, due, COALESCE(uid, ''), updated_at, COALESCE(assignee_id, 0), priority, labels, COALESCE(recurrence, ''), COALESCE(status_id, 0)
 	`, todoItemsTable)
 }
 
@@ -101,23 +230,74 @@ func createListItemsQuery() string {
 
 func allTodosQuery() string {
 	return fmt.Sprintf(`--sql
-		SELECT ti.id, ti.title, ti.description
+		SELECT COALESCE(tc.count, 0), ti.id, ti.title, ti.description, ti.done, ti.due, COALESCE(ti.uid, ''), ti.updated_at, COALESCE(ti.assignee_id, 0), ti.priority, ti.labels, COALESCE(ti.recurrence, ''), COALESCE(ti.status_id, 0)
 		FROM %s AS ti
 		INNER JOIN %s AS li ON li.item_id = ti.id
+		LEFT JOIN (
+			SELECT c.todo_id, COUNT(*) AS count
+			FROM %s AS c
+			INNER JOIN %s AS cli ON cli.item_id = c.todo_id
+			WHERE cli.list_id = $1
+			GROUP BY c.todo_id
+		) AS tc ON tc.todo_id = ti.id
 		WHERE li.list_id = $1
+	`, todoItemsTable, listsItemsTable, todoCommentsTable, listsItemsTable)
+}
+
+func todosByListIDsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT li.list_id, ti.id, ti.title, ti.description, ti.done, ti.due, COALESCE(ti.uid, ''), ti.updated_at, COALESCE(ti.assignee_id, 0), ti.priority, ti.labels, COALESCE(ti.recurrence, ''), COALESCE(ti.status_id, 0)
+		FROM %s AS ti
+		INNER JOIN %s AS li ON li.item_id = ti.id
+		WHERE li.list_id = ANY($1)
+		ORDER BY ti.id
 	`, todoItemsTable, listsItemsTable)
 }
 
+func assignedTodosQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT li.list_id, ti.id, ti.title, ti.description, ti.done, ti.due, COALESCE(ti.uid, ''), ti.updated_at, COALESCE(ti.assignee_id, 0), ti.priority, ti.labels, COALESCE(ti.recurrence, ''), COALESCE(ti.status_id, 0)
+		FROM %s AS ti
+		INNER JOIN %s AS li ON li.item_id = ti.id
+		INNER JOIN %s AS ul ON ul.list_id = li.list_id
+		WHERE ti.assignee_id = $1
+		AND ul.user_id = $1
+		AND ($2::BOOLEAN IS NULL OR ti.done = $2)
+		ORDER BY ti.done, ti.due NULLS LAST, ti.id
+	`, todoItemsTable, listsItemsTable, usersListsTable)
+}
+
 func todoByIDQuery() string {
 	return fmt.Sprintf(`--sql
-		SELECT ti.id, ti.title, ti.description, ti.done
+		SELECT (SELECT COUNT(*) FROM %s WHERE todo_id = ti.id), ti.id, ti.title, ti.description, ti.done, ti.due, COALESCE(ti.uid, ''), ti.updated_at, COALESCE(ti.assignee_id, 0), ti.priority, ti.labels, COALESCE(ti.recurrence, ''), COALESCE(ti.status_id, 0)
 		FROM %s AS ti
 		INNER JOIN %s AS li ON li.item_id = ti.id
 		WHERE li.list_id = $1
 		AND ti.id = $2
+	`, todoCommentsTable, todoItemsTable, listsItemsTable)
+}
+
+func todoByUIDQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT ti.id, ti.title, ti.description, ti.done, ti.due, COALESCE(ti.uid, ''), ti.updated_at, COALESCE(ti.assignee_id, 0), ti.priority, ti.labels, COALESCE(ti.recurrence, ''), COALESCE(ti.status_id, 0)
+		FROM %s AS ti
+		INNER JOIN %s AS li ON li.item_id = ti.id
+		WHERE li.list_id = $1
+		AND COALESCE(ti.uid, ti.id::text) = $2
+		ORDER BY ti.id
+		LIMIT 1
 	`, todoItemsTable, listsItemsTable)
 }
 
+func lockTodoQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT done, COALESCE(assignee_id, 0), COALESCE(status_id, 0)
+		FROM %s
+		WHERE id = $1
+		FOR UPDATE
+	`, todoItemsTable)
+}
+
 func updateTodo(todoID int, data core.UpdateItemData) (string, []interface{}) {
 	setValues := make([]string, 0)
 	args := make([]interface{}, 0)
@@ -132,19 +312,58 @@ func updateTodo(todoID int, data core.UpdateItemData) (string, []interface{}) {
 		args = append(args, *data.Description)
 		argID++
 	}
+	if data.Done != nil {
+		setValues = append(setValues, fmt.Sprintf("done = $%[1]d, completed_at = CASE WHEN $%[1]d THEN COALESCE(completed_at, NOW()) END", argID))
+		args = append(args, *data.Done)
+		argID++
+	}
+	if data.AssigneeID != nil {
+		setValues = append(setValues, fmt.Sprintf("assignee_id = NULLIF($%d, 0)", argID))
+		args = append(args, *data.AssigneeID)
+		argID++
+	}
+	if data.Priority != nil {
+		setValues = append(setValues, fmt.Sprintf("priority = $%d", argID))
+		args = append(args, *data.Priority)
+		argID++
+	}
+	if data.Labels != nil {
+		setValues = append(setValues, fmt.Sprintf("labels = COALESCE($%d::TEXT[], '{}')", argID))
+		args = append(args, *data.Labels)
+		argID++
+	}
+	if data.Recurrence != nil {
+		setValues = append(setValues, fmt.Sprintf("recurrence = NULLIF($%d, '')", argID))
+		args = append(args, *data.Recurrence)
+		argID++
+	}
+	if data.StatusID != nil {
+		setValues = append(setValues, fmt.Sprintf("status_id = NULLIF($%d, 0)", argID))
+		args = append(args, *data.StatusID)
+		argID++
+	}
+	if data.ClearDue {
+		setValues = append(setValues, "due = NULL")
+	} else if data.Due != nil {
+		setValues = append(setValues, fmt.Sprintf("due = $%d", argID))
+		args = append(args, *data.Due)
+		argID++
+	}
+	setValues = append(setValues, "updated_at = NOW()", "change_xid = txid_current()")
 	setQuery := strings.Join(setValues, ",")
 	args = append(args, todoID)
 	return fmt.Sprintf(`--sql
-		UPDATE %s
+		UPDATE %s AS ti
 		SET %s
 		WHERE id = $%d
-		RETURNING id, title, description, done
-	`, todoItemsTable, setQuery, argID), args
+		RETURNING (SELECT COUNT(*) FROM %s WHERE todo_id = ti.id), id, title, description, done, due, COALESCE(uid, ''), updated_at, COALESCE(assignee_id, 0), priority, labels, COALESCE(recurrence, ''), COALESCE(status_id, 0)
+	`, todoItemsTable, setQuery, argID, todoCommentsTable), args
 }
 
 func deleteTodoById() string {
 	return fmt.Sprintf(`--sql
 		DELETE FROM %s
 		WHERE id = $1
+		RETURNING id, title, description, done, due, COALESCE(uid, ''), updated_at, COALESCE(assignee_id, 0), priority, labels, COALESCE(recurrence, ''), COALESCE(status_id, 0)
 	`, todoItemsTable)
 }
@@ -18,40 +18,91 @@ func NewTodoList(db *sql.DB) *TodoList {
 	return &TodoList{db}
 }
 
-// CreateList creates new List in the DB and links it to the given User
+// CreateList creates new List in the workspace of the List, lists without
+// workspace are created in the personal workspace of the given User
 func (r *TodoList) CreateList(userID int, l core.Todolist) (core.Todolist, error) {
 	var list core.Todolist
 	tx, err := r.db.Begin()
 	if err != nil {
 		return list, err
 	}
-	err = tx.QueryRow(createListQuery(), l.Title, l.Description).Scan(&list.ID, &list.Title, &list.Description)
+	list, err = scanList(tx.QueryRow(createListQuery(), l.Title, l.Description, l.WorkspaceID, userID))
 	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return list, fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		return list, rollback(tx, err)
+	}
+	if err := insertOutbox(tx, core.NewEvent(core.EventListCreated, userID, list.ID, 0, list)); err != nil {
+		return list, rollback(tx, err)
+	}
+	return list, tx.Commit()
+}
+
+// ImportLists creates lists with their todos in the personal workspace of the
+// given User within a single transaction
+func (r *TodoList) ImportLists(userID int, lists []core.ListWithTodos) ([]core.ListWithTodos, error) {
+	created := make([]core.ListWithTodos, 0, len(lists))
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lists {
+		l.WorkspaceID = 0
+		list, err := createListWithTodos(tx, userID, l)
+		if err != nil {
+			return nil, rollback(tx, err)
 		}
-		return list, err
+		created = append(created, list)
+	}
+	return created, tx.Commit()
+}
+
+// createListWithTodos creates the list with its todos in the workspace of the
+// list, zero workspace ID stands for the personal workspace of the User
+func createListWithTodos(tx *sql.Tx, userID int, l core.ListWithTodos) (core.ListWithTodos, error) {
+	todolist, err := scanList(tx.QueryRow(createListQuery(), l.Title, l.Description, l.WorkspaceID, userID))
+	if err != nil {
+		return core.ListWithTodos{}, err
+	}
+	list := core.ListWithTodos{Todolist: todolist, Todos: make([]core.TodoItem, 0, len(l.Todos))}
+	if err := insertOutbox(tx, core.NewEvent(core.EventListCreated, userID, list.ID, 0, list.Todolist)); err != nil {
+		return core.ListWithTodos{}, err
 	}
-	if _, err := tx.Exec(createUsersListQuery(), userID, list.ID); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return list, fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+	for _, t := range l.Todos {
+		todo, err := scanTodo(tx.QueryRow(createTodoQuery(), t.Title, t.Description, t.Done, t.Due, t.UID, 0,
+			t.Priority, t.Labels, t.Recurrence, 0))
+		if err != nil {
+			return core.ListWithTodos{}, err
 		}
-		return list, err
+		if _, err := tx.Exec(createListItemsQuery(), list.ID, todo.ID); err != nil {
+			return core.ListWithTodos{}, err
+		}
+		if err := insertOutbox(tx, core.NewEvent(core.EventTodoCreated, userID, list.ID, todo.ID, todo)); err != nil {
+			return core.ListWithTodos{}, err
+		}
+		list.Todos = append(list.Todos, todo)
 	}
-	return list, tx.Commit()
+	return list, nil
 }
 
-// GetAllLists returns all lists from DB which belong to the given User
+// GetAllLists returns lists of all workspaces the given User is member of
 func (r *TodoList) GetAllLists(userID int) ([]core.Todolist, error) {
+	return r.getLists(allListsQuery(), userID)
+}
+
+// GetWorkspaceLists returns all lists of the workspace
+func (r *TodoList) GetWorkspaceLists(workspaceID int) ([]core.Todolist, error) {
+	return r.getLists(workspaceListsQuery(), workspaceID)
+}
+
+func (r *TodoList) getLists(query string, args ...interface{}) ([]core.Todolist, error) {
 	var lists []core.Todolist
-	rows, err := r.db.Query(allListsQuery(), userID)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var list core.Todolist
-		if err := rows.Scan(&list.ID, &list.Title, &list.Description); err != nil {
+		list, err := scanList(rows)
+		if err != nil {
 			return nil, err
 		}
 		lists = append(lists, list)
@@ -64,52 +115,82 @@ func (r *TodoList) GetAllLists(userID int) ([]core.Todolist, error) {
 
 // GetListByID returns list by ID from DB which belongs to the given User
 func (r *TodoList) GetListByID(userID, listID int) (core.Todolist, error) {
-	var list core.Todolist
-	err := r.db.QueryRow(listByIDQuery(), userID, listID).Scan(&list.ID, &list.Title, &list.Description)
-	return list, err
+	return scanList(r.db.QueryRow(listByIDQuery(), userID, listID))
 }
 
 // UpdateList save changes of list to the DB
-func (r *TodoList) UpdateList(listID int, data core.UpdateListData) (core.Todolist, error) {
+func (r *TodoList) UpdateList(userID, listID int, data core.UpdateListData) (core.Todolist, error) {
 	var list core.Todolist
+	tx, err := r.db.Begin()
+	if err != nil {
+		return list, err
+	}
 	query, args := updateList(listID, data)
-	err := r.db.QueryRow(query, args...).Scan(&list.ID, &list.Title, &list.Description)
-	return list, err
+	if list, err = scanList(tx.QueryRow(query, args...)); err != nil {
+		return list, rollback(tx, err)
+	}
+	if err := insertOutbox(tx, core.NewEvent(core.EventListUpdated, userID, list.ID, 0, list)); err != nil {
+		return list, rollback(tx, err)
+	}
+	return list, tx.Commit()
 }
 
 // DeleteList removes List from DB by ID
-func (r *TodoList) DeleteList(listID int) error {
-	_, err := r.db.Exec(deleteListById(), listID)
-	return err
+func (r *TodoList) DeleteList(userID, listID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	// members are unlinked together with the list, so tombstones go first
+	if err := insertTombstones(tx, core.EntityList, listID, listID); err != nil {
+		return rollback(tx, err)
+	}
+	if _, err := tx.Exec(deleteListById(), listID); err != nil {
+		return rollback(tx, err)
+	}
+	if err := insertOutbox(tx, core.NewEvent(core.EventListDeleted, userID, listID, 0, nil)); err != nil {
+		return rollback(tx, err)
+	}
+	return tx.Commit()
 }
 
-func createUsersListQuery() string {
-	return fmt.Sprintf(`--sql
-		INSERT INTO %s (user_id, list_id) 
-		VALUES ($1, $2) 
-	`, usersListsTable)
+func scanList(row rowScanner) (core.Todolist, error) {
+	var list core.Todolist
+	err := row.Scan(&list.ID, &list.WorkspaceID, &list.Title, &list.Description, &list.UpdatedAt)
+	return list, err
 }
 
+// createListQuery creates the list in the workspace, zero workspace ID stands
+// for the personal workspace of the user
 func createListQuery() string {
 	return fmt.Sprintf(`--sql
-		INSERT INTO %s (title, description) 
-		VALUES ($1, $2) 
-		RETURNING id, title, description
-	`, todoListsTable)
+		INSERT INTO %s (title, description, workspace_id)
+		VALUES ($1, $2, COALESCE(NULLIF($3::INT, 0), (SELECT id FROM %s WHERE personal_user_id = $4)))
+		RETURNING id, workspace_id, title, description, updated_at
+	`, todoListsTable, workspacesTable)
 }
 
 func allListsQuery() string {
 	return fmt.Sprintf(`--sql
-		SELECT tl.id, tl.title, tl.description 
+		SELECT tl.id, tl.workspace_id, tl.title, tl.description, tl.updated_at
 		FROM %s AS tl 
 		INNER JOIN %s AS ul ON tl.id = ul.list_id 
 		WHERE ul.user_id = $1
 	`, todoListsTable, usersListsTable)
 }
 
+func workspaceListsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, workspace_id, title, description, updated_at
+		FROM %s
+		WHERE workspace_id = $1
+		ORDER BY id
+	`, todoListsTable)
+}
+
 func listByIDQuery() string {
 	return fmt.Sprintf(`--sql
-		SELECT tl.id, tl.title, tl.description 
+		SELECT tl.id, tl.workspace_id, tl.title, tl.description, tl.updated_at
 		FROM %s AS tl 
 		INNER JOIN %s AS ul ON tl.id = ul.list_id 
 		WHERE ul.user_id = $1
@@ -131,13 +212,14 @@ func updateList(listID int, data core.UpdateListData) (string, []interface{}) {
 		args = append(args, *data.Description)
 		argID++
 	}
+	setValues = append(setValues, "updated_at = NOW()", "change_xid = txid_current()")
 	setQuery := strings.Join(setValues, ",")
 	args = append(args, listID)
 	return fmt.Sprintf(`--sql
 		UPDATE %s
 		SET %s
 		WHERE id = $%d
-		RETURNING id, title, description
+		RETURNING id, workspace_id, title, description, updated_at
 	`, todoListsTable, setQuery, argID), args
 }
 
@@ -0,0 +1,205 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// GetTOTP returns TOTP secret of the User, zero value means it isn't enrolled
+func (r *Auth) GetTOTP(userID int) (core.TOTP, error) {
+	var t core.TOTP
+	err := r.db.QueryRow(totpQuery(), userID).Scan(&t.Secret, &t.Enabled, &t.LastUsedStep)
+	return t, err
+}
+
+// SaveTOTPSecret stores the new secret which isn't enabled until it's confirmed
+func (r *Auth) SaveTOTPSecret(userID int, secret string) error {
+	_, err := r.db.Exec(saveTOTPSecretQuery(), userID, secret)
+	return err
+}
+
+// EnableTOTP enables the secret and replaces recovery codes of the User
+func (r *Auth) EnableTOTP(userID int, step int64, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(enableTOTPQuery(), userID, step); err != nil {
+		return rollback(tx, err)
+	}
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return rollback(tx, err)
+	}
+	return tx.Commit()
+}
+
+// DisableTOTP removes secret and recovery codes of the User
+func (r *Auth) DisableTOTP(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(deleteTOTPQuery(), userID); err != nil {
+		return rollback(tx, err)
+	}
+	if _, err := tx.Exec(deleteRecoveryCodesQuery(), userID); err != nil {
+		return rollback(tx, err)
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep remembers the step of the used code. It reports false when the
+// code of this or later step was already used
+func (r *Auth) UseTOTPStep(userID int, step int64) (bool, error) {
+	return affected(r.db.Exec(useTOTPStepQuery(), userID, step))
+}
+
+// UseRecoveryCode marks unused recovery code as used and reports whether it existed
+func (r *Auth) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	return affected(r.db.Exec(useRecoveryCodeQuery(), userID, codeHash))
+}
+
+// ReplaceRecoveryCodes removes all recovery codes of the User and stores the new ones
+func (r *Auth) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return rollback(tx, err)
+	}
+	return tx.Commit()
+}
+
+// CreateChallenge records the challenge of the sign in, expired challenges are
+// removed at the same time
+func (r *Auth) CreateChallenge(id string, userID int, expiresAt time.Time) error {
+	_, err := r.db.Exec(createChallengeQuery(), id, userID, expiresAt)
+	return err
+}
+
+// AttemptChallenge counts the attempt to pass the challenge. It reports false
+// when the challenge is used, expired or has no attempts left
+func (r *Auth) AttemptChallenge(id string, maxAttempts int) (bool, error) {
+	return affected(r.db.Exec(attemptChallengeQuery(), id, maxAttempts))
+}
+
+// UseChallenge marks the challenge as used and reports whether it was unused
+func (r *Auth) UseChallenge(id string) (bool, error) {
+	return affected(r.db.Exec(useChallengeQuery(), id))
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(deleteRecoveryCodesQuery(), userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(createRecoveryCodesQuery(), userID, codeHashes)
+	return err
+}
+
+// affected reports whether the statement changed any rows
+func affected(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func totpQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT COALESCE(t.secret, ''), COALESCE(t.enabled, FALSE), COALESCE(t.last_used_step, 0)
+		FROM %s AS u
+		LEFT JOIN %s AS t ON t.user_id = u.id
+		WHERE u.id = $1
+	`, usersTable, userTOTPTable)
+}
+
+func saveTOTPSecretQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, enabled = FALSE, last_used_step = 0, created_at = NOW()
+	`, userTOTPTable)
+}
+
+func enableTOTPQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET enabled = TRUE, last_used_step = $2
+		WHERE user_id = $1
+	`, userTOTPTable)
+}
+
+func deleteTOTPQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE user_id = $1
+	`, userTOTPTable)
+}
+
+func useTOTPStepQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET last_used_step = $2
+		WHERE user_id = $1
+		AND enabled
+		AND last_used_step < $2
+	`, userTOTPTable)
+}
+
+func useRecoveryCodeQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET used_at = NOW()
+		WHERE user_id = $1
+		AND code_hash = $2
+		AND used_at IS NULL
+	`, userRecoveryCodesTable)
+}
+
+func deleteRecoveryCodesQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE user_id = $1
+	`, userRecoveryCodesTable)
+}
+
+func createRecoveryCodesQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (user_id, code_hash)
+		SELECT $1, UNNEST($2::TEXT[])
+	`, userRecoveryCodesTable)
+}
+
+func createChallengeQuery() string {
+	return fmt.Sprintf(`--sql
+		WITH expired AS (
+			DELETE FROM %[1]s WHERE expires_at < NOW()
+		)
+		INSERT INTO %[1]s (id, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, loginChallengesTable)
+}
+
+func attemptChallengeQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET attempts = attempts + 1
+		WHERE id = $1
+		AND used_at IS NULL
+		AND attempts < $2
+		AND expires_at > NOW()
+	`, loginChallengesTable)
+}
+
+func useChallengeQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET used_at = NOW()
+		WHERE id = $1
+		AND used_at IS NULL
+	`, loginChallengesTable)
+}
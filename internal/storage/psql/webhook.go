@@ -0,0 +1,238 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Webhook represents repository of webhooks and their deliveries
+type Webhook struct {
+	db *sql.DB
+}
+
+// NewWebhook returns instance of Webhook repository
+func NewWebhook(db *sql.DB) *Webhook {
+	return &Webhook{db}
+}
+
+// CreateWebhook creates new webhook of the given User
+func (r *Webhook) CreateWebhook(userID int, w core.Webhook) (core.Webhook, error) {
+	err := r.db.QueryRow(createWebhookQuery(), userID, w.URL, w.Secret, w.Events).
+		Scan(&w.ID, &w.Active, &w.CreatedAt)
+	w.UserID = userID
+	return w, err
+}
+
+// GetAllWebhooks returns all webhooks of the given User
+func (r *Webhook) GetAllWebhooks(userID int) ([]core.Webhook, error) {
+	var webhooks []core.Webhook
+	rows, err := r.db.Query(allWebhooksQuery(), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhookByID returns webhook by ID which belongs to the given User
+func (r *Webhook) GetWebhookByID(userID, webhookID int) (core.Webhook, error) {
+	return scanWebhook(r.db.QueryRow(webhookByIDQuery(), userID, webhookID))
+}
+
+// DeleteWebhook removes webhook and its deliveries from DB
+func (r *Webhook) DeleteWebhook(webhookID int) error {
+	_, err := r.db.Exec(deleteWebhookByID(), webhookID)
+	return err
+}
+
+// EnqueueDeliveries creates pending deliveries of the event for every matching webhook
+// of the list's members and returns their amount. Deliveries of the same event
+// are created only once
+func (r *Webhook) EnqueueDeliveries(e core.Event, payload []byte) (int64, error) {
+	res, err := r.db.Exec(enqueueDeliveriesQuery(), e.Type, string(payload), e.UserID, e.ListID, e.ID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ClaimDeliveries returns pending deliveries which are due and postpones them for
+// the lease duration, so other workers don't pick them up at the same time
+func (r *Webhook) ClaimDeliveries(limit int, lease time.Duration) ([]core.WebhookDelivery, error) {
+	var deliveries []core.WebhookDelivery
+	rows, err := r.db.Query(claimDeliveriesQuery(), limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			d       core.WebhookDelivery
+			payload []byte
+		)
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventType, &payload, &d.Status, &d.Attempts, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// SaveDeliveryAttempt stores the result of the delivery attempt
+func (r *Webhook) SaveDeliveryAttempt(d core.WebhookDelivery) error {
+	_, err := r.db.Exec(saveDeliveryAttemptQuery(),
+		d.Status, d.Attempts, d.ResponseCode, d.LastError, d.NextAttemptAt, d.DeliveredAt, d.ID)
+	return err
+}
+
+// GetDeliveries returns the latest deliveries of the webhook
+func (r *Webhook) GetDeliveries(webhookID, limit int) ([]core.WebhookDelivery, error) {
+	var deliveries []core.WebhookDelivery
+	rows, err := r.db.Query(deliveriesQuery(), webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			d           core.WebhookDelivery
+			payload     []byte
+			deliveredAt sql.NullTime
+		)
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&d.ResponseCode, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &deliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func scanWebhook(row rowScanner) (core.Webhook, error) {
+	var (
+		w      core.Webhook
+		events pgtype.TextArray
+	)
+	if err := row.Scan(&w.ID, &w.UserID, &w.URL, &events, &w.Active, &w.CreatedAt); err != nil {
+		return w, err
+	}
+	return w, events.AssignTo(&w.Events)
+}
+
+func createWebhookQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (user_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, active, created_at
+	`, webhooksTable)
+}
+
+func allWebhooksQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, user_id, url, events, active, created_at
+		FROM %s
+		WHERE user_id = $1
+		ORDER BY id
+	`, webhooksTable)
+}
+
+func webhookByIDQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, user_id, url, events, active, created_at
+		FROM %s
+		WHERE user_id = $1
+		AND id = $2
+	`, webhooksTable)
+}
+
+func deleteWebhookByID() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s
+		WHERE id = $1
+	`, webhooksTable)
+}
+
+func enqueueDeliveriesQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (webhook_id, event_id, event_type, payload)
+		SELECT w.id, $5, $1, $2
+		FROM %s AS w
+		WHERE w.active
+		AND (cardinality(w.events) = 0 OR $1 = ANY(w.events))
+		AND (
+			w.user_id = $3
+			OR w.user_id IN (SELECT user_id FROM %s WHERE list_id = $4)
+		)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING
+	`, webhookDeliveriesTable, webhooksTable, usersListsTable)
+}
+
+func claimDeliveriesQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s AS d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM %s AS w
+		WHERE w.id = d.webhook_id
+		AND d.id IN (
+			SELECT id FROM %s
+			WHERE status = '%s'
+			AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.status, d.attempts, w.url, w.secret
+	`, webhookDeliveriesTable, webhooksTable, webhookDeliveriesTable, core.DeliveryPending)
+}
+
+func saveDeliveryAttemptQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s
+		SET status = $1,
+			attempts = $2,
+			response_code = NULLIF($3, 0),
+			last_error = NULLIF($4, ''),
+			next_attempt_at = $5,
+			delivered_at = $6
+		WHERE id = $7
+	`, webhookDeliveriesTable)
+}
+
+func deliveriesQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, webhook_id, event_type, payload, status, attempts,
+			COALESCE(response_code, 0), COALESCE(last_error, ''),
+			next_attempt_at, created_at, delivered_at
+		FROM %s
+		WHERE webhook_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, webhookDeliveriesTable)
+}
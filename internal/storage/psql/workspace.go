@@ -0,0 +1,520 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// personalWorkspaceName is a name of the workspace created for every user
+const personalWorkspaceName = "Personal"
+
+// Workspace represents repository of workspaces, their members and invitations
+type Workspace struct {
+	db *sql.DB
+}
+
+// NewWorkspace returns instance of Workspace repository
+func NewWorkspace(db *sql.DB) *Workspace {
+	return &Workspace{db}
+}
+
+// CreateWorkspace creates new Workspace owned by the given User
+func (r *Workspace) CreateWorkspace(userID int, w core.Workspace) (core.Workspace, error) {
+	var ws core.Workspace
+	tx, err := r.db.Begin()
+	if err != nil {
+		return ws, err
+	}
+	err = tx.QueryRow(createWorkspaceQuery(), w.Name, nil).Scan(&ws.ID, &ws.Name, &ws.Personal, &ws.CreatedAt)
+	if err != nil {
+		return ws, rollback(tx, err)
+	}
+	if _, err := tx.Exec(createMemberQuery(), ws.ID, userID, core.WorkspaceRoleOwner); err != nil {
+		return ws, rollback(tx, err)
+	}
+	ws.Role = core.WorkspaceRoleOwner
+	return ws, tx.Commit()
+}
+
+// GetWorkspaces returns all workspaces the given User is member of
+func (r *Workspace) GetWorkspaces(userID int) ([]core.Workspace, error) {
+	rows, err := r.db.Query(userWorkspacesQuery(), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var workspaces []core.Workspace
+	for rows.Next() {
+		ws, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, ws)
+	}
+	return workspaces, rows.Err()
+}
+
+// GetWorkspace returns the Workspace with role of the given User, who has to be
+// member of the Workspace
+func (r *Workspace) GetWorkspace(userID, workspaceID int) (core.Workspace, error) {
+	return scanWorkspace(r.db.QueryRow(userWorkspaceQuery(), userID, workspaceID))
+}
+
+// UpdateWorkspace saves changes of the Workspace
+func (r *Workspace) UpdateWorkspace(workspaceID int, data core.UpdateWorkspaceData) (core.Workspace, error) {
+	var ws core.Workspace
+	err := r.db.QueryRow(updateWorkspaceQuery(), workspaceID, data.Name).Scan(&ws.ID, &ws.Name, &ws.Personal, &ws.CreatedAt)
+	return ws, err
+}
+
+// DeleteWorkspace removes the Workspace together with its lists, their todos
+// and history
+func (r *Workspace) DeleteWorkspace(workspaceID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	rows, err := tx.Query(workspaceListIDsQuery(), workspaceID)
+	if err != nil {
+		return rollback(tx, err)
+	}
+	var listIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return rollback(tx, err)
+		}
+		listIDs = append(listIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return rollback(tx, err)
+	}
+	for _, listID := range listIDs {
+		if err := insertTombstones(tx, core.EntityList, listID, listID); err != nil {
+			return rollback(tx, err)
+		}
+	}
+	if len(listIDs) > 0 {
+		for _, query := range []string{
+			deleteListsTodosQuery(),
+			deleteListsEventsQuery(),
+			deleteListsOutboxQuery(),
+			deleteListsQuery(),
+		} {
+			if _, err := tx.Exec(query, listIDs); err != nil {
+				return rollback(tx, err)
+			}
+		}
+	}
+	if _, err := tx.Exec(deleteWorkspaceQuery(), workspaceID); err != nil {
+		return rollback(tx, err)
+	}
+	return tx.Commit()
+}
+
+// GetMembers returns members of the Workspace
+func (r *Workspace) GetMembers(workspaceID int) ([]core.WorkspaceMember, error) {
+	rows, err := r.db.Query(membersQuery(), workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var members []core.WorkspaceMember
+	for rows.Next() {
+		var m core.WorkspaceMember
+		if err := rows.Scan(&m.UserID, &m.Name, &m.Username, &m.Role, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// GetMemberRole returns role of the User in the Workspace
+func (r *Workspace) GetMemberRole(workspaceID, userID int) (string, error) {
+	var role string
+	err := r.db.QueryRow(memberRoleQuery(), workspaceID, userID).Scan(&role)
+	return role, err
+}
+
+// GetListRole returns role of the User in the workspace of the List
+func (r *Workspace) GetListRole(listID, userID int) (string, error) {
+	var role string
+	err := r.db.QueryRow(listRoleQuery(), listID, userID).Scan(&role)
+	return role, err
+}
+
+// CountOwners returns number of owners of the Workspace
+func (r *Workspace) CountOwners(workspaceID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(countOwnersQuery(), workspaceID).Scan(&count)
+	return count, err
+}
+
+// UpdateMemberRole changes role of the member
+func (r *Workspace) UpdateMemberRole(workspaceID, userID int, role string) error {
+	ok, err := affected(r.db.Exec(updateMemberRoleQuery(), workspaceID, userID, role))
+	if err == nil && !ok {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// RemoveMember removes the User from the Workspace on behalf of the actor. Todos
+// assigned to the User are unassigned, lists of the Workspace are reported as
+// deleted to the User's sync clients
+func (r *Workspace) RemoveMember(actorID, workspaceID, userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := unassignMember(tx, actorID, workspaceID, userID); err != nil {
+		return rollback(tx, err)
+	}
+	if _, err := tx.Exec(memberTombstonesQuery(), workspaceID, userID); err != nil {
+		return rollback(tx, err)
+	}
+	ok, err := affected(tx.Exec(deleteMemberQuery(), workspaceID, userID))
+	if err != nil {
+		return rollback(tx, err)
+	}
+	if !ok {
+		return rollback(tx, sql.ErrNoRows)
+	}
+	return tx.Commit()
+}
+
+// CreateInvite saves the invitation to the Workspace
+func (r *Workspace) CreateInvite(i core.WorkspaceInvite) (core.WorkspaceInvite, error) {
+	err := r.db.QueryRow(createInviteQuery(), i.WorkspaceID, i.Email, i.Role, i.Hash, i.InvitedBy, i.ExpiresAt).
+		Scan(&i.ID, &i.CreatedAt)
+	return i, err
+}
+
+// GetInvites returns pending invitations to the Workspace
+func (r *Workspace) GetInvites(workspaceID int) ([]core.WorkspaceInvite, error) {
+	rows, err := r.db.Query(invitesQuery(), workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var invites []core.WorkspaceInvite
+	for rows.Next() {
+		i, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, i)
+	}
+	return invites, rows.Err()
+}
+
+// GetInviteByHash returns pending invitation by hash of its token
+func (r *Workspace) GetInviteByHash(hash string) (core.WorkspaceInvite, error) {
+	return scanInvite(r.db.QueryRow(inviteByHashQuery(), hash))
+}
+
+// DeleteInvite revokes the invitation
+func (r *Workspace) DeleteInvite(workspaceID, inviteID int) error {
+	ok, err := affected(r.db.Exec(deleteInviteQuery(), workspaceID, inviteID))
+	if err == nil && !ok {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// AcceptInvite uses the invitation and adds the User to its Workspace. Role of
+// the User who is already member doesn't change
+func (r *Workspace) AcceptInvite(inviteID, userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	var (
+		workspaceID int
+		role        string
+	)
+	if err := tx.QueryRow(useInviteQuery(), inviteID).Scan(&workspaceID, &role); err != nil {
+		return rollback(tx, err)
+	}
+	ok, err := affected(tx.Exec(joinWorkspaceQuery(), workspaceID, userID, role))
+	if err != nil {
+		return rollback(tx, err)
+	}
+	if ok {
+		// existing lists have to be delivered to sync clients of the new member
+		for _, query := range []string{touchWorkspaceListsQuery(), touchWorkspaceTodosQuery()} {
+			if _, err := tx.Exec(query, workspaceID); err != nil {
+				return rollback(tx, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// GetUserByID returns the User, invitations are matched against the User's email
+func (r *Workspace) GetUserByID(userID int) (core.User, error) {
+	return scanUser(r.db.QueryRow(userByIDQuery(), userID))
+}
+
+// unassignMember unassigns todos of the workspace's lists from the user and
+// records the changes in history of the lists
+func unassignMember(tx *sql.Tx, actorID, workspaceID, userID int) error {
+	rows, err := tx.Query(unassignMemberQuery(), workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	var todos []core.AssignedTodo
+	for rows.Next() {
+		var t core.AssignedTodo
+		if t.TodoItem, err = scanTodo(rows, &t.ListID); err != nil {
+			rows.Close()
+			return err
+		}
+		todos = append(todos, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, t := range todos {
+		assignment := core.TodoAssignment{TodoItem: t.TodoItem, PreviousAssigneeID: userID}
+		if err := insertOutbox(tx, core.NewEvent(core.EventTodoAssigned, actorID, t.ListID, t.ID, assignment)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createPersonalWorkspace creates the workspace owned by the new user
+func createPersonalWorkspace(tx *sql.Tx, userID int) error {
+	var workspaceID int
+	if err := tx.QueryRow(createWorkspaceQuery(), personalWorkspaceName, userID).Scan(&workspaceID, new(string), new(bool), new(sql.NullTime)); err != nil {
+		return err
+	}
+	_, err := tx.Exec(createMemberQuery(), workspaceID, userID, core.WorkspaceRoleOwner)
+	return err
+}
+
+func scanWorkspace(row rowScanner) (core.Workspace, error) {
+	var ws core.Workspace
+	err := row.Scan(&ws.ID, &ws.Name, &ws.Personal, &ws.CreatedAt, &ws.Role)
+	return ws, err
+}
+
+func scanInvite(row rowScanner) (core.WorkspaceInvite, error) {
+	var (
+		i         core.WorkspaceInvite
+		invitedBy sql.NullInt64
+	)
+	err := row.Scan(&i.ID, &i.WorkspaceID, &i.Email, &i.Role, &invitedBy, &i.ExpiresAt, &i.CreatedAt)
+	i.InvitedBy = int(invitedBy.Int64)
+	return i, err
+}
+
+func createWorkspaceQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (name, personal_user_id)
+		VALUES ($1, $2)
+		RETURNING id, name, personal_user_id IS NOT NULL, created_at
+	`, workspacesTable)
+}
+
+func createMemberQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, workspaceMembersTable)
+}
+
+func userWorkspacesQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT w.id, w.name, w.personal_user_id IS NOT NULL, w.created_at, wm.role
+		FROM %s AS w
+		INNER JOIN %s AS wm ON wm.workspace_id = w.id
+		WHERE wm.user_id = $1
+		ORDER BY w.personal_user_id IS NULL, w.id
+	`, workspacesTable, workspaceMembersTable)
+}
+
+func userWorkspaceQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT w.id, w.name, w.personal_user_id IS NOT NULL, w.created_at, wm.role
+		FROM %s AS w
+		INNER JOIN %s AS wm ON wm.workspace_id = w.id
+		WHERE wm.user_id = $1
+		AND w.id = $2
+	`, workspacesTable, workspaceMembersTable)
+}
+
+func updateWorkspaceQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET name = COALESCE($2, name)
+		WHERE id = $1
+		RETURNING id, name, personal_user_id IS NOT NULL, created_at
+	`, workspacesTable)
+}
+
+func workspaceListIDsQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id FROM %s WHERE workspace_id = $1
+	`, todoListsTable)
+}
+
+func deleteWorkspaceQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s WHERE id = $1
+	`, workspacesTable)
+}
+
+func membersQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT u.id, u.name, u.username, wm.role, wm.created_at
+		FROM %s AS wm
+		INNER JOIN %s AS u ON u.id = wm.user_id
+		WHERE wm.workspace_id = $1
+		ORDER BY wm.created_at, u.id
+	`, workspaceMembersTable, usersTable)
+}
+
+func memberRoleQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT role FROM %s
+		WHERE workspace_id = $1
+		AND user_id = $2
+	`, workspaceMembersTable)
+}
+
+func listRoleQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT wm.role
+		FROM %s AS tl
+		INNER JOIN %s AS wm ON wm.workspace_id = tl.workspace_id
+		WHERE tl.id = $1
+		AND wm.user_id = $2
+	`, todoListsTable, workspaceMembersTable)
+}
+
+func countOwnersQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT COUNT(*) FROM %s
+		WHERE workspace_id = $1
+		AND role = 'owner'
+	`, workspaceMembersTable)
+}
+
+func updateMemberRoleQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET role = $3
+		WHERE workspace_id = $1
+		AND user_id = $2
+	`, workspaceMembersTable)
+}
+
+func memberTombstonesQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (user_id, entity, entity_id, list_id)
+		SELECT $2, '%s', id, id
+		FROM %s
+		WHERE workspace_id = $1
+	`, syncTombstonesTable, core.EntityList, todoListsTable)
+}
+
+func unassignMemberQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s AS ti
+		SET assignee_id = NULL, updated_at = NOW(), change_xid = txid_current()
+		FROM %s AS li, %s AS tl
+		WHERE li.item_id = ti.id
+		AND tl.id = li.list_id
+		AND tl.workspace_id = $1
+		AND ti.assignee_id = $2
+		RETURNING li.list_id, ti.id, ti.title, ti.description, ti.done, ti.due, COALESCE(ti.uid, ''), ti.updated_at, COALESCE(ti.assignee_id, 0), ti.priority, ti.labels, COALESCE(ti.recurrence, ''), COALESCE(ti.status_id, 0)
+	`, todoItemsTable, listsItemsTable, todoListsTable)
+}
+
+func deleteMemberQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s
+		WHERE workspace_id = $1
+		AND user_id = $2
+	`, workspaceMembersTable)
+}
+
+func createInviteQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (workspace_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, workspaceInvitesTable)
+}
+
+func invitesQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, workspace_id, email, role, invited_by, expires_at, created_at
+		FROM %s
+		WHERE workspace_id = $1
+		AND accepted_at IS NULL
+		AND expires_at > NOW()
+		ORDER BY id
+	`, workspaceInvitesTable)
+}
+
+func inviteByHashQuery() string {
+	return fmt.Sprintf(`--sql
+		SELECT id, workspace_id, email, role, invited_by, expires_at, created_at
+		FROM %s
+		WHERE token_hash = $1
+		AND accepted_at IS NULL
+		AND expires_at > NOW()
+	`, workspaceInvitesTable)
+}
+
+func deleteInviteQuery() string {
+	return fmt.Sprintf(`--sql
+		DELETE FROM %s
+		WHERE workspace_id = $1
+		AND id = $2
+	`, workspaceInvitesTable)
+}
+
+func useInviteQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET accepted_at = NOW()
+		WHERE id = $1
+		AND accepted_at IS NULL
+		AND expires_at > NOW()
+		RETURNING workspace_id, role
+	`, workspaceInvitesTable)
+}
+
+func joinWorkspaceQuery() string {
+	return fmt.Sprintf(`--sql
+		INSERT INTO %s (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (workspace_id, user_id) DO NOTHING
+	`, workspaceMembersTable)
+}
+
+func touchWorkspaceListsQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET change_xid = txid_current()
+		WHERE workspace_id = $1
+	`, todoListsTable)
+}
+
+func touchWorkspaceTodosQuery() string {
+	return fmt.Sprintf(`--sql
+		UPDATE %s SET change_xid = txid_current()
+		WHERE id IN (
+			SELECT li.item_id
+			FROM %s AS li
+			INNER JOIN %s AS tl ON tl.id = li.list_id
+			WHERE tl.workspace_id = $1
+		)
+	`, todoItemsTable, listsItemsTable, todoListsTable)
+}
@@ -0,0 +1,268 @@
+// Package caldav implements subset of CalDAV (RFC 4791) which exposes lists as
+// calendar collections of VTODO resources, so native task apps can sync them
+package caldav
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// BasePath is a path the handler must be mounted at
+const BasePath = "/dav"
+
+const (
+	methodPropfind = "PROPFIND"
+	methodReport   = "REPORT"
+	realm          = "todo-app"
+	objectExt      = ".ics"
+	maxBodySize    = 1 << 20
+)
+
+var (
+	errNotFound     = errors.New("resource not found")
+	errUnauthorized = errors.New("invalid credentials")
+)
+
+func init() {
+	// chi rejects requests with unknown methods before routing them
+	chi.RegisterMethod(methodPropfind)
+	chi.RegisterMethod(methodReport)
+}
+
+type AuthService interface {
+	AuthenticateBasic(username, secret string, scopes ...string) (int, error)
+}
+
+type TodoListService interface {
+	GetAllLists(userID int) ([]core.Todolist, error)
+	GetListByID(userID, listID int) (core.Todolist, error)
+}
+
+type TodoItemService interface {
+	CreateTodo(userID, listID int, todo core.TodoItem) (core.TodoItem, error)
+	GetAllTodos(listID int) ([]core.TodoItem, error)
+	GetTodoByUID(listID int, uid string) (core.TodoItem, error)
+	UpdateTodo(userID, listID, todoID int, data core.UpdateItemData) (core.TodoItem, error)
+	DeleteTodo(userID, listID, todoID int) error
+}
+
+type EventService interface {
+	GetEventsSince(listID int, seq int64) ([]core.Event, error)
+	GetLastEventSeq(listID int) (int64, error)
+}
+
+// Deps represents external dependencies for CalDAV handler
+type Deps struct {
+	AuthService     AuthService
+	TodoListService TodoListService
+	TodoItemService TodoItemService
+	EventService    EventService
+	Log             *zap.Logger
+}
+
+// Handler serves CalDAV requests of the users authenticated with Basic auth
+type Handler struct {
+	auth   AuthService
+	lists  TodoListService
+	todos  TodoItemService
+	events EventService
+	log    *zap.Logger
+}
+
+// NewHandler returns http handler which serves CalDAV requests under BasePath
+func NewHandler(deps Deps) *Handler {
+	return &Handler{
+		auth:   deps.AuthService,
+		lists:  deps.TodoListService,
+		todos:  deps.TodoItemService,
+		events: deps.EventService,
+		log:    deps.Log,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		h.options(w, r)
+		return
+	}
+	userID, err := h.authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	res, ok := parsePath(r.URL.EscapedPath())
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case methodPropfind:
+		err = h.propfind(w, r, userID, res)
+	case methodReport:
+		err = h.report(w, r, userID, res)
+	case http.MethodGet, http.MethodHead:
+		err = h.get(w, r, userID, res)
+	case http.MethodPut:
+		err = h.put(w, r, userID, res)
+	case http.MethodDelete:
+		err = h.delete(w, r, userID, res)
+	default:
+		w.Header().Set("Allow", allowedMethods)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		h.handleError(w, r, err)
+	}
+}
+
+const allowedMethods = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+
+func (h *Handler) options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	w.Header().Set("Allow", allowedMethods)
+	w.WriteHeader(http.StatusOK)
+}
+
+// authenticate returns ID of the user from the Basic auth credentials, password
+// can be replaced by personal access token
+func (h *Handler) authenticate(r *http.Request) (int, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return 0, errUnauthorized
+	}
+	userID, err := h.auth.AuthenticateBasic(username, password, core.ScopeListsRead, core.ScopeTodosRead, core.ScopeTodosWrite)
+	if err != nil {
+		return 0, errUnauthorized
+	}
+	return userID, nil
+}
+
+// handleError replies with the status which corresponds to the error
+func (h *Handler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	var herr *httpError
+	switch {
+	case errors.As(err, &herr):
+		if herr.condition.Local != "" {
+			writeError(w, herr.code, herr.condition)
+			return
+		}
+		http.Error(w, herr.Error(), herr.code)
+	case errors.Is(err, errNotFound):
+		http.NotFound(w, r)
+	default:
+		h.log.Error("can't handle CalDAV request",
+			zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// httpError is an error which is reported to the client as is, condition is
+// an optional name of the failed precondition element
+type httpError struct {
+	code      int
+	message   string
+	condition xml.Name
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func newHTTPError(code int, message string) error {
+	return &httpError{code: code, message: message}
+}
+
+func newPreconditionError(code int, condition xml.Name, message string) error {
+	return &httpError{code: code, message: message, condition: condition}
+}
+
+type resourceKind int
+
+const (
+	rootResource resourceKind = iota
+	principalResource
+	homeResource
+	calendarResource
+	objectResource
+)
+
+// resource is a parsed request path
+type resource struct {
+	kind   resourceKind
+	listID int
+	name   string
+}
+
+// parsePath maps escaped path under BasePath to the resource:
+//
+//	/dav/                               root
+//	/dav/principal/                     current user
+//	/dav/calendars/                     calendar home
+//	/dav/calendars/{listID}/            calendar of the list
+//	/dav/calendars/{listID}/{name}.ics  todo
+func parsePath(p string) (resource, bool) {
+	if !strings.HasPrefix(p, BasePath) {
+		return resource{}, false
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(p, BasePath), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		return resource{kind: rootResource}, true
+	case len(parts) == 1 && parts[0] == "principal":
+		return resource{kind: principalResource}, true
+	case parts[0] != "calendars":
+		return resource{}, false
+	case len(parts) == 1:
+		return resource{kind: homeResource}, true
+	}
+	listID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return resource{}, false
+	}
+	switch {
+	case len(parts) == 2:
+		return resource{kind: calendarResource, listID: listID}, true
+	case len(parts) == 3 && strings.HasSuffix(parts[2], objectExt) && len(parts[2]) > len(objectExt):
+		name, err := url.PathUnescape(strings.TrimSuffix(parts[2], objectExt))
+		if err != nil {
+			return resource{}, false
+		}
+		return resource{kind: objectResource, listID: listID, name: name}, true
+	}
+	return resource{}, false
+}
+
+func principalHref() string {
+	return BasePath + "/principal/"
+}
+
+func homeHref() string {
+	return BasePath + "/calendars/"
+}
+
+func calendarHref(listID int) string {
+	return homeHref() + strconv.Itoa(listID) + "/"
+}
+
+func objectHref(listID int, name string) string {
+	return calendarHref(listID) + url.PathEscape(name) + objectExt
+}
+
+// objectName returns name of the todo's resource, todos created by calendar
+// clients keep the names which clients gave them
+func objectName(t core.TodoItem) string {
+	if t.UID != "" {
+		return t.UID
+	}
+	return strconv.Itoa(t.ID)
+}
@@ -0,0 +1,430 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	gocaldav "github.com/emersion/go-webdav/caldav"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+const (
+	testUser     = "alice"
+	testPassword = "secret"
+	testUserID   = 1
+	testListID   = 42
+)
+
+// backend keeps the only list of the test user in memory and records events
+// of its todos the way the services do
+type backend struct {
+	mu     sync.Mutex
+	list   core.Todolist
+	todos  []core.TodoItem
+	events []core.Event
+	nextID int
+}
+
+func newBackend() *backend {
+	return &backend{list: core.Todolist{ID: testListID, Title: "Groceries"}, nextID: 1}
+}
+
+func (b *backend) AuthenticateBasic(username, secret string, scopes ...string) (int, error) {
+	if username != testUser || secret != testPassword {
+		return 0, errors.New("invalid credentials")
+	}
+	return testUserID, nil
+}
+
+func (b *backend) GetAllLists(userID int) ([]core.Todolist, error) {
+	return []core.Todolist{b.list}, nil
+}
+
+func (b *backend) GetListByID(userID, listID int) (core.Todolist, error) {
+	if userID != testUserID || listID != testListID {
+		return core.Todolist{}, errors.New("list not found")
+	}
+	return b.list, nil
+}
+
+func (b *backend) CreateTodo(userID, listID int, todo core.TodoItem) (core.TodoItem, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	todo.ID, b.nextID = b.nextID, b.nextID+1
+	todo.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	b.todos = append(b.todos, todo)
+	b.record(core.EventTodoCreated, userID, todo)
+	return todo, nil
+}
+
+func (b *backend) GetAllTodos(listID int) ([]core.TodoItem, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]core.TodoItem(nil), b.todos...), nil
+}
+
+func (b *backend) GetTodoByUID(listID int, uid string) (core.TodoItem, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, t := range b.todos {
+		if objectName(t) == uid {
+			return t, nil
+		}
+	}
+	return core.TodoItem{}, errors.New("todo not found")
+}
+
+func (b *backend) UpdateTodo(userID, listID, todoID int, data core.UpdateItemData) (core.TodoItem, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.todos {
+		t := &b.todos[i]
+		if t.ID != todoID {
+			continue
+		}
+		if data.Title != nil {
+			t.Title = *data.Title
+		}
+		if data.Description != nil {
+			t.Description = *data.Description
+		}
+		if data.Done != nil {
+			t.Done = *data.Done
+		}
+		t.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+		b.record(core.EventTodoUpdated, userID, *t)
+		return *t, nil
+	}
+	return core.TodoItem{}, errors.New("todo not found")
+}
+
+func (b *backend) DeleteTodo(userID, listID, todoID int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, t := range b.todos {
+		if t.ID == todoID {
+			b.todos = append(b.todos[:i], b.todos[i+1:]...)
+			b.record(core.EventTodoDeleted, userID, t)
+			return nil
+		}
+	}
+	return errors.New("todo not found")
+}
+
+func (b *backend) GetEventsSince(listID int, seq int64) ([]core.Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var events []core.Event
+	for _, e := range b.events {
+		if e.Seq > seq {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (b *backend) GetLastEventSeq(listID int) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.events) == 0 {
+		return 0, nil
+	}
+	return b.events[len(b.events)-1].Seq, nil
+}
+
+func (b *backend) record(eventType string, userID int, t core.TodoItem) {
+	e := core.NewEvent(eventType, userID, testListID, t.ID, t)
+	e.Seq = int64(len(b.events) + 1)
+	e.ID = e.Seq
+	b.events = append(b.events, e)
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *backend) {
+	t.Helper()
+	b := newBackend()
+	h := NewHandler(Deps{
+		AuthService:     b,
+		TodoListService: b,
+		TodoItemService: b,
+		EventService:    b,
+		Log:             zap.NewNop(),
+	})
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv, b
+}
+
+func newTestClient(t *testing.T, srv *httptest.Server) (*gocaldav.Client, webdav.HTTPClient) {
+	t.Helper()
+	hc := webdav.HTTPClientWithBasicAuth(srv.Client(), testUser, testPassword)
+	c, err := gocaldav.NewClient(hc, srv.URL+BasePath+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, hc
+}
+
+func newVTODO(uid, summary string) *ical.Calendar {
+	todo := ical.NewComponent(ical.CompToDo)
+	todo.Props.SetText(ical.PropUID, uid)
+	todo.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+	todo.Props.SetText(ical.PropSummary, summary)
+	todo.Props.SetText(ical.PropStatus, "NEEDS-ACTION")
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//todo-app//test//EN")
+	cal.Children = append(cal.Children, todo)
+	return cal
+}
+
+func summaryOf(t *testing.T, cal *ical.Calendar) string {
+	t.Helper()
+	for _, c := range cal.Children {
+		if c.Name == ical.CompToDo {
+			s, err := c.Props.Text(ical.PropSummary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}
+	}
+	t.Fatal("calendar object has no VTODO")
+	return ""
+}
+
+// davResponse is a response of multistatus which the tests read without the client
+type davResponse struct {
+	Href     string `xml:"DAV: href"`
+	Status   string `xml:"DAV: status"`
+	Propstat []struct {
+		Prop struct {
+			ETag string `xml:"DAV: getetag"`
+			CTag string `xml:"http://calendarserver.org/ns/ getctag"`
+		} `xml:"DAV: prop"`
+	} `xml:"DAV: propstat"`
+}
+
+func (r davResponse) etag() string {
+	for _, ps := range r.Propstat {
+		if ps.Prop.ETag != "" {
+			return ps.Prop.ETag
+		}
+	}
+	return ""
+}
+
+func (r davResponse) ctag() string {
+	for _, ps := range r.Propstat {
+		if ps.Prop.CTag != "" {
+			return ps.Prop.CTag
+		}
+	}
+	return ""
+}
+
+type davMultistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+	SyncToken string        `xml:"DAV: sync-token"`
+}
+
+// do sends the raw WebDAV request and decodes its multistatus response
+func do(t *testing.T, hc webdav.HTTPClient, method, url, depth, body string) davMultistatus {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", depth)
+	resp, err := hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("%s %s: status %d: %s", method, url, resp.StatusCode, data)
+	}
+	var ms davMultistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		t.Fatalf("%s %s: %v: %s", method, url, err, data)
+	}
+	return ms
+}
+
+func getCTag(t *testing.T, hc webdav.HTTPClient, url string) string {
+	t.Helper()
+	ms := do(t, hc, methodPropfind, url, "0", `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/">
+  <d:prop><cs:getctag/></d:prop>
+</d:propfind>`)
+	if len(ms.Responses) != 1 {
+		t.Fatalf("PROPFIND %s returned %d responses", url, len(ms.Responses))
+	}
+	return ms.Responses[0].ctag()
+}
+
+func syncCollection(t *testing.T, hc webdav.HTTPClient, url, token string) davMultistatus {
+	t.Helper()
+	return do(t, hc, methodReport, url, "1", `<?xml version="1.0" encoding="utf-8"?>
+<d:sync-collection xmlns:d="DAV:">
+  <d:sync-token>`+token+`</d:sync-token>
+  <d:sync-level>1</d:sync-level>
+  <d:prop><d:getetag/></d:prop>
+</d:sync-collection>`)
+}
+
+func TestCalDAVRoundTrip(t *testing.T) {
+	srv, _ := newTestServer(t)
+	c, hc := newTestClient(t, srv)
+	ctx := context.Background()
+
+	principal, err := c.FindCurrentUserPrincipal(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal != principalHref() {
+		t.Errorf("principal = %s, want %s", principal, principalHref())
+	}
+	home, err := c.FindCalendarHomeSet(ctx, principal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if home != homeHref() {
+		t.Errorf("home set = %s, want %s", home, homeHref())
+	}
+	calendars, err := c.FindCalendars(ctx, home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calendars) != 1 {
+		t.Fatalf("found %d calendars, want 1", len(calendars))
+	}
+	cal := calendars[0]
+	if cal.Path != calendarHref(testListID) || cal.Name != "Groceries" {
+		t.Errorf("calendar = %+v", cal)
+	}
+	if len(cal.SupportedComponentSet) != 1 || cal.SupportedComponentSet[0] != ical.CompToDo {
+		t.Errorf("supported components = %v, want VTODO", cal.SupportedComponentSet)
+	}
+	ctag := getCTag(t, hc, srv.URL+cal.Path)
+
+	path := objectHref(testListID, "buy-milk")
+	if _, err := c.PutCalendarObject(ctx, path, newVTODO("buy-milk", "Buy milk")); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := c.GetCalendarObject(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summaryOf(t, obj.Data); got != "Buy milk" {
+		t.Errorf("summary = %q, want Buy milk", got)
+	}
+	if obj.ETag == "" {
+		t.Fatal("GET returned no ETag")
+	}
+	newCTag := getCTag(t, hc, srv.URL+cal.Path)
+	if newCTag == ctag {
+		t.Errorf("ctag %s didn't change after PUT", ctag)
+	}
+
+	// calendar-query and calendar-multiget report the same ETag as GET
+	objects, err := c.QueryCalendar(ctx, cal.Path, &gocaldav.CalendarQuery{
+		CompRequest: gocaldav.CalendarCompRequest{Name: ical.CompCalendar, AllProps: true, AllComps: true},
+		CompFilter:  gocaldav.CompFilter{Name: ical.CompCalendar, Comps: []gocaldav.CompFilter{{Name: ical.CompToDo}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Path != path {
+		t.Fatalf("calendar-query returned %+v", objects)
+	}
+	if objects[0].ETag != obj.ETag {
+		t.Errorf("calendar-query etag = %s, want %s", objects[0].ETag, obj.ETag)
+	}
+	objects, err = c.MultiGetCalendar(ctx, cal.Path, &gocaldav.CalendarMultiGet{
+		Paths:       []string{path},
+		CompRequest: gocaldav.CalendarCompRequest{Name: ical.CompCalendar, AllProps: true, AllComps: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || summaryOf(t, objects[0].Data) != "Buy milk" {
+		t.Fatalf("calendar-multiget returned %+v", objects)
+	}
+
+	// the update changes ETag and the sync-collection reports only the changed todo
+	if _, err := c.PutCalendarObject(ctx, objectHref(testListID, "buy-bread"), newVTODO("buy-bread", "Buy bread")); err != nil {
+		t.Fatal(err)
+	}
+	token := getCTag(t, hc, srv.URL+cal.Path)
+	if _, err := c.PutCalendarObject(ctx, path, newVTODO("buy-milk", "Buy oat milk")); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := c.GetCalendarObject(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ETag == obj.ETag {
+		t.Errorf("etag %s didn't change after update", obj.ETag)
+	}
+	ms := syncCollection(t, hc, srv.URL+cal.Path, token)
+	if len(ms.Responses) != 1 || ms.Responses[0].Href != path {
+		t.Fatalf("sync-collection since %s returned %+v", token, ms.Responses)
+	}
+	// the client unquotes entity tags
+	if got := ms.Responses[0].etag(); got != `"`+updated.ETag+`"` {
+		t.Errorf("sync-collection etag = %s, want %q", got, updated.ETag)
+	}
+	if ms.SyncToken == token || ms.SyncToken != getCTag(t, hc, srv.URL+cal.Path) {
+		t.Errorf("sync-collection token = %s, want the current ctag", ms.SyncToken)
+	}
+
+	// deleted todos are reported as missing
+	token = ms.SyncToken
+	req, err := http.NewRequest(http.MethodDelete, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d", resp.StatusCode)
+	}
+	ms = syncCollection(t, hc, srv.URL+cal.Path, token)
+	if len(ms.Responses) != 1 || ms.Responses[0].Href != path || !strings.Contains(ms.Responses[0].Status, "404") {
+		t.Fatalf("sync-collection after DELETE returned %+v", ms.Responses)
+	}
+	if _, err := c.GetCalendarObject(ctx, path); err == nil {
+		t.Error("GET of the deleted todo succeeded")
+	}
+}
+
+func TestCalDAVRejectsInvalidCredentials(t *testing.T) {
+	srv, _ := newTestServer(t)
+	hc := webdav.HTTPClientWithBasicAuth(srv.Client(), testUser, "wrong")
+	c, err := gocaldav.NewClient(hc, srv.URL+BasePath+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.FindCurrentUserPrincipal(context.Background()); err == nil {
+		t.Fatal("PROPFIND with invalid credentials succeeded")
+	}
+}
@@ -0,0 +1,212 @@
+package caldav
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vbetsun/todo-app/internal/exporter"
+)
+
+var (
+	errNoVTODO          = errors.New("calendar object must contain VTODO component")
+	errMalformedICal    = errors.New("malformed iCalendar data")
+	errMalformedDueDate = errors.New("malformed DUE property")
+)
+
+var icsUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";")
+
+// vtodo holds properties of VTODO component which todos can store
+type vtodo struct {
+	uid         string
+	summary     string
+	description string
+	done        bool
+	due         *time.Time
+	priority    int
+	categories  []string
+	rrule       string
+}
+
+// icalProperty is a single unfolded content line
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseVTODO reads the first VTODO component of iCalendar object, other
+// properties and nested components like alarms are ignored
+func parseVTODO(data string) (vtodo, error) {
+	var (
+		todo      vtodo
+		stack     []string
+		found     bool
+		status    string
+		completed bool
+	)
+	for _, line := range unfoldLines(data) {
+		p, err := parseICalLine(line)
+		if err != nil {
+			return todo, err
+		}
+		switch p.name {
+		case "BEGIN":
+			stack = append(stack, strings.ToUpper(p.value))
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != strings.ToUpper(p.value) {
+				return todo, errMalformedICal
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 1 && strings.EqualFold(p.value, "VTODO") && !found {
+				found = true
+				todo.done = status == "COMPLETED" || (status == "" && completed)
+			}
+			continue
+		}
+		// only properties of the first VTODO itself matter
+		if found || len(stack) != 2 || stack[0] != "VCALENDAR" || stack[1] != "VTODO" {
+			continue
+		}
+		switch p.name {
+		case "UID":
+			todo.uid = p.value
+		case "SUMMARY":
+			todo.summary = icsUnescaper.Replace(p.value)
+		case "DESCRIPTION":
+			todo.description = icsUnescaper.Replace(p.value)
+		case "STATUS":
+			status = strings.ToUpper(p.value)
+		case "COMPLETED":
+			completed = true
+		case "DUE":
+			due, err := parseICalTime(p)
+			if err != nil {
+				return todo, err
+			}
+			todo.due = &due
+		case "PRIORITY":
+			priority, err := strconv.Atoi(strings.TrimSpace(p.value))
+			if err != nil {
+				return todo, errMalformedICal
+			}
+			todo.priority = exporter.PriorityFromICS(priority)
+		case "CATEGORIES":
+			todo.categories = append(todo.categories, splitICalList(p.value)...)
+		case "RRULE":
+			todo.rrule = strings.ToUpper(p.value)
+		}
+	}
+	if len(stack) != 0 {
+		return todo, errMalformedICal
+	}
+	if !found {
+		return todo, errNoVTODO
+	}
+	return todo, nil
+}
+
+// splitICalList splits the list of text values on unescaped commas
+func splitICalList(value string) []string {
+	var (
+		values  []string
+		current strings.Builder
+		escaped bool
+	)
+	for _, c := range value {
+		switch {
+		case escaped:
+			current.WriteRune('\\')
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ',':
+			values = append(values, icsUnescaper.Replace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	if v := icsUnescaper.Replace(current.String()); v != "" {
+		values = append(values, v)
+	}
+	return values
+}
+
+// unfoldLines splits data into content lines joining the folded ones
+func unfoldLines(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseICalLine parses content line of the form NAME;PARAM=VALUE:VALUE
+func parseICalLine(line string) (icalProperty, error) {
+	p := icalProperty{params: make(map[string]string)}
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return p, errMalformedICal
+	}
+	p.value = line[colon+1:]
+	parts := strings.Split(line[:colon], ";")
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return p, errMalformedICal
+		}
+		p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return p, nil
+}
+
+// parseICalTime parses DATE or DATE-TIME value, floating time and unknown
+// time zones are treated as UTC
+func parseICalTime(p icalProperty) (time.Time, error) {
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(p.value) == len("20060102") {
+		t, err := time.Parse("20060102", p.value)
+		if err != nil {
+			return t, errMalformedDueDate
+		}
+		return t, nil
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		t, err := time.Parse("20060102T150405Z", p.value)
+		if err != nil {
+			return t, errMalformedDueDate
+		}
+		return t, nil
+	}
+	loc := time.UTC
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", p.value, loc)
+	if err != nil {
+		return t, errMalformedDueDate
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,252 @@
+package caldav
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/exporter"
+)
+
+// maxFieldLength is a limit of titles and descriptions length in the DB
+const maxFieldLength = 255
+
+func (h *Handler) propfind(w http.ResponseWriter, r *http.Request, userID int, res resource) error {
+	var req propfindRequest
+	if err := decodeBody(r, &req); err != nil {
+		return err
+	}
+	// infinite depth is served as depth 1, there are no deeper collections anyway
+	children := r.Header.Get("Depth") != "0"
+	sel := newSelector(req.AllProp != nil, req.PropName != nil, req.Prop)
+	ms := &multistatus{}
+	switch res.kind {
+	case rootResource:
+		ms.add(sel.response(BasePath+"/", rootProps()))
+		if children {
+			ms.add(sel.response(principalHref(), principalProps()))
+			ms.add(sel.response(homeHref(), homeProps()))
+		}
+	case principalResource:
+		ms.add(sel.response(principalHref(), principalProps()))
+	case homeResource:
+		ms.add(sel.response(homeHref(), homeProps()))
+		if children {
+			lists, err := h.lists.GetAllLists(userID)
+			if err != nil {
+				return err
+			}
+			for _, list := range lists {
+				lastSeq, err := h.events.GetLastEventSeq(list.ID)
+				if err != nil {
+					return err
+				}
+				ms.add(sel.response(calendarHref(list.ID), calendarProps(list, lastSeq)))
+			}
+		}
+	case calendarResource:
+		list, err := h.list(userID, res.listID)
+		if err != nil {
+			return err
+		}
+		lastSeq, err := h.events.GetLastEventSeq(list.ID)
+		if err != nil {
+			return err
+		}
+		ms.add(sel.response(calendarHref(list.ID), calendarProps(list, lastSeq)))
+		if children {
+			todos, err := h.todos.GetAllTodos(list.ID)
+			if err != nil {
+				return err
+			}
+			for _, t := range todos {
+				ms.add(sel.response(objectHref(list.ID, objectName(t)), objectProps(t)))
+			}
+		}
+	case objectResource:
+		t, err := h.object(userID, res)
+		if err != nil {
+			return err
+		}
+		ms.add(sel.response(objectHref(res.listID, objectName(t)), objectProps(t)))
+	}
+	ms.write(w)
+	return nil
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, userID int, res resource) error {
+	if res.kind != objectResource {
+		return newHTTPError(http.StatusMethodNotAllowed, "only calendar objects can be fetched")
+	}
+	t, err := h.object(userID, res)
+	if err != nil {
+		return err
+	}
+	data := exporter.CalendarObject(t)
+	w.Header().Set("Content-Type", calendarContentType)
+	w.Header().Set("ETag", etag(data))
+	// handles HEAD and conditional requests
+	http.ServeContent(w, r, "", t.UpdatedAt, strings.NewReader(data))
+	return nil
+}
+
+// put creates or replaces the todo with the content of VTODO component. Response
+// has no ETag because the todo keeps only some properties of the component,
+// so clients have to fetch the stored version
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, userID int, res resource) error {
+	if res.kind != objectResource {
+		return newHTTPError(http.StatusMethodNotAllowed, "only calendar objects can be stored")
+	}
+	if _, err := h.list(userID, res.listID); err != nil {
+		return err
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	vt, err := parseVTODO(string(body))
+	if errors.Is(err, errNoVTODO) {
+		return newPreconditionError(http.StatusForbidden, condSupportedComponent, err.Error())
+	}
+	if err != nil {
+		return newPreconditionError(http.StatusBadRequest, condValidCalendarData, err.Error())
+	}
+	if err := validateVTODO(vt); err != nil {
+		return err
+	}
+	existing, err := h.todos.GetTodoByUID(res.listID, res.name)
+	exists := err == nil
+	if err := checkPreconditions(r, existing, exists); err != nil {
+		return err
+	}
+	if !exists {
+		if vt.uid != "" && vt.uid != res.name {
+			return newPreconditionError(http.StatusBadRequest, condValidCalendarObject, "resource name must match UID of the todo")
+		}
+		todo := core.TodoItem{
+			Title:       vt.summary,
+			Description: vt.description,
+			Done:        vt.done,
+			Due:         vt.due,
+			UID:         res.name,
+			Priority:    vt.priority,
+			Labels:      vt.categories,
+		}
+		if core.ValidRecurrence(vt.rrule) {
+			todo.Recurrence = vt.rrule
+		}
+		if _, err := h.todos.CreateTodo(userID, res.listID, todo); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+	data := core.UpdateItemData{
+		Title:       &vt.summary,
+		Description: &vt.description,
+		Done:        &vt.done,
+		Due:         vt.due,
+		ClearDue:    vt.due == nil,
+		Priority:    &vt.priority,
+		Labels:      &vt.categories,
+	}
+	// recurrences which todos don't support are kept as they are
+	if core.ValidRecurrence(vt.rrule) {
+		data.Recurrence = &vt.rrule
+	}
+	if _, err := h.todos.UpdateTodo(userID, res.listID, existing.ID, data); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, userID int, res resource) error {
+	if res.kind != objectResource {
+		return newHTTPError(http.StatusForbidden, "only calendar objects can be deleted")
+	}
+	t, err := h.object(userID, res)
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(r, t, true); err != nil {
+		return err
+	}
+	if err := h.todos.DeleteTodo(userID, res.listID, t.ID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// list returns list of the user by ID
+func (h *Handler) list(userID, listID int) (core.Todolist, error) {
+	list, err := h.lists.GetListByID(userID, listID)
+	if err != nil {
+		return list, errNotFound
+	}
+	return list, nil
+}
+
+// object returns todo which the resource refers to
+func (h *Handler) object(userID int, res resource) (core.TodoItem, error) {
+	if _, err := h.list(userID, res.listID); err != nil {
+		return core.TodoItem{}, err
+	}
+	t, err := h.todos.GetTodoByUID(res.listID, res.name)
+	if err != nil {
+		return t, errNotFound
+	}
+	return t, nil
+}
+
+// checkPreconditions validates If-Match and If-None-Match headers against the
+// current state of the todo, so clients don't overwrite changes of each other
+func checkPreconditions(r *http.Request, t core.TodoItem, exists bool) error {
+	ifMatch := r.Header.Get("If-Match")
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifMatch == "" && ifNoneMatch == "" {
+		return nil
+	}
+	current := ""
+	if exists {
+		current = etag(exporter.CalendarObject(t))
+	}
+	if ifMatch != "" && (!exists || !matchETag(ifMatch, current)) {
+		return newHTTPError(http.StatusPreconditionFailed, "resource has been changed")
+	}
+	if ifNoneMatch != "" && exists && matchETag(ifNoneMatch, current) {
+		return newHTTPError(http.StatusPreconditionFailed, "resource already exists")
+	}
+	return nil
+}
+
+// matchETag reports whether the list of entity tags from the header contains the
+// given one. Some clients drop quotes of the tags, so they're not compared
+func matchETag(header, tag string) bool {
+	tag = strings.Trim(tag, `"`)
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.Trim(strings.TrimPrefix(v, "W/"), `"`) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func validateVTODO(vt vtodo) error {
+	if strings.TrimSpace(vt.summary) == "" {
+		return newHTTPError(http.StatusBadRequest, "todo must have SUMMARY")
+	}
+	if utf8.RuneCountInString(vt.summary) > maxFieldLength {
+		return newHTTPError(http.StatusBadRequest, fmt.Sprintf("SUMMARY is longer than %d characters", maxFieldLength))
+	}
+	if utf8.RuneCountInString(vt.description) > maxFieldLength {
+		return newHTTPError(http.StatusBadRequest, fmt.Sprintf("DESCRIPTION is longer than %d characters", maxFieldLength))
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package caldav
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/exporter"
+)
+
+const (
+	calendarContentType = "text/calendar; charset=utf-8; component=vtodo"
+	syncTokenPrefix     = "urn:todo-app:sync:"
+)
+
+var (
+	propResourceType          = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName           = xml.Name{Space: nsDAV, Local: "displayname"}
+	propCurrentUserPrincipal  = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL          = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propOwner                 = xml.Name{Space: nsDAV, Local: "owner"}
+	propCurrentUserPrivileges = xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}
+	propSupportedReportSet    = xml.Name{Space: nsDAV, Local: "supported-report-set"}
+	propSyncToken             = xml.Name{Space: nsDAV, Local: "sync-token"}
+	propGetETag               = xml.Name{Space: nsDAV, Local: "getetag"}
+	propGetContentType        = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propGetContentLength      = xml.Name{Space: nsDAV, Local: "getcontentlength"}
+	propGetLastModified       = xml.Name{Space: nsDAV, Local: "getlastmodified"}
+	propCalendarHomeSet       = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propCalendarDescription   = xml.Name{Space: nsCalDAV, Local: "calendar-description"}
+	propSupportedComponents   = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propCalendarData          = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propGetCTag               = xml.Name{Space: nsCS, Local: "getctag"}
+)
+
+// Preconditions reported in DAV:error bodies
+var (
+	condValidSyncToken      = xml.Name{Space: nsDAV, Local: "valid-sync-token"}
+	condSupportedReport     = xml.Name{Space: nsDAV, Local: "supported-report"}
+	condSupportedComponent  = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component"}
+	condValidCalendarData   = xml.Name{Space: nsCalDAV, Local: "valid-calendar-data"}
+	condValidCalendarObject = xml.Name{Space: nsCalDAV, Local: "valid-calendar-object-resource"}
+)
+
+const privileges = "<d:privilege><d:read/></d:privilege>" +
+	"<d:privilege><d:write/></d:privilege>" +
+	"<d:privilege><d:write-content/></d:privilege>" +
+	"<d:privilege><d:bind/></d:privilege>" +
+	"<d:privilege><d:unbind/></d:privilege>" +
+	"<d:privilege><d:read-current-user-privilege-set/></d:privilege>"
+
+const supportedReports = "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+	"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>" +
+	"<d:supported-report><d:report><d:sync-collection/></d:report></d:supported-report>"
+
+// selector picks properties which client asked for
+type selector struct {
+	names    propNames
+	allProp  bool
+	propName bool
+}
+
+func newSelector(allProp, propName bool, names propNames) selector {
+	return selector{names: names, allProp: allProp || (!propName && len(names) == 0), propName: propName}
+}
+
+// response returns response element of the resource with the requested properties
+func (s selector) response(href string, props []prop) response {
+	resp := response{href: href}
+	switch {
+	case s.propName:
+		for _, p := range props {
+			resp.found = append(resp.found, prop{name: p.name})
+		}
+	case s.allProp:
+		for _, p := range props {
+			// calendar data is too heavy, so clients have to ask for it explicitly
+			if p.name != propCalendarData {
+				resp.found = append(resp.found, p)
+			}
+		}
+	default:
+		for _, name := range s.names {
+			if p, ok := findProp(props, name); ok {
+				resp.found = append(resp.found, p)
+			} else {
+				resp.missing = append(resp.missing, name)
+			}
+		}
+	}
+	return resp
+}
+
+func findProp(props []prop, name xml.Name) (prop, bool) {
+	for _, p := range props {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return prop{}, false
+}
+
+func hrefValue(href string) string {
+	return "<d:href>" + escape(href) + "</d:href>"
+}
+
+func rootProps() []prop {
+	return []prop{
+		{propResourceType, "<d:collection/>"},
+		{propCurrentUserPrincipal, hrefValue(principalHref())},
+		{propCalendarHomeSet, hrefValue(homeHref())},
+	}
+}
+
+func principalProps() []prop {
+	return []prop{
+		{propResourceType, "<d:principal/>"},
+		{propCurrentUserPrincipal, hrefValue(principalHref())},
+		{propPrincipalURL, hrefValue(principalHref())},
+		{propCalendarHomeSet, hrefValue(homeHref())},
+	}
+}
+
+func homeProps() []prop {
+	return []prop{
+		{propResourceType, "<d:collection/>"},
+		{propCurrentUserPrincipal, hrefValue(principalHref())},
+		{propOwner, hrefValue(principalHref())},
+		{propCurrentUserPrivileges, privileges},
+	}
+}
+
+func calendarProps(list core.Todolist, lastSeq int64) []prop {
+	token := escape(syncToken(lastSeq))
+	return []prop{
+		{propResourceType, "<d:collection/><c:calendar/>"},
+		{propDisplayName, escape(list.Title)},
+		{propCalendarDescription, escape(list.Description)},
+		{propSupportedComponents, `<c:comp name="VTODO"/>`},
+		{propSupportedReportSet, supportedReports},
+		{propCurrentUserPrincipal, hrefValue(principalHref())},
+		{propOwner, hrefValue(principalHref())},
+		{propCurrentUserPrivileges, privileges},
+		{propSyncToken, token},
+		{propGetCTag, token},
+	}
+}
+
+func objectProps(t core.TodoItem) []prop {
+	data := exporter.CalendarObject(t)
+	return []prop{
+		{propResourceType, ""},
+		{propGetETag, escape(etag(data))},
+		{propGetContentType, calendarContentType},
+		{propGetContentLength, strconv.Itoa(len(data))},
+		{propGetLastModified, t.UpdatedAt.UTC().Format(http.TimeFormat)},
+		{propCalendarData, escape(data)},
+	}
+}
+
+// etag returns strong entity tag of the calendar object
+func etag(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// syncToken returns token which identifies state of the calendar by its latest event
+func syncToken(lastSeq int64) string {
+	return syncTokenPrefix + strconv.FormatInt(lastSeq, 10)
+}
+
+func parseSyncToken(token string) (int64, bool) {
+	if !strings.HasPrefix(token, syncTokenPrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(token, syncTokenPrefix), 10, 64)
+	if err != nil || id < 0 {
+		return 0, false
+	}
+	return id, true
+}
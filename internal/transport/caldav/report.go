@@ -0,0 +1,211 @@
+package caldav
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+var (
+	reportCalendarQuery    = xml.Name{Space: nsCalDAV, Local: "calendar-query"}
+	reportCalendarMultiget = xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}
+	reportSyncCollection   = xml.Name{Space: nsDAV, Local: "sync-collection"}
+)
+
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, userID int, res resource) error {
+	if res.kind != calendarResource {
+		return newPreconditionError(http.StatusForbidden, condSupportedReport, "reports are supported by calendars only")
+	}
+	list, err := h.list(userID, res.listID)
+	if err != nil {
+		return err
+	}
+	var req reportRequest
+	if err := decodeBody(r, &req); err != nil {
+		return err
+	}
+	sel := newSelector(req.AllProp != nil, false, req.Prop)
+	var ms *multistatus
+	switch req.XMLName {
+	case reportCalendarQuery:
+		ms, err = h.calendarQuery(list, req, sel)
+	case reportCalendarMultiget:
+		ms, err = h.calendarMultiget(list, req, sel)
+	case reportSyncCollection:
+		ms, err = h.syncCollection(list, req, sel)
+	default:
+		return newPreconditionError(http.StatusForbidden, condSupportedReport, "unsupported report")
+	}
+	if err != nil {
+		return err
+	}
+	ms.write(w)
+	return nil
+}
+
+// calendarQuery returns todos which match the filter. Only component filters and
+// presence of the properties are applied, so the result can be wider than asked
+func (h *Handler) calendarQuery(list core.Todolist, req reportRequest, sel selector) (*multistatus, error) {
+	todos, err := h.todos.GetAllTodos(list.ID)
+	if err != nil {
+		return nil, err
+	}
+	ms := &multistatus{}
+	for _, t := range todos {
+		if matchFilter(req.Filter, t) {
+			ms.add(sel.response(objectHref(list.ID, objectName(t)), objectProps(t)))
+		}
+	}
+	return ms, nil
+}
+
+// calendarMultiget returns todos by their hrefs
+func (h *Handler) calendarMultiget(list core.Todolist, req reportRequest, sel selector) (*multistatus, error) {
+	ms := &multistatus{}
+	for _, href := range req.Hrefs {
+		u, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			ms.add(response{href: href, status: http.StatusNotFound})
+			continue
+		}
+		res, ok := parsePath(u.EscapedPath())
+		if !ok || res.kind != objectResource || res.listID != list.ID {
+			ms.add(response{href: href, status: http.StatusNotFound})
+			continue
+		}
+		t, err := h.todos.GetTodoByUID(list.ID, res.name)
+		if err != nil {
+			ms.add(response{href: href, status: http.StatusNotFound})
+			continue
+		}
+		ms.add(sel.response(href, objectProps(t)))
+	}
+	return ms, nil
+}
+
+// syncCollection returns todos which were changed since the state identified by
+// the sync token. Changes are taken from the list's events, the token without
+// changes returns all todos of the list
+func (h *Handler) syncCollection(list core.Todolist, req reportRequest, sel selector) (*multistatus, error) {
+	latest, err := h.events.GetLastEventSeq(list.ID)
+	if err != nil {
+		return nil, err
+	}
+	ms := &multistatus{syncToken: syncToken(latest)}
+	if req.SyncToken == "" {
+		todos, err := h.todos.GetAllTodos(list.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range todos {
+			ms.add(sel.response(objectHref(list.ID, objectName(t)), objectProps(t)))
+		}
+		return ms, nil
+	}
+	since, ok := parseSyncToken(req.SyncToken)
+	if !ok {
+		return nil, newPreconditionError(http.StatusForbidden, condValidSyncToken, "invalid sync token")
+	}
+	events, err := h.events.GetEventsSince(list.ID, since)
+	if err != nil {
+		return nil, err
+	}
+	// names of the changed todos by their IDs
+	changed := make(map[int]string)
+	deleted := make(map[int]string)
+	for _, e := range events {
+		if e.TodoID == 0 {
+			continue
+		}
+		name := eventObjectName(e)
+		if e.Type == core.EventTodoDeleted {
+			delete(changed, e.TodoID)
+			deleted[e.TodoID] = name
+		} else {
+			delete(deleted, e.TodoID)
+			changed[e.TodoID] = name
+		}
+	}
+	if len(changed) > 0 {
+		todos, err := h.todos.GetAllTodos(list.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range todos {
+			if _, ok := changed[t.ID]; ok {
+				ms.add(sel.response(objectHref(list.ID, objectName(t)), objectProps(t)))
+				delete(changed, t.ID)
+			}
+		}
+		// the rest were deleted after the latest event had been read
+		for id, name := range changed {
+			deleted[id] = name
+		}
+	}
+	for _, name := range deleted {
+		ms.add(response{href: objectHref(list.ID, name), status: http.StatusNotFound})
+	}
+	ms.syncToken = syncToken(since)
+	if len(events) > 0 {
+		last := events[len(events)-1].Seq
+		ms.syncToken = syncToken(last)
+		if last < latest {
+			// there are more changes than a single response holds, the client
+			// repeats the report with the new token to get the rest of them
+			ms.add(response{href: calendarHref(list.ID), status: http.StatusInsufficientStorage})
+		}
+	}
+	return ms, nil
+}
+
+// eventObjectName returns name of the todo's resource from the event's data
+func eventObjectName(e core.Event) string {
+	var t core.TodoItem
+	if err := json.Unmarshal(e.Data, &t); err != nil || t.ID == 0 {
+		return strconv.Itoa(e.TodoID)
+	}
+	return objectName(t)
+}
+
+// matchFilter reports whether the todo matches comp-filter of VCALENDAR
+func matchFilter(f *compQuery, t core.TodoItem) bool {
+	if f == nil {
+		return true
+	}
+	if !strings.EqualFold(f.Name, "VCALENDAR") {
+		return false
+	}
+	for _, c := range f.Comps {
+		if !strings.EqualFold(c.Name, "VTODO") {
+			return false
+		}
+		for _, p := range c.Props {
+			if hasProperty(t, p.Name) == (p.IsNotDefined != nil) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hasProperty reports whether VTODO of the todo has the property
+func hasProperty(t core.TodoItem, name string) bool {
+	switch strings.ToUpper(name) {
+	case "UID", "DTSTAMP", "SUMMARY", "STATUS":
+		return true
+	case "COMPLETED", "PERCENT-COMPLETE":
+		return t.Done
+	case "DESCRIPTION":
+		return t.Description != ""
+	case "DUE":
+		return t.Due != nil
+	case "LAST-MODIFIED":
+		return !t.UpdatedAt.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+)
+
+// prefixes are namespace prefixes used in responses
+var prefixes = map[string]string{
+	nsDAV:    "d",
+	nsCalDAV: "c",
+	nsCS:     "cs",
+}
+
+// propNames is a list of properties which client asks for
+type propNames []xml.Name
+
+func (p *propNames) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			*p = append(*p, t.Name)
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+type propfindRequest struct {
+	XMLName  xml.Name  `xml:"DAV: propfind"`
+	AllProp  *struct{} `xml:"DAV: allprop"`
+	PropName *struct{} `xml:"DAV: propname"`
+	Prop     propNames `xml:"DAV: prop"`
+}
+
+// reportRequest holds elements of all supported reports
+type reportRequest struct {
+	XMLName   xml.Name
+	AllProp   *struct{}  `xml:"DAV: allprop"`
+	Prop      propNames  `xml:"DAV: prop"`
+	Hrefs     []string   `xml:"DAV: href"`
+	SyncToken string     `xml:"DAV: sync-token"`
+	Filter    *compQuery `xml:"urn:ietf:params:xml:ns:caldav filter>comp-filter"`
+}
+
+// compQuery is a comp-filter element of calendar-query report
+type compQuery struct {
+	Name  string      `xml:"name,attr"`
+	Comps []compQuery `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	Props []propQuery `xml:"urn:ietf:params:xml:ns:caldav prop-filter"`
+}
+
+// propQuery is a prop-filter element of calendar-query report
+type propQuery struct {
+	Name         string    `xml:"name,attr"`
+	IsNotDefined *struct{} `xml:"urn:ietf:params:xml:ns:caldav is-not-defined"`
+}
+
+// decodeBody decodes XML request body into v, empty body leaves v untouched
+func decodeBody(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := xml.Unmarshal(body, v); err != nil {
+		return newHTTPError(http.StatusBadRequest, "malformed XML body")
+	}
+	return nil
+}
+
+// prop is a property with its value in the form of XML content
+type prop struct {
+	name  xml.Name
+	value string
+}
+
+// response is a response element of the multistatus, resource has either
+// status or properties
+type response struct {
+	href    string
+	status  int
+	found   []prop
+	missing []xml.Name
+}
+
+// multistatus builds body of 207 Multi-Status response
+type multistatus struct {
+	responses []response
+	syncToken string
+}
+
+func (ms *multistatus) add(r response) {
+	ms.responses = append(ms.responses, r)
+}
+
+func (ms *multistatus) write(w http.ResponseWriter) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+	for _, r := range ms.responses {
+		b.WriteString("<d:response>")
+		b.WriteString("<d:href>" + escape(r.href) + "</d:href>")
+		if r.status != 0 {
+			b.WriteString("<d:status>" + statusLine(r.status) + "</d:status>")
+		}
+		if len(r.found) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, p := range r.found {
+				writeElement(&b, p.name, p.value)
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusOK) + "</d:status></d:propstat>")
+		}
+		if len(r.missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range r.missing {
+				writeElement(&b, name, "")
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusNotFound) + "</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	if ms.syncToken != "" {
+		b.WriteString("<d:sync-token>" + escape(ms.syncToken) + "</d:sync-token>")
+	}
+	b.WriteString("</d:multistatus>")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
+
+// writeError replies with DAV:error body which names the failed precondition
+func writeError(w http.ResponseWriter, code int, condition xml.Name) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<d:error xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+	writeElement(&b, condition, "")
+	b.WriteString("</d:error>")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(code)
+	io.WriteString(w, b.String())
+}
+
+// writeElement writes element with already encoded content
+func writeElement(b *strings.Builder, name xml.Name, content string) {
+	tag := name.Local
+	attrs := ""
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		attrs = ` xmlns="` + escape(name.Space) + `"`
+	}
+	if content == "" {
+		fmt.Fprintf(b, "<%s%s/>", tag, attrs)
+		return
+	}
+	fmt.Fprintf(b, "<%s%s>%s</%s>", tag, attrs, content, tag)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
@@ -0,0 +1,56 @@
+// Package graphql implements GraphQL API on top of the application services
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+//go:embed schema.graphql
+var schema string
+
+type TodoListService interface {
+	CreateList(userID int, list core.Todolist) (core.Todolist, error)
+	GetAllLists(userID int) ([]core.Todolist, error)
+	GetListByID(userID, listID int) (core.Todolist, error)
+	UpdateList(userID, listID int, data core.UpdateListData) (core.Todolist, error)
+	DeleteList(userID, listID int) error
+}
+
+type TodoItemService interface {
+	CreateTodo(userID, listID int, todo core.TodoItem) (core.TodoItem, error)
+	GetTodosByListIDs(listIDs []int) (map[int][]core.TodoItem, error)
+	GetTodoByID(listID, todoID int) (core.TodoItem, error)
+	UpdateTodo(userID, listID, todoID int, data core.UpdateItemData) (core.TodoItem, error)
+	DeleteTodo(userID, listID, todoID int) error
+}
+
+// Deps represents external dependencies for GraphQL resolvers
+type Deps struct {
+	TodoListService TodoListService
+	TodoItemService TodoItemService
+}
+
+// NewHandler returns http handler which serves GraphQL queries.
+// It expects the user to be already authenticated by the rest middleware
+func NewHandler(deps Deps) http.Handler {
+	s := gql.MustParseSchema(schema, &Resolver{
+		lists: deps.TodoListService,
+		todos: deps.TodoItemService,
+	})
+	return withLoaders(&relay.Handler{Schema: s}, deps.TodoItemService)
+}
+
+// withLoaders is a middleware that attaches fresh dataloaders to every request,
+// so batched results are never shared between users or requests
+func withLoaders(next http.Handler, todos TodoItemService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), loaderCtx, newTodoLoader(todos.GetTodosByListIDs))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
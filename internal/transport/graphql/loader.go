@@ -0,0 +1,70 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+// Key to use when setting the loaders context.
+type ctxKeyLoader string
+
+const loaderCtx ctxKeyLoader = "todoLoader"
+
+var errLoaderNotFound = errors.New("todo loader not found")
+
+// todoLoader collects IDs of all lists resolved during a single request and
+// fetches their todos with one query when the first of them is requested
+type todoLoader struct {
+	fetch   func(listIDs []int) (map[int][]core.TodoItem, error)
+	mu      sync.Mutex
+	pending []int
+	cache   map[int][]core.TodoItem
+}
+
+func newTodoLoader(fetch func(listIDs []int) (map[int][]core.TodoItem, error)) *todoLoader {
+	return &todoLoader{
+		fetch: fetch,
+		cache: make(map[int][]core.TodoItem),
+	}
+}
+
+func loaderFrom(ctx context.Context) (*todoLoader, error) {
+	l, ok := ctx.Value(loaderCtx).(*todoLoader)
+	if !ok {
+		return nil, errLoaderNotFound
+	}
+	return l, nil
+}
+
+// prime registers lists which todos are going to be loaded within the same batch
+func (l *todoLoader) prime(listIDs ...int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range listIDs {
+		if _, ok := l.cache[id]; !ok {
+			l.pending = append(l.pending, id)
+		}
+	}
+}
+
+// load returns todos of the given list, fetching the whole pending batch if needed
+func (l *todoLoader) load(listID int) ([]core.TodoItem, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if todos, ok := l.cache[listID]; ok {
+		return todos, nil
+	}
+	keys := append(l.pending, listID)
+	todos, err := l.fetch(keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range keys {
+		l.cache[id] = todos[id]
+	}
+	l.pending = nil
+	return l.cache[listID], nil
+}
@@ -0,0 +1,327 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/vbetsun/todo-app/internal/auth"
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+var (
+	errUserNotFound = errors.New("userID not found")
+	errForbidden    = errors.New("access token doesn't have the required scopes")
+)
+
+// Resolver is a root resolver for queries and mutations
+type Resolver struct {
+	lists TodoListService
+	todos TodoItemService
+}
+
+type listResolver struct {
+	list  core.Todolist
+	todos TodoItemService
+}
+
+type todoResolver struct {
+	todo core.TodoItem
+}
+
+type createListInput struct {
+	Title       string
+	Description *string
+}
+
+type updateListInput struct {
+	Title       *string
+	Description *string
+}
+
+type createTodoInput struct {
+	Title       string
+	Description *string
+	Due         *gql.Time
+}
+
+type updateTodoInput struct {
+	Title       *string
+	Description *string
+	Done        *bool
+	Due         *gql.Time
+}
+
+func (r *Resolver) Lists(ctx context.Context) ([]*listResolver, error) {
+	userID, ok := auth.UserID(ctx)
+	if !ok {
+		return nil, errUserNotFound
+	}
+	lists, err := r.lists.GetAllLists(userID)
+	if err != nil {
+		return nil, err
+	}
+	loader, err := loaderFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*listResolver, 0, len(lists))
+	for _, l := range lists {
+		loader.prime(l.ID)
+		res = append(res, &listResolver{l, r.todos})
+	}
+	return res, nil
+}
+
+func (r *Resolver) List(ctx context.Context, args struct{ ID gql.ID }) (*listResolver, error) {
+	list, err := r.userList(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &listResolver{list, r.todos}, nil
+}
+
+func (r *Resolver) CreateList(ctx context.Context, args struct{ Input createListInput }) (*listResolver, error) {
+	userID, err := authorize(ctx, core.ScopeListsWrite)
+	if err != nil {
+		return nil, err
+	}
+	list := core.Todolist{Title: args.Input.Title}
+	if args.Input.Description != nil {
+		list.Description = *args.Input.Description
+	}
+	list, err = r.lists.CreateList(userID, list)
+	if err != nil {
+		return nil, err
+	}
+	return &listResolver{list, r.todos}, nil
+}
+
+func (r *Resolver) UpdateList(ctx context.Context, args struct {
+	ID    gql.ID
+	Input updateListInput
+}) (*listResolver, error) {
+	if args.Input.Title == nil && args.Input.Description == nil {
+		return nil, errors.New("you should provide one of Title or Description")
+	}
+	userID, err := authorize(ctx, core.ScopeListsWrite)
+	if err != nil {
+		return nil, err
+	}
+	list, err := r.userList(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	list, err = r.lists.UpdateList(userID, list.ID, core.UpdateListData{
+		Title:       args.Input.Title,
+		Description: args.Input.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &listResolver{list, r.todos}, nil
+}
+
+func (r *Resolver) DeleteList(ctx context.Context, args struct{ ID gql.ID }) (bool, error) {
+	userID, err := authorize(ctx, core.ScopeListsWrite)
+	if err != nil {
+		return false, err
+	}
+	list, err := r.userList(ctx, args.ID)
+	if err != nil {
+		return false, err
+	}
+	if err := r.lists.DeleteList(userID, list.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *Resolver) CreateTodo(ctx context.Context, args struct {
+	ListID gql.ID
+	Input  createTodoInput
+}) (*todoResolver, error) {
+	userID, err := authorize(ctx, core.ScopeTodosWrite)
+	if err != nil {
+		return nil, err
+	}
+	list, err := r.userList(ctx, args.ListID)
+	if err != nil {
+		return nil, err
+	}
+	todo := core.TodoItem{Title: args.Input.Title, Due: toTime(args.Input.Due)}
+	if args.Input.Description != nil {
+		todo.Description = *args.Input.Description
+	}
+	todo, err = r.todos.CreateTodo(userID, list.ID, todo)
+	if err != nil {
+		return nil, err
+	}
+	return &todoResolver{todo}, nil
+}
+
+func (r *Resolver) UpdateTodo(ctx context.Context, args struct {
+	ListID gql.ID
+	ID     gql.ID
+	Input  updateTodoInput
+}) (*todoResolver, error) {
+	in := args.Input
+	if in.Title == nil && in.Description == nil && in.Done == nil && in.Due == nil {
+		return nil, errors.New("you should provide one of Title, Description, Done or Due")
+	}
+	userID, err := authorize(ctx, core.ScopeTodosWrite)
+	if err != nil {
+		return nil, err
+	}
+	list, todo, err := r.userTodo(ctx, args.ListID, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	todo, err = r.todos.UpdateTodo(userID, list.ID, todo.ID, core.UpdateItemData{
+		Title:       in.Title,
+		Description: in.Description,
+		Done:        in.Done,
+		Due:         toTime(in.Due),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &todoResolver{todo}, nil
+}
+
+func (r *Resolver) DeleteTodo(ctx context.Context, args struct {
+	ListID gql.ID
+	ID     gql.ID
+}) (bool, error) {
+	userID, err := authorize(ctx, core.ScopeTodosWrite)
+	if err != nil {
+		return false, err
+	}
+	list, todo, err := r.userTodo(ctx, args.ListID, args.ID)
+	if err != nil {
+		return false, err
+	}
+	if err := r.todos.DeleteTodo(userID, list.ID, todo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// authorize returns ID of the current user if the credentials grant the scopes
+func authorize(ctx context.Context, scopes ...string) (int, error) {
+	userID, ok := auth.UserID(ctx)
+	if !ok {
+		return 0, errUserNotFound
+	}
+	if !auth.HasScopes(ctx, scopes...) {
+		return 0, errForbidden
+	}
+	return userID, nil
+}
+
+// userList returns the list only if it belongs to the current user
+func (r *Resolver) userList(ctx context.Context, id gql.ID) (core.Todolist, error) {
+	userID, ok := auth.UserID(ctx)
+	if !ok {
+		return core.Todolist{}, errUserNotFound
+	}
+	listID, err := parseID(id)
+	if err != nil {
+		return core.Todolist{}, err
+	}
+	return r.lists.GetListByID(userID, listID)
+}
+
+// userTodo returns the todo and its list only if the list belongs to the current user
+func (r *Resolver) userTodo(ctx context.Context, listID, id gql.ID) (core.Todolist, core.TodoItem, error) {
+	list, err := r.userList(ctx, listID)
+	if err != nil {
+		return list, core.TodoItem{}, err
+	}
+	todoID, err := parseID(id)
+	if err != nil {
+		return list, core.TodoItem{}, err
+	}
+	todo, err := r.todos.GetTodoByID(list.ID, todoID)
+	return list, todo, err
+}
+
+func (r *listResolver) ID() gql.ID {
+	return formatID(r.list.ID)
+}
+
+func (r *listResolver) Title() string {
+	return r.list.Title
+}
+
+func (r *listResolver) Description() string {
+	return r.list.Description
+}
+
+func (r *listResolver) Todos(ctx context.Context) ([]*todoResolver, error) {
+	loader, err := loaderFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	todos, err := loader.load(r.list.ID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*todoResolver, 0, len(todos))
+	for _, t := range todos {
+		res = append(res, &todoResolver{t})
+	}
+	return res, nil
+}
+
+func (r *listResolver) Todo(args struct{ ID gql.ID }) (*todoResolver, error) {
+	todoID, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := r.todos.GetTodoByID(r.list.ID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	return &todoResolver{todo}, nil
+}
+
+func (r *todoResolver) ID() gql.ID {
+	return formatID(r.todo.ID)
+}
+
+func (r *todoResolver) Title() string {
+	return r.todo.Title
+}
+
+func (r *todoResolver) Description() string {
+	return r.todo.Description
+}
+
+func (r *todoResolver) Done() bool {
+	return r.todo.Done
+}
+
+func (r *todoResolver) Due() *gql.Time {
+	if r.todo.Due == nil {
+		return nil
+	}
+	return &gql.Time{Time: *r.todo.Due}
+}
+
+func parseID(id gql.ID) (int, error) {
+	return strconv.Atoi(string(id))
+}
+
+func formatID(id int) gql.ID {
+	return gql.ID(strconv.Itoa(id))
+}
+
+func toTime(t *gql.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	return &t.Time
+}
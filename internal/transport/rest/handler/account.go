@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/exporter"
+	"go.uber.org/zap"
+)
+
+type AccountService interface {
+	GetUser(userID int) (core.User, error)
+	UpdateUser(userID int, data core.UpdateUserData) (core.User, error)
+	ChangePassword(userID int, current, password string) (string, error)
+	DeleteUser(userID int, w exporter.Writer) error
+}
+
+type AccountHandler struct {
+	service AccountService
+	log     *zap.Logger
+}
+
+type UpdateUserRequest struct {
+	*core.UpdateUserData
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type UserResponse struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Username      string `json:"username"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+func NewAccountHandler(service AccountService, log *zap.Logger) *AccountHandler {
+	return &AccountHandler{service, log}
+}
+
+func (uu *UpdateUserRequest) Bind(r *http.Request) error {
+	if uu.UpdateUserData == nil {
+		return errors.New("missing required User fields")
+	}
+	return nil
+}
+
+func (cp *ChangePasswordRequest) Bind(r *http.Request) error {
+	if cp.CurrentPassword == "" {
+		return errors.New("missing required CurrentPassword field")
+	}
+	if cp.NewPassword == "" {
+		return errors.New("missing required NewPassword field")
+	}
+	return nil
+}
+
+func (ur *UserResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func newUserResponse(u core.User) *UserResponse {
+	return &UserResponse{
+		ID:            u.ID,
+		Name:          u.Name,
+		Username:      u.Username,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+	}
+}
+
+func (h *AccountHandler) getMe(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	user, err := h.service.GetUser(userID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, newUserResponse(user)); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *AccountHandler) updateMe(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &UpdateUserRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	user, err := h.service.UpdateUser(userID, *data.UpdateUserData)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, newUserResponse(user)); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// changePassword responds with the new token, since all previous ones are revoked
+func (h *AccountHandler) changePassword(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &ChangePasswordRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	token, err := h.service.ChangePassword(userID, data.CurrentPassword, data.NewPassword)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &SignInResponse{Token: token}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// deleteMe removes the account and responds with JSON export of user's lists.
+// The export is buffered, so nothing is removed when it fails
+func (h *AccountHandler) deleteMe(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	var buf bytes.Buffer
+	ew, err := exporter.NewWriter(exporter.FormatJSON, &buf)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.DeleteUser(userID, ew); err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	w.Header().Set("Content-Type", exporter.ContentType(exporter.FormatJSON))
+	w.Header().Set("Content-Disposition", `attachment; filename="todos.json"`)
+	if _, err := buf.WriteTo(w); err != nil {
+		h.log.Error("can't write export of deleted account", zap.Int("userID", userID), zap.Error(err))
+	}
+}
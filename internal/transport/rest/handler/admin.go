@@ -0,0 +1,346 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// Key to use when setting the managed user context.
+type ctxKeyAdmin string
+
+const managedUserCtx ctxKeyAdmin = "managedUser"
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+type AdminService interface {
+	GetUsers(f core.UserFilter) ([]core.User, error)
+	GetUser(userID int) (core.User, error)
+	DisableUser(adminID, userID int) (core.User, error)
+	EnableUser(adminID, userID int) (core.User, error)
+	SetRole(adminID, userID int, role string) (core.User, error)
+	SignOutUser(adminID, userID int) error
+	Impersonate(adminID, userID int, reason string) (string, time.Time, error)
+	GetAuditLog(targetUserID, limit int) ([]core.AuditEntry, error)
+	GetUsageStats() (core.UsageStats, error)
+}
+
+type AdminHandler struct {
+	service AdminService
+	log     *zap.Logger
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason"`
+}
+
+type AdminUserResponse struct {
+	UserResponse
+	Role      string    `json:"role"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AllAdminUsersResponse struct {
+	Data []*AdminUserResponse `json:"data"`
+}
+
+type ImpersonateResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type UsageStatsResponse struct {
+	*core.UsageStats
+}
+
+type AuditLogResponse struct {
+	Data []core.AuditEntry `json:"data"`
+}
+
+func NewAdminHandler(service AdminService, log *zap.Logger) *AdminHandler {
+	return &AdminHandler{service, log}
+}
+
+func (sr *SetRoleRequest) Bind(r *http.Request) error {
+	if sr.Role == "" {
+		return errors.New("missing required Role field")
+	}
+	return nil
+}
+
+func (ir *ImpersonateRequest) Bind(r *http.Request) error {
+	if ir.Reason == "" {
+		return errors.New("missing required Reason field")
+	}
+	return nil
+}
+
+func (ur *AdminUserResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (au *AllAdminUsersResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(au.Data) == 0 {
+		au.Data = make([]*AdminUserResponse, 0)
+	}
+	return nil
+}
+
+func (ir *ImpersonateResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (sr *UsageStatsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (al *AuditLogResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(al.Data) == 0 {
+		al.Data = make([]core.AuditEntry, 0)
+	}
+	return nil
+}
+
+func newAdminUserResponse(u core.User) *AdminUserResponse {
+	return &AdminUserResponse{
+		UserResponse: *newUserResponse(u),
+		Role:         u.Role,
+		Disabled:     u.Disabled,
+		CreatedAt:    u.CreatedAt,
+	}
+}
+
+func (h *AdminHandler) managedUserCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		user, err := h.service.GetUser(userID)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		ctx := context.WithValue(r.Context(), managedUserCtx, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *AdminHandler) getUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := core.UserFilter{Query: q.Get("q")}
+	if raw := q.Get("disabled"); raw != "" {
+		disabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(errors.New("disabled should be a boolean"))); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		filter.Disabled = &disabled
+	}
+	if raw := q.Get("limit"); raw != "" {
+		filter.Limit, _ = strconv.Atoi(raw)
+	}
+	if raw := q.Get("offset"); raw != "" {
+		filter.Offset, _ = strconv.Atoi(raw)
+	}
+	users, err := h.service.GetUsers(filter)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	resp := &AllAdminUsersResponse{}
+	for _, u := range users {
+		resp.Data = append(resp.Data, newAdminUserResponse(u))
+	}
+	if err := render.Render(w, r, resp); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *AdminHandler) getUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(managedUserCtx).(core.User)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrUserNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, newAdminUserResponse(user)); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *AdminHandler) disableUser(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, h.service.DisableUser)
+}
+
+func (h *AdminHandler) enableUser(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, h.service.EnableUser)
+}
+
+func (h *AdminHandler) setRole(w http.ResponseWriter, r *http.Request) {
+	data := &SetRoleRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	h.updateUser(w, r, func(adminID, userID int) (core.User, error) {
+		return h.service.SetRole(adminID, userID, data.Role)
+	})
+}
+
+// updateUser applies the admin's action to the user from the context and
+// responds with the updated user
+func (h *AdminHandler) updateUser(w http.ResponseWriter, r *http.Request, action func(adminID, userID int) (core.User, error)) {
+	adminID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	user, ok := r.Context().Value(managedUserCtx).(core.User)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrUserNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	user, err = action(adminID, user.ID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, newAdminUserResponse(user)); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *AdminHandler) signOutUser(w http.ResponseWriter, r *http.Request) {
+	adminID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	user, ok := r.Context().Value(managedUserCtx).(core.User)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrUserNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.SignOutUser(adminID, user.ID); err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (h *AdminHandler) impersonate(w http.ResponseWriter, r *http.Request) {
+	adminID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &ImpersonateRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	user, ok := r.Context().Value(managedUserCtx).(core.User)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrUserNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	token, expiresAt, err := h.service.Impersonate(adminID, user.ID, data.Reason)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	h.log.Info("Impersonation started", zap.Int("adminID", adminID), zap.Int("userID", user.ID))
+	if err := render.Render(w, r, &ImpersonateResponse{Token: token, ExpiresAt: expiresAt}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *AdminHandler) getStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.GetUsageStats()
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &UsageStatsResponse{&stats}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *AdminHandler) getAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit, err := getLimit(r, defaultAuditLimit, maxAuditLimit)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	var targetUserID int
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		if targetUserID, err = strconv.Atoi(raw); err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(errors.New("user_id should be a number"))); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+	}
+	entries, err := h.service.GetAuditLog(targetUserID, limit)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AuditLogResponse{Data: entries}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// multipartOverhead is a room for headers and boundaries of the multipart body
+// over the size of the uploaded file
+const multipartOverhead = 1 << 20 // 1 Mb
+
+// Key to use when setting the attachment context.
+type ctxKeyAttachment string
+
+const attachmentCtx ctxKeyAttachment = "attachment"
+
+type AttachmentService interface {
+	MaxSize() int64
+	GetAttachments(todoID int) ([]core.Attachment, error)
+	GetAttachment(todoID, attachmentID int) (core.Attachment, error)
+	Upload(ctx context.Context, userID, listID, todoID int, filename string, size int64, r io.Reader) (core.Attachment, error)
+	Open(ctx context.Context, a core.Attachment) (io.ReadCloser, error)
+	DeleteAttachment(userID, listID int, a core.Attachment) error
+}
+
+type AttachmentHandler struct {
+	service AttachmentService
+	log     *zap.Logger
+}
+
+type AttachmentResponse struct {
+	*core.Attachment
+}
+
+type AllAttachmentsResponse struct {
+	Data []core.Attachment `json:"data"`
+}
+
+func NewAttachmentHandler(service AttachmentService, log *zap.Logger) *AttachmentHandler {
+	return &AttachmentHandler{service, log}
+}
+
+func (ar *AttachmentResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (aa *AllAttachmentsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(aa.Data) == 0 {
+		aa.Data = make([]core.Attachment, 0)
+	}
+	return nil
+}
+
+// attachmentCtx loads the attachment of the todo from the context
+func (h *AttachmentHandler) attachmentCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		todo, ok := r.Context().Value(todoCtx).(core.TodoItem)
+		if !ok {
+			if err := render.Render(w, r, ErrInternalServer(ErrTodoNotFound)); err != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		attachmentID, err := strconv.Atoi(chi.URLParam(r, "attachmentID"))
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		attachment, err := h.service.GetAttachment(todo.ID, attachmentID)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		ctx := context.WithValue(r.Context(), attachmentCtx, attachment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *AttachmentHandler) getAttachments(w http.ResponseWriter, r *http.Request) {
+	todo, ok := r.Context().Value(todoCtx).(core.TodoItem)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTodoNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	attachments, err := h.service.GetAttachments(todo.ID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllAttachmentsResponse{Data: attachments}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// uploadAttachment saves the file from the "file" field of the multipart form
+func (h *AttachmentHandler) uploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	todo, ok := r.Context().Value(todoCtx).(core.TodoItem)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTodoNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	limit := h.service.MaxSize() + multipartOverhead
+	if r.ContentLength > limit {
+		if rErr := render.Render(w, r, ErrTooLarge(ErrUploadTooLarge)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	defer file.Close()
+	if header.Size > h.service.MaxSize() {
+		if rErr := render.Render(w, r, ErrTooLarge(ErrUploadTooLarge)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	attachment, err := h.service.Upload(r.Context(), userID, list.ID, todo.ID, header.Filename, header.Size, file)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(w, r, &AttachmentResponse{Attachment: &attachment}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *AttachmentHandler) getAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := r.Context().Value(attachmentCtx).(core.Attachment)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrAttachmentNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AttachmentResponse{Attachment: &attachment}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// downloadAttachment streams content of the attachment, it's always served as
+// a download, so uploaded HTML can't be rendered by the browser on our origin
+func (h *AttachmentHandler) downloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := r.Context().Value(attachmentCtx).(core.Attachment)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrAttachmentNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	content, err := h.service.Open(r.Context(), attachment)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	defer content.Close()
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, content); err != nil {
+		h.log.Error("can't send attachment", zap.Int("attachmentID", attachment.ID), zap.Error(err))
+	}
+}
+
+func (h *AttachmentHandler) deleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	attachment, ok := r.Context().Value(attachmentCtx).(core.Attachment)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrAttachmentNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.DeleteAttachment(userID, list.ID, attachment); err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
@@ -11,8 +11,13 @@ import (
 
 type AuthService interface {
 	CreateUser(user core.User) (core.User, error)
-	GenerateToken(username, password string) (string, error)
-	ParseToken(token string) (int, error)
+	SignIn(username, password string) (core.SignIn, error)
+	VerifyChallenge(challenge, code string) (string, error)
+	EnrollTOTP(userID int) (core.TOTPEnrollment, error)
+	ConfirmTOTP(userID int, code string) ([]string, error)
+	DisableTOTP(userID int, code string) error
+	RegenerateRecoveryCodes(userID int, code string) ([]string, error)
+	Identify(token string) (core.Principal, error)
 }
 
 type AuthHandler struct {
@@ -33,10 +38,13 @@ type SignUpResponse struct {
 	ID       int    `json:"id"`
 	Name     string `json:"name"`
 	Username string `json:"username"`
+	Email    string `json:"email,omitempty"`
 }
 
 type SignInResponse struct {
-	Token string `json:"token"`
+	Token             string `json:"token,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
 }
 
 func NewAuthHandler(service AuthService, log *zap.Logger) *AuthHandler {
@@ -93,7 +101,7 @@ func (h *AuthHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	if err := render.Render(w, r, &SignUpResponse{ID: u.ID, Name: u.Name, Username: u.Username}); err != nil {
+	if err := render.Render(w, r, &SignUpResponse{ID: u.ID, Name: u.Name, Username: u.Username, Email: u.Email}); err != nil {
 		h.log.Error(ErrRenderResp.Error())
 	}
 }
@@ -106,14 +114,25 @@ func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	token, err := h.service.GenerateToken(data.Username, data.Password)
+	res, err := h.service.SignIn(data.Username, data.Password)
+	if ok, rErr := renderRetryError(w, r, err); ok {
+		if rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
 	if err != nil {
 		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
 			h.log.Error(ErrRenderResp.Error())
 		}
 		return
 	}
-	if err := render.Render(w, r, &SignInResponse{Token: token}); err != nil {
+	resp := &SignInResponse{
+		Token:             res.Token,
+		ChallengeToken:    res.ChallengeToken,
+		TwoFactorRequired: res.ChallengeToken != "",
+	}
+	if err := render.Render(w, r, resp); err != nil {
 		h.log.Error(ErrRenderResp.Error())
 	}
 }
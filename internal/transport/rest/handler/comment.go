@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// Key to use when setting the comment context.
+type ctxKeyComment string
+
+const commentCtx ctxKeyComment = "comment"
+
+type CommentService interface {
+	GetComments(todoID, limit, offset int) ([]core.Comment, error)
+	GetComment(todoID, commentID int) (core.Comment, error)
+	CreateComment(userID, listID, todoID int, body string) (core.Comment, error)
+	UpdateComment(userID, listID int, c core.Comment, body string) (core.Comment, error)
+	DeleteComment(userID, listID int, c core.Comment) error
+}
+
+type CommentHandler struct {
+	service CommentService
+	log     *zap.Logger
+}
+
+type CommentRequest struct {
+	Body string `json:"body"`
+}
+
+type CommentResponse struct {
+	*core.Comment
+}
+
+type AllCommentsResponse struct {
+	Data []core.Comment `json:"data"`
+}
+
+func NewCommentHandler(service CommentService, log *zap.Logger) *CommentHandler {
+	return &CommentHandler{service, log}
+}
+
+func (cr *CommentRequest) Bind(r *http.Request) error {
+	if cr.Body == "" {
+		return errors.New("missing required Body field")
+	}
+	return nil
+}
+
+func (cr *CommentResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (ac *AllCommentsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(ac.Data) == 0 {
+		ac.Data = make([]core.Comment, 0)
+	}
+	return nil
+}
+
+// commentCtx loads the comment of the todo from the context
+func (h *CommentHandler) commentCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		todo, ok := r.Context().Value(todoCtx).(core.TodoItem)
+		if !ok {
+			if err := render.Render(w, r, ErrInternalServer(ErrTodoNotFound)); err != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		commentID, err := strconv.Atoi(chi.URLParam(r, "commentID"))
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		comment, err := h.service.GetComment(todo.ID, commentID)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		ctx := context.WithValue(r.Context(), commentCtx, comment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// getComments returns page of the todo's comments, it's controlled by limit
+// and offset query parameters
+func (h *CommentHandler) getComments(w http.ResponseWriter, r *http.Request) {
+	todo, ok := r.Context().Value(todoCtx).(core.TodoItem)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTodoNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	var limit, offset int
+	q := r.URL.Query()
+	if raw := q.Get("limit"); raw != "" {
+		limit, _ = strconv.Atoi(raw)
+	}
+	if raw := q.Get("offset"); raw != "" {
+		offset, _ = strconv.Atoi(raw)
+	}
+	comments, err := h.service.GetComments(todo.ID, limit, offset)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllCommentsResponse{Data: comments}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *CommentHandler) createComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	todo, ok := r.Context().Value(todoCtx).(core.TodoItem)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTodoNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &CommentRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	comment, err := h.service.CreateComment(userID, list.ID, todo.ID, data.Body)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(w, r, &CommentResponse{Comment: &comment}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *CommentHandler) getComment(w http.ResponseWriter, r *http.Request) {
+	comment, ok := r.Context().Value(commentCtx).(core.Comment)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrCommentNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &CommentResponse{Comment: &comment}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *CommentHandler) updateComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	comment, ok := r.Context().Value(commentCtx).(core.Comment)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrCommentNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &CommentRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	comment, err = h.service.UpdateComment(userID, list.ID, comment, data.Body)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &CommentResponse{Comment: &comment}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *CommentHandler) deleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	comment, ok := r.Context().Value(commentCtx).(core.Comment)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrCommentNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.DeleteComment(userID, list.ID, comment); err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
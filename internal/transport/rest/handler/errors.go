@@ -8,9 +8,17 @@ import (
 )
 
 var (
-	ErrRenderResp   = errors.New("can't render response")
-	ErrListNotFound = errors.New("listID not found")
-	ErrTodoNotFound = errors.New("todoID not found")
+	ErrRenderResp         = errors.New("can't render response")
+	ErrListNotFound       = errors.New("listID not found")
+	ErrUserNotFound       = errors.New("userID not found")
+	ErrWorkspaceNotFound  = errors.New("workspaceID not found")
+	ErrTodoNotFound       = errors.New("todoID not found")
+	ErrCommentNotFound    = errors.New("commentID not found")
+	ErrAttachmentNotFound = errors.New("attachmentID not found")
+	ErrTemplateNotFound   = errors.New("templateID not found")
+	ErrShareLinkNotFound  = errors.New("linkID not found")
+	ErrSharePassword      = errors.New("link requires the valid password in the X-Share-Password header")
+	ErrUploadTooLarge     = errors.New("uploaded file is too large")
 )
 
 type ErrResponse struct {
@@ -41,6 +49,14 @@ func ErrUnauthorized(err error) render.Renderer {
 	}
 }
 
+func ErrForbidden(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: 403,
+		ErrorText:      err.Error(),
+	}
+}
+
 var ErrNotFound = &ErrResponse{HTTPStatusCode: 404, ErrorText: "Resource not found."}
 
 func ErrRender(err error) render.Renderer {
@@ -58,3 +74,35 @@ func ErrInternalServer(err error) render.Renderer {
 		ErrorText:      err.Error(),
 	}
 }
+
+func ErrTooLarge(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: 413,
+		ErrorText:      err.Error(),
+	}
+}
+
+func ErrTooManyRequests(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: 429,
+		ErrorText:      err.Error(),
+	}
+}
+
+// forbiddenError is implemented by errors caused by missing permissions
+type forbiddenError interface {
+	error
+	Forbidden() bool
+}
+
+// ErrService responds with 403 status if the error is caused by missing
+// permissions, other errors are rendered by the fallback
+func ErrService(err error, fallback func(error) render.Renderer) render.Renderer {
+	var ferr forbiddenError
+	if errors.As(err, &ferr) && ferr.Forbidden() {
+		return ErrForbidden(err)
+	}
+	return fallback(err)
+}
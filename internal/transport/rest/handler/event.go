@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/gorilla/websocket"
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/pubsub"
+	"go.uber.org/zap"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+	lastEventIDParam  = "last_event_id"
+	// streamHeartbeat is the interval of SSE comments which detect gone clients
+	// and keep proxies from closing idle streams
+	streamHeartbeat = 30 * time.Second
+	streamRetry     = 500 * time.Millisecond
+	wsPingInterval  = 30 * time.Second
+	wsWriteTimeout  = 10 * time.Second
+)
+
+// Key to use when setting the connection context.
+type ctxKeyConn string
+
+const connCtx ctxKeyConn = "conn"
+
+var errStreamingUnsupported = errors.New("streaming is not supported")
+
+type EventService interface {
+	Subscribe(listID int) *pubsub.Subscription
+	GetEventsSince(listID int, seq int64) ([]core.Event, error)
+	GetLastEventSeq(listID int) (int64, error)
+}
+
+type EventHandler struct {
+	service  EventService
+	upgrader websocket.Upgrader
+	log      *zap.Logger
+}
+
+func NewEventHandler(service EventService, log *zap.Logger) *EventHandler {
+	return &EventHandler{service: service, log: log}
+}
+
+// ConnContext keeps the client's connection in the context of its requests, so
+// streams can lift the server's write timeout. It's used as http.Server.ConnContext
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connCtx, c)
+}
+
+// clearWriteDeadline lets the stream outlive the server's write timeout
+func clearWriteDeadline(r *http.Request) error {
+	conn, ok := r.Context().Value(connCtx).(net.Conn)
+	if !ok {
+		return errStreamingUnsupported
+	}
+	return conn.SetWriteDeadline(time.Time{})
+}
+
+// missedEvents returns events the client hasn't received yet and ID of the
+// last event it knows about. Clients without Last-Event-ID start from the
+// latest event, so the history isn't replayed to them
+func (h *EventHandler) missedEvents(r *http.Request, listID int) ([]core.Event, int64, error) {
+	lastEventID, ok, err := getLastEventID(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !ok {
+		lastEventID, err = h.service.GetLastEventSeq(listID)
+		return nil, lastEventID, err
+	}
+	missed, err := h.service.GetEventsSince(listID, lastEventID)
+	return missed, lastEventID, err
+}
+
+// streamEvents sends changes of the list to the client as Server-Sent Events
+func (h *EventHandler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(errStreamingUnsupported)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if _, _, err := getLastEventID(r); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := clearWriteDeadline(r); err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	// subscribe before reading the history, so nothing is lost in between
+	sub := h.service.Subscribe(list.ID)
+	defer sub.Close()
+	missed, lastEventID, err := h.missedEvents(r, list.ID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	sent := make(map[int64]bool, len(missed))
+	for _, e := range missed {
+		if err := writeSSE(w, e); err != nil {
+			return
+		}
+		sent[e.Seq] = true
+	}
+	flusher.Flush()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if sent[e.Seq] || e.Seq <= lastEventID {
+				continue
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// streamEventsWS sends changes of the list to the client over WebSocket
+func (h *EventHandler) streamEventsWS(w http.ResponseWriter, r *http.Request) {
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if _, _, err := getLastEventID(r); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	sub := h.service.Subscribe(list.ID)
+	defer sub.Close()
+	missed, lastEventID, err := h.missedEvents(r, list.ID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// upgrader has already replied to the client
+		return
+	}
+	defer conn.Close()
+	closed := make(chan struct{})
+	go func() {
+		// clients don't send anything, but reading is required for handling control frames
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	sent := make(map[int64]bool, len(missed))
+	for _, e := range missed {
+		if err := writeWS(conn, e); err != nil {
+			return
+		}
+		sent[e.Seq] = true
+	}
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if sent[e.Seq] || e.Seq <= lastEventID {
+				continue
+			}
+			if err := writeWS(conn, e); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// getLastEventID returns sequence number of the last event received by the
+// client, ok is false when the client hasn't received any
+func getLastEventID(r *http.Request) (id int64, ok bool, err error) {
+	raw := r.Header.Get(lastEventIDHeader)
+	if raw == "" {
+		raw = r.URL.Query().Get(lastEventIDParam)
+	}
+	if raw == "" {
+		return 0, false, nil
+	}
+	id, err = strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, false, errors.New("invalid Last-Event-ID")
+	}
+	return id, true, nil
+}
+
+func writeSSE(w http.ResponseWriter, e core.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+	return err
+}
+
+func writeWS(conn *websocket.Conn, e core.Event) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(e)
+}
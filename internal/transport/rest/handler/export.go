@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/exporter"
+	"go.uber.org/zap"
+)
+
+type ExportService interface {
+	ExportAll(userID int, w exporter.Writer) error
+	ExportList(list core.Todolist, w exporter.Writer) error
+}
+
+type ExportHandler struct {
+	service ExportService
+	log     *zap.Logger
+}
+
+func NewExportHandler(service ExportService, log *zap.Logger) *ExportHandler {
+	return &ExportHandler{service, log}
+}
+
+func (h *ExportHandler) exportAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ew, ok := h.exportWriter(w, r, "todos")
+	if !ok {
+		return
+	}
+	if err := h.service.ExportAll(userID, ew); err != nil {
+		// the response is already being streamed, so the status can't be changed
+		h.log.Error("can't export lists", zap.Int("userID", userID), zap.Error(err))
+	}
+}
+
+func (h *ExportHandler) exportList(w http.ResponseWriter, r *http.Request) {
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ew, ok := h.exportWriter(w, r, fmt.Sprintf("list-%d", list.ID))
+	if !ok {
+		return
+	}
+	if err := h.service.ExportList(list, ew); err != nil {
+		h.log.Error("can't export list", zap.Int("listID", list.ID), zap.Error(err))
+	}
+}
+
+// exportWriter returns writer of the requested format and sets headers of the
+// downloaded file, json is used by default
+func (h *ExportHandler) exportWriter(w http.ResponseWriter, r *http.Request, name string) (exporter.Writer, bool) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exporter.FormatJSON
+	}
+	ew, err := exporter.NewWriter(format, w)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return nil, false
+	}
+	w.Header().Set("Content-Type", exporter.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	return ew, true
+}
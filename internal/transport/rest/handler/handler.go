@@ -2,36 +2,112 @@
 package handler
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/ratelimit"
 	"go.uber.org/zap"
 )
 
 // Deps represents external dependencies for rest handlers
 type Deps struct {
-	AuthService     AuthService
-	TodoListService TodoListService
-	TodoItemService TodoItemService
-	Log             *zap.Logger
+	AuthService       AuthService
+	TodoListService   TodoListService
+	TodoItemService   TodoItemService
+	EventService      EventService
+	WebhookService    WebhookService
+	ImportService     ImportService
+	ExportService     ExportService
+	SyncService       SyncService
+	TokenService      AccessTokenService
+	OIDCService       OIDCService
+	AccountService    AccountService
+	RecoveryService   RecoveryService
+	AdminService      AdminService
+	WorkspaceService  WorkspaceService
+	CommentService    CommentService
+	AttachmentService AttachmentService
+	QuickAddService   QuickAddService
+	SettingsService   SettingsService
+	TemplateService   TemplateService
+	ShareLinkService  ShareLinkService
+	StatsService      StatsService
+	StatusService     StatusService
+	RateLimiter       ratelimit.Store // optional, requests aren't limited without it
+	RateLimits        RateLimits
+	GraphQL           http.Handler
+	CalDAV            http.Handler
+	Log               *zap.Logger
 }
 
 // Handler represents rest modules of API
 type Handler struct {
-	Auth     *AuthHandler
-	TodoList *TodoListHandler
-	TodoItem *TodoItemHandler
-	log      *zap.Logger
+	Auth       *AuthHandler
+	TodoList   *TodoListHandler
+	TodoItem   *TodoItemHandler
+	Event      *EventHandler
+	Webhook    *WebhookHandler
+	Import     *ImportHandler
+	Export     *ExportHandler
+	Sync       *SyncHandler
+	Token      *AccessTokenHandler
+	OIDC       *OIDCHandler
+	Account    *AccountHandler
+	Recovery   *RecoveryHandler
+	Admin      *AdminHandler
+	Workspace  *WorkspaceHandler
+	Comment    *CommentHandler
+	Attachment *AttachmentHandler
+	QuickAdd   *QuickAddHandler
+	Settings   *SettingsHandler
+	Template   *TemplateHandler
+	ShareLink  *ShareLinkHandler
+	Stats      *StatsHandler
+	Status     *StatusHandler
+	GraphQL    http.Handler
+	CalDAV     http.Handler
+	limiter    ratelimit.Store
+	limits     RateLimits
+	log        *zap.Logger
 }
 
 // New returns instance of rest handler
 func New(deps Deps) *Handler {
-	return &Handler{
-		Auth:     NewAuthHandler(deps.AuthService, deps.Log),
-		TodoList: NewTodoListHandler(deps.TodoListService, deps.Log),
-		TodoItem: NewTodoItemHandler(deps.TodoItemService, deps.Log),
-		log:      deps.Log,
+	h := &Handler{
+		Auth:       NewAuthHandler(deps.AuthService, deps.Log),
+		TodoList:   NewTodoListHandler(deps.TodoListService, deps.Log),
+		TodoItem:   NewTodoItemHandler(deps.TodoItemService, deps.Log),
+		Event:      NewEventHandler(deps.EventService, deps.Log),
+		Webhook:    NewWebhookHandler(deps.WebhookService, deps.Log),
+		Import:     NewImportHandler(deps.ImportService, deps.Log),
+		Export:     NewExportHandler(deps.ExportService, deps.Log),
+		Sync:       NewSyncHandler(deps.SyncService, deps.Log),
+		Token:      NewAccessTokenHandler(deps.TokenService, deps.Log),
+		Account:    NewAccountHandler(deps.AccountService, deps.Log),
+		Recovery:   NewRecoveryHandler(deps.RecoveryService, deps.Log),
+		Admin:      NewAdminHandler(deps.AdminService, deps.Log),
+		Workspace:  NewWorkspaceHandler(deps.WorkspaceService, deps.Log),
+		Comment:    NewCommentHandler(deps.CommentService, deps.Log),
+		Attachment: NewAttachmentHandler(deps.AttachmentService, deps.Log),
+		QuickAdd:   NewQuickAddHandler(deps.QuickAddService, deps.Log),
+		Settings:   NewSettingsHandler(deps.SettingsService, deps.Log),
+		Template:   NewTemplateHandler(deps.TemplateService, deps.Log),
+		ShareLink:  NewShareLinkHandler(deps.ShareLinkService, deps.Log),
+		Stats:      NewStatsHandler(deps.StatsService, deps.Log),
+		Status:     NewStatusHandler(deps.StatusService, deps.Log),
+		GraphQL:    deps.GraphQL,
+		CalDAV:     deps.CalDAV,
+		limiter:    deps.RateLimiter,
+		limits:     deps.RateLimits,
+		log:        deps.Log,
+	}
+	if deps.OIDCService != nil {
+		h.OIDC = NewOIDCHandler(deps.OIDCService, deps.Log)
 	}
+	return h
 }
 
 // Routes creates, composes, and returns rest routes for API
@@ -42,39 +118,217 @@ func (h *Handler) Routes() *chi.Mux {
 	r.Use(h.Recoverer)
 	r.Use(SendRequestID)
 	r.Use(render.SetContentType(render.ContentTypeJSON))
-	r.Mount("/auth", h.authRouter())
-	r.With(h.Auth.UserIdentity).Mount("/api", h.apiRouter())
+	r.With(h.RateLimit("auth", h.limits.Auth, byIP)).Mount("/auth", h.authRouter())
+	apiLimit := h.RateLimit("api", h.limits.API, byUser)
+	r.With(h.Auth.UserIdentity, apiLimit).Mount("/api", h.apiRouter())
+	r.With(h.Auth.UserIdentity, apiLimit, h.Auth.RequireAdmin).Mount("/admin", h.adminRouter())
+	// public share links are available without authentication
+	r.With(h.RateLimit("public", h.limits.Public, byIP)).Mount("/public", h.publicRouter())
+	// mutations check write scopes on their own
+	r.With(h.Auth.UserIdentity, apiLimit, h.Auth.RequireScopes(core.ScopeListsRead, core.ScopeTodosRead)).Mount("/graphql", h.GraphQL)
+	// CalDAV clients authenticate with Basic auth on their own
+	r.With(h.RateLimit("dav", h.limits.DAV, byIP)).Mount("/dav", h.CalDAV)
+	r.Handle("/.well-known/caldav", http.RedirectHandler("/dav/", http.StatusMovedPermanently))
 	return r
 }
 
 func (h *Handler) authRouter() chi.Router {
 	r := chi.NewRouter()
 	r.Post("/sign-up", h.Auth.SignUp)
-	r.Post("/sign-in", h.Auth.SignIn)
+	r.With(h.RateLimit("sign-in", h.limits.SignIn, byUsername)).Post("/sign-in", h.Auth.SignIn)
+	r.Post("/sign-in/verify", h.Auth.VerifyChallenge)
+	r.Post("/forgot-password", h.Recovery.forgotPassword)
+	r.Post("/reset-password", h.Recovery.resetPassword)
+	r.Get("/verify-email", h.Recovery.verifyEmail)
+	r.Post("/verify-email", h.Recovery.verifyEmail)
+	// sign in through OpenID provider is available only when it's configured
+	if h.OIDC != nil {
+		r.Get("/oidc/login", h.OIDC.login)
+		r.Get("/oidc/callback", h.OIDC.callback)
+	}
+	return r
+}
+
+func (h *Handler) publicRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/lists/{token}", h.ShareLink.getPublicList)
 	return r
 }
 
 func (h *Handler) apiRouter() chi.Router {
+	scopes := h.Auth.RequireScopes
 	r := chi.NewRouter()
-	r.Route("/lists", func(r chi.Router) {
-		r.Get("/", h.TodoList.getAllLists)
-		r.Post("/", h.TodoList.createList)
-		r.Route("/{listID}", func(r chi.Router) {
-			r.Use(h.TodoList.listCtx)
+	r.Route("/lists", h.listRoutes)
+	r.With(scopes(core.ScopeTodosRead)).Get("/todos/assigned", h.TodoItem.getAssignedTodos)
+	r.With(scopes(core.ScopeListsRead, core.ScopeTodosWrite)).Post("/quick-add", h.QuickAdd.quickAdd)
+	r.With(scopes(core.ScopeListsRead, core.ScopeTodosRead)).Get("/stats", h.Stats.getStats)
+	r.Route("/templates", func(r chi.Router) {
+		r.With(scopes(core.ScopeListsRead)).Get("/", h.Template.getTemplates)
+		r.With(scopes(core.ScopeListsRead, core.ScopeListsWrite)).Post("/", h.Template.createTemplate)
+		// templates shared by public links are available to everyone who has the token
+		r.Route("/shared/{token}", func(r chi.Router) {
+			r.Use(h.Template.sharedTemplateCtx)
+			r.With(scopes(core.ScopeListsRead)).Get("/", h.Template.getTemplate)
+			r.With(scopes(core.ScopeListsWrite, core.ScopeTodosWrite)).Post("/instantiate", h.Template.instantiate)
+		})
+		r.Route("/{templateID}", func(r chi.Router) {
+			r.Use(h.Template.templateCtx)
+			r.With(scopes(core.ScopeListsRead)).Get("/", h.Template.getTemplate)
+			r.Group(func(r chi.Router) {
+				r.Use(scopes(core.ScopeListsWrite))
+				r.Patch("/", h.Template.updateTemplate)
+				r.Delete("/", h.Template.deleteTemplate)
+				r.Post("/share", h.Template.shareTemplate)
+				r.Delete("/share", h.Template.unshareTemplate)
+			})
+			r.With(scopes(core.ScopeListsWrite, core.ScopeTodosWrite)).Post("/instantiate", h.Template.instantiate)
+		})
+	})
+	r.Route("/workspaces", func(r chi.Router) {
+		r.With(scopes(core.ScopeListsRead)).Get("/", h.Workspace.getWorkspaces)
+		r.With(h.Auth.RequireSession).Post("/", h.Workspace.createWorkspace)
+		r.Route("/{wsID}", func(r chi.Router) {
+			r.Use(h.Workspace.workspaceCtx)
+			r.Group(func(r chi.Router) {
+				r.Use(scopes(core.ScopeListsRead))
+				r.Get("/", h.Workspace.getWorkspace)
+				r.Get("/members", h.Workspace.getMembers)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(h.Auth.RequireSession)
+				r.Patch("/", h.Workspace.updateWorkspace)
+				r.Delete("/", h.Workspace.deleteWorkspace)
+				r.Patch("/members/{userID}", h.Workspace.updateMember)
+				r.Delete("/members/{userID}", h.Workspace.removeMember)
+				r.Get("/invites", h.Workspace.getInvites)
+				r.Post("/invites", h.Workspace.createInvite)
+				r.Delete("/invites/{inviteID}", h.Workspace.deleteInvite)
+			})
+			r.Route("/lists", h.listRoutes)
+		})
+	})
+	r.With(h.Auth.RequireSession).Post("/invites/accept", h.Workspace.acceptInvite)
+	r.With(scopes(core.ScopeListsWrite, core.ScopeTodosWrite)).Post("/import", h.Import.importFile)
+	r.With(scopes(core.ScopeListsRead, core.ScopeTodosRead)).Get("/export", h.Export.exportAll)
+	r.With(scopes(core.ScopeListsRead, core.ScopeTodosRead)).Get("/sync", h.Sync.getChanges)
+	r.With(scopes(core.ScopeListsWrite, core.ScopeTodosWrite)).Post("/sync", h.Sync.applyMutations)
+	r.Route("/webhooks", func(r chi.Router) {
+		r.With(scopes(core.ScopeWebhooksRead)).Get("/", h.Webhook.getAllWebhooks)
+		r.With(scopes(core.ScopeWebhooksWrite)).Post("/", h.Webhook.createWebhook)
+		r.Route("/{webhookID}", func(r chi.Router) {
+			r.Use(h.Webhook.webhookCtx)
+			r.With(scopes(core.ScopeWebhooksRead)).Get("/", h.Webhook.getWebhook)
+			r.With(scopes(core.ScopeWebhooksWrite)).Delete("/", h.Webhook.deleteWebhook)
+			r.With(scopes(core.ScopeWebhooksRead)).Get("/deliveries", h.Webhook.getDeliveries)
+		})
+	})
+	r.Route("/me", func(r chi.Router) {
+		r.Get("/", h.Account.getMe)
+		r.Get("/settings", h.Settings.getSettings)
+		r.Group(func(r chi.Router) {
+			r.Use(h.Auth.RequireSession)
+			r.Put("/settings", h.Settings.updateSettings)
+			r.Patch("/", h.Account.updateMe)
+			r.Delete("/", h.Account.deleteMe)
+			r.Post("/password", h.Account.changePassword)
+			r.Post("/verify-email", h.Recovery.resendVerification)
+		})
+	})
+	r.Route("/2fa", func(r chi.Router) {
+		r.Use(h.Auth.RequireSession)
+		r.Post("/enroll", h.Auth.enrollTOTP)
+		r.Post("/confirm", h.Auth.confirmTOTP)
+		r.Post("/disable", h.Auth.disableTOTP)
+		r.Post("/recovery-codes", h.Auth.regenerateRecoveryCodes)
+	})
+	r.Route("/tokens", func(r chi.Router) {
+		r.Use(h.Auth.RequireSession)
+		r.Get("/", h.Token.getAllTokens)
+		r.Post("/", h.Token.createToken)
+		r.Delete("/{tokenID}", h.Token.deleteToken)
+	})
+	return r
+}
+
+// listRoutes mounts lists with their todos, under the workspace's routes only
+// lists of the workspace are available
+func (h *Handler) listRoutes(r chi.Router) {
+	scopes := h.Auth.RequireScopes
+	r.With(scopes(core.ScopeListsRead)).Get("/", h.TodoList.getAllLists)
+	r.With(scopes(core.ScopeListsWrite)).Post("/", h.TodoList.createList)
+	r.Route("/{listID}", func(r chi.Router) {
+		r.Use(h.TodoList.listCtx)
+		r.Group(func(r chi.Router) {
+			r.Use(scopes(core.ScopeListsRead))
 			r.Get("/", h.TodoList.getList)
+			r.Get("/events", h.Event.streamEvents)
+			r.Get("/events/ws", h.Event.streamEventsWS)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(scopes(core.ScopeListsWrite))
 			r.Patch("/", h.TodoList.updateList)
 			r.Delete("/", h.TodoList.deleteList)
-			r.Route("/todos", func(r chi.Router) {
-				r.Get("/", h.TodoItem.getAllTodos)
-				r.Post("/", h.TodoItem.createTodo)
-				r.Route("/{todoID}", func(r chi.Router) {
-					r.Use(h.TodoItem.todoCtx)
-					r.Get("/", h.TodoItem.getTodo)
-					r.Patch("/", h.TodoItem.updateTodo)
-					r.Delete("/", h.TodoItem.deleteTodo)
+		})
+		r.With(scopes(core.ScopeListsRead, core.ScopeTodosRead)).Get("/export", h.Export.exportList)
+		r.Route("/share-links", func(r chi.Router) {
+			r.Use(scopes(core.ScopeListsWrite))
+			r.Get("/", h.ShareLink.getShareLinks)
+			r.Post("/", h.ShareLink.createShareLink)
+			r.With(h.ShareLink.shareLinkCtx).Delete("/{linkID}", h.ShareLink.deleteShareLink)
+		})
+		r.Route("/statuses", func(r chi.Router) {
+			r.With(scopes(core.ScopeListsRead)).Get("/", h.Status.getStatuses)
+			r.With(scopes(core.ScopeListsWrite, core.ScopeTodosWrite)).Put("/", h.Status.replaceStatuses)
+		})
+		r.Route("/todos", func(r chi.Router) {
+			r.With(scopes(core.ScopeTodosRead)).Get("/", h.TodoItem.getAllTodos)
+			r.With(scopes(core.ScopeTodosWrite)).Post("/", h.TodoItem.createTodo)
+			r.Route("/{todoID}", func(r chi.Router) {
+				r.Use(h.TodoItem.todoCtx)
+				r.With(scopes(core.ScopeTodosRead)).Get("/", h.TodoItem.getTodo)
+				r.With(scopes(core.ScopeTodosWrite)).Patch("/", h.TodoItem.updateTodo)
+				r.With(scopes(core.ScopeTodosWrite)).Delete("/", h.TodoItem.deleteTodo)
+				r.With(scopes(core.ScopeTodosWrite)).Post("/move", h.TodoItem.moveTodo)
+				r.Route("/comments", func(r chi.Router) {
+					r.With(scopes(core.ScopeTodosRead)).Get("/", h.Comment.getComments)
+					r.With(scopes(core.ScopeTodosWrite)).Post("/", h.Comment.createComment)
+					r.Route("/{commentID}", func(r chi.Router) {
+						r.Use(h.Comment.commentCtx)
+						r.With(scopes(core.ScopeTodosRead)).Get("/", h.Comment.getComment)
+						r.With(scopes(core.ScopeTodosWrite)).Patch("/", h.Comment.updateComment)
+						r.With(scopes(core.ScopeTodosWrite)).Delete("/", h.Comment.deleteComment)
+					})
+				})
+				r.Route("/attachments", func(r chi.Router) {
+					r.With(scopes(core.ScopeTodosRead)).Get("/", h.Attachment.getAttachments)
+					r.With(scopes(core.ScopeTodosWrite)).Post("/", h.Attachment.uploadAttachment)
+					r.Route("/{attachmentID}", func(r chi.Router) {
+						r.Use(h.Attachment.attachmentCtx)
+						r.With(scopes(core.ScopeTodosRead)).Get("/", h.Attachment.getAttachment)
+						r.With(scopes(core.ScopeTodosRead)).Get("/content", h.Attachment.downloadAttachment)
+						r.With(scopes(core.ScopeTodosWrite)).Delete("/", h.Attachment.deleteAttachment)
+					})
 				})
 			})
 		})
 	})
+}
+
+func (h *Handler) adminRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/stats", h.Admin.getStats)
+	r.Get("/audit", h.Admin.getAuditLog)
+	r.Route("/users", func(r chi.Router) {
+		r.Get("/", h.Admin.getUsers)
+		r.Route("/{userID}", func(r chi.Router) {
+			r.Use(h.Admin.managedUserCtx)
+			r.Get("/", h.Admin.getUser)
+			r.Post("/disable", h.Admin.disableUser)
+			r.Post("/enable", h.Admin.enableUser)
+			r.Post("/sign-out", h.Admin.signOutUser)
+			r.Put("/role", h.Admin.setRole)
+			r.Post("/impersonate", h.Admin.impersonate)
+		})
+	})
 	return r
 }
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"github.com/vbetsun/todo-app/internal/importer"
+	"go.uber.org/zap"
+)
+
+// maxImportSize is a limit of the uploaded file size
+const maxImportSize = 5 << 20 // 5 Mb
+
+type ImportService interface {
+	Import(userID int, format string, r io.Reader, defaultList string, dryRun bool) (core.ImportResult, error)
+}
+
+type ImportHandler struct {
+	service ImportService
+	log     *zap.Logger
+}
+
+type ImportResponse struct {
+	*core.ImportResult
+}
+
+func NewImportHandler(service ImportService, log *zap.Logger) *ImportHandler {
+	return &ImportHandler{service, log}
+}
+
+func (ir *ImportResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(ir.Lists) == 0 {
+		ir.Lists = make([]core.ListWithTodos, 0)
+	}
+	if len(ir.Errors) == 0 {
+		ir.Errors = make([]core.ImportError, 0)
+	}
+	return nil
+}
+
+// importFile creates lists and todos from the uploaded file, with dry_run=true
+// it only returns the preview of what is going to be created
+func (h *ImportHandler) importFile(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	defer file.Close()
+	format := r.FormValue("format")
+	if format == "" {
+		format = importer.FormatFromFilename(header.Filename)
+	}
+	dryRun := false
+	if raw := r.FormValue("dry_run"); raw != "" {
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(errors.New("invalid dry_run value"))); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+	}
+	res, err := h.service.Import(userID, format, file, r.FormValue("list"), dryRun)
+	if errors.Is(err, importer.ErrUnknownFormat) {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	switch {
+	case len(res.Errors) > 0:
+		render.Status(r, http.StatusUnprocessableEntity)
+	case !dryRun:
+		render.Status(r, http.StatusCreated)
+	}
+	if err := render.Render(w, r, &ImportResponse{ImportResult: &res}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
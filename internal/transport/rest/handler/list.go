@@ -20,9 +20,10 @@ const listCtx ctxKeyList = "list"
 type TodoListService interface {
 	CreateList(userID int, list core.Todolist) (core.Todolist, error)
 	GetAllLists(userID int) ([]core.Todolist, error)
+	GetWorkspaceLists(userID, workspaceID int) ([]core.Todolist, error)
 	GetListByID(userID, listID int) (core.Todolist, error)
-	UpdateList(listID int, data core.UpdateListData) (core.Todolist, error)
-	DeleteList(listID int) error
+	UpdateList(userID, listID int, data core.UpdateListData) (core.Todolist, error)
+	DeleteList(userID, listID int) error
 }
 
 type TodoListHandler struct {
@@ -92,6 +93,10 @@ func (h *TodoListHandler) listCtx(next http.Handler) http.Handler {
 			return
 		}
 		list, err := h.service.GetListByID(userID, listID)
+		// lists of other workspaces aren't available under the workspace's routes
+		if ws, ok := r.Context().Value(workspaceCtx).(core.Workspace); ok && err == nil && list.WorkspaceID != ws.ID {
+			err = ErrListNotFound
+		}
 		if err != nil {
 			if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
 				h.log.Error(ErrRenderResp.Error())
@@ -111,9 +116,14 @@ func (h *TodoListHandler) getAllLists(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	lists, err := h.service.GetAllLists(userID)
+	var lists []core.Todolist
+	if ws, ok := r.Context().Value(workspaceCtx).(core.Workspace); ok {
+		lists, err = h.service.GetWorkspaceLists(userID, ws.ID)
+	} else {
+		lists, err = h.service.GetAllLists(userID)
+	}
 	if err != nil {
-		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
 			h.log.Error(ErrRenderResp.Error())
 		}
 		return
@@ -138,9 +148,12 @@ func (h *TodoListHandler) createList(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	if ws, ok := r.Context().Value(workspaceCtx).(core.Workspace); ok {
+		data.WorkspaceID = ws.ID
+	}
 	list, err := h.service.CreateList(userID, *data.Todolist)
 	if err != nil {
-		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
 			h.log.Error(ErrRenderResp.Error())
 		}
 		return
@@ -165,6 +178,13 @@ func (h *TodoListHandler) getList(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TodoListHandler) updateList(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
 	list, ok := r.Context().Value(listCtx).(core.Todolist)
 	if !ok {
 		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
@@ -179,9 +199,9 @@ func (h *TodoListHandler) updateList(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	list, err := h.service.UpdateList(list.ID, *data.UpdateListData)
+	list, err = h.service.UpdateList(userID, list.ID, *data.UpdateListData)
 	if err != nil {
-		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
 			h.log.Error(ErrRenderResp.Error())
 		}
 		return
@@ -192,6 +212,13 @@ func (h *TodoListHandler) updateList(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TodoListHandler) deleteList(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
 	list, ok := r.Context().Value(listCtx).(core.Todolist)
 	if !ok {
 		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
@@ -199,9 +226,9 @@ func (h *TodoListHandler) deleteList(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	err := h.service.DeleteList(list.ID)
+	err = h.service.DeleteList(userID, list.ID)
 	if err != nil {
-		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
 			h.log.Error(ErrRenderResp.Error())
 		}
 		return
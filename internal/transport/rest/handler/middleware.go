@@ -1,24 +1,20 @@
 package handler
 
 import (
-	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/auth"
+	"github.com/vbetsun/todo-app/internal/core"
 	"go.uber.org/zap"
 )
 
-// Key to use when setting the user context.
-type ctxKeyUser string
-
-const (
-	authHeader            = "Authorization"
-	userCtx    ctxKeyUser = "userID"
-)
+const authHeader = "Authorization"
 
 func (h *AuthHandler) UserIdentity(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -36,18 +32,81 @@ func (h *AuthHandler) UserIdentity(next http.Handler) http.Handler {
 			}
 			return
 		}
-		userID, err := h.service.ParseToken(headerParts[1])
+		p, err := h.service.Identify(headerParts[1])
 		if err != nil {
 			if rErr := render.Render(w, r, ErrUnauthorized(err)); rErr != nil {
 				h.log.Error(ErrRenderResp.Error())
 			}
 			return
 		}
-		ctx := context.WithValue(r.Context(), userCtx, userID)
+		ctx := auth.NewContext(r.Context(), p)
+		if p.ImpersonatorID != 0 {
+			// requests of impersonated users are the audit trail of the admin
+			h.log.Info("Impersonated",
+				zap.Int("adminID", p.ImpersonatorID),
+				zap.Int("userID", p.UserID),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("reqId", middleware.GetReqID(r.Context())))
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireScopes is a middleware which allows requests authenticated by personal
+// access token only when the token has all the scopes. Requests authenticated
+// by JWT are always allowed
+func (h *AuthHandler) RequireScopes(scopes ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !auth.HasScopes(r.Context(), scopes...) {
+				err := fmt.Errorf("token requires scopes: %s", strings.Join(scopes, ", "))
+				if rErr := render.Render(w, r, ErrForbidden(err)); rErr != nil {
+					h.log.Error(ErrRenderResp.Error())
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession is a middleware which rejects requests authenticated by
+// personal access token, e.g. tokens can't manage other tokens. Admins who
+// impersonate the user are rejected as well
+func (h *AuthHandler) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := auth.FromContext(r.Context())
+		if p.Scopes != nil {
+			if err := render.Render(w, r, ErrForbidden(errors.New("access token isn't allowed"))); err != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		if p.ImpersonatorID != 0 {
+			if err := render.Render(w, r, ErrForbidden(errors.New("not allowed while impersonating"))); err != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin is a middleware which allows only admins signed in by their own
+// session
+func (h *AuthHandler) RequireAdmin(next http.Handler) http.Handler {
+	return h.RequireSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, _ := auth.FromContext(r.Context()); p.Role != core.RoleAdmin {
+			if err := render.Render(w, r, ErrForbidden(errors.New("admin role is required"))); err != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
 // Logger is a middleware that logs the start and end of each request, along
 // with some useful data about what was requested, what the response status was,
 // and how long it took to return.
@@ -105,7 +164,7 @@ func SendRequestID(next http.Handler) http.Handler {
 }
 
 func getUserID(w http.ResponseWriter, r *http.Request) (int, error) {
-	userID, ok := r.Context().Value(userCtx).(int)
+	userID, ok := auth.UserID(r.Context())
 	if !ok {
 		return 0, errors.New("userID not found")
 	}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+const (
+	oidcCookie     = "oidc_session"
+	oidcCookiePath = "/auth/oidc"
+	oidcCookieAge  = 600
+)
+
+type OIDCService interface {
+	BeginLogin(ctx context.Context) (string, string, error)
+	FinishLogin(ctx context.Context, session, state, code string) (core.SignIn, error)
+}
+
+type OIDCHandler struct {
+	service OIDCService
+	log     *zap.Logger
+}
+
+func NewOIDCHandler(service OIDCService, log *zap.Logger) *OIDCHandler {
+	return &OIDCHandler{service, log}
+}
+
+// login redirects the user to the provider, secrets of the sign in are kept in
+// the cookie until the provider redirects the user back
+func (h *OIDCHandler) login(w http.ResponseWriter, r *http.Request) {
+	authURL, session, err := h.service.BeginLogin(r.Context())
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    session,
+		Path:     oidcCookiePath,
+		MaxAge:   oidcCookieAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// callback finishes the sign in and returns token or the second factor
+// challenge the same way as SignIn does
+func (h *OIDCHandler) callback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(oidcCookie)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrUnauthorized(errors.New("missing login session"))); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	// the session can be used only once
+	http.SetCookie(w, &http.Cookie{Name: oidcCookie, Path: oidcCookiePath, MaxAge: -1, HttpOnly: true})
+	q := r.URL.Query()
+	if providerErr := q.Get("error"); providerErr != "" {
+		if rErr := render.Render(w, r, ErrUnauthorized(errors.New("provider error: "+providerErr))); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if q.Get("code") == "" {
+		if rErr := render.Render(w, r, ErrInvalidRequest(errors.New("missing required code param"))); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	res, err := h.service.FinishLogin(r.Context(), cookie.Value, q.Get("state"), q.Get("code"))
+	if err != nil {
+		if rErr := render.Render(w, r, ErrUnauthorized(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	resp := &SignInResponse{
+		Token:             res.Token,
+		ChallengeToken:    res.ChallengeToken,
+		TwoFactorRequired: res.ChallengeToken != "",
+	}
+	if err := render.Render(w, r, resp); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+type QuickAddService interface {
+	QuickAdd(userID int, text, listTitle string, loc *time.Location) (int, core.TodoItem, error)
+}
+
+type QuickAddHandler struct {
+	service QuickAddService
+	log     *zap.Logger
+}
+
+// QuickAddRequest contains free text of the todo, the todo goes to the list
+// with the given title or to the default list. Relative dates are resolved in
+// the time zone, the user's time zone from settings is used by default
+type QuickAddRequest struct {
+	Text     string `json:"text"`
+	List     string `json:"list"`
+	TimeZone string `json:"time_zone"`
+
+	loc *time.Location
+}
+
+type QuickAddResponse struct {
+	ListID int `json:"list_id"`
+	*core.TodoItem
+}
+
+func NewQuickAddHandler(service QuickAddService, log *zap.Logger) *QuickAddHandler {
+	return &QuickAddHandler{service, log}
+}
+
+func (qr *QuickAddRequest) Bind(r *http.Request) error {
+	if strings.TrimSpace(qr.Text) == "" {
+		return errors.New("missing required Text field")
+	}
+	if qr.TimeZone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(qr.TimeZone)
+	if err != nil {
+		return errors.New("unknown time zone")
+	}
+	qr.loc = loc
+	return nil
+}
+
+func (qr *QuickAddResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// quickAdd creates the todo from free text, e.g. "Pay rent tomorrow 9am #home
+// !high every month"
+func (h *QuickAddHandler) quickAdd(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &QuickAddRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	listID, todo, err := h.service.QuickAdd(userID, data.Text, data.List, data.loc)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(w, r, &QuickAddResponse{ListID: listID, TodoItem: &todo}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/auth"
+	"github.com/vbetsun/todo-app/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+// maxPeekedBody limits the part of the request body read for the rate limit key
+const maxPeekedBody = 1 << 16
+
+var errRateLimited = errors.New("too many requests")
+
+// RateLimits represents rules of rate limiting, zero rules don't limit anything
+type RateLimits struct {
+	// Auth limits requests to auth endpoints per client IP
+	Auth ratelimit.Rule
+	// SignIn limits sign in attempts per username
+	SignIn ratelimit.Rule
+	// API limits requests to API and GraphQL per user
+	API ratelimit.Rule
+	// DAV limits requests to CalDAV per client IP, since it's authenticated
+	// with passwords on every request
+	DAV ratelimit.Rule
+	// Public limits requests to public share links per client IP, it also
+	// slows down guessing of link passwords
+	Public ratelimit.Rule
+}
+
+// rateLimitKey returns the key of the bucket the request is taken from. Requests
+// with empty key aren't limited
+type rateLimitKey func(r *http.Request) string
+
+// retryError is implemented by errors of requests which can be retried later
+type retryError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// RateLimit is a middleware which responds with 429 status when the requests of
+// the key exceed the rule. Failures of the store don't block requests
+func (h *Handler) RateLimit(name string, rule ratelimit.Rule, key rateLimitKey) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if h.limiter == nil || !rule.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := h.limiter.Take(r.Context(), name+":"+k, rule)
+			if err != nil {
+				h.log.Error("can't check rate limit", zap.String("rule", name), zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", seconds(res.Reset))
+			if !res.Allowed {
+				w.Header().Set("Retry-After", seconds(res.RetryAfter))
+				if rErr := render.Render(w, r, ErrTooManyRequests(errRateLimited)); rErr != nil {
+					h.log.Error(ErrRenderResp.Error())
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// renderRetryError responds with 429 status if the error can be retried later
+func renderRetryError(w http.ResponseWriter, r *http.Request, err error) (bool, error) {
+	var rerr retryError
+	if !errors.As(err, &rerr) {
+		return false, nil
+	}
+	w.Header().Set("Retry-After", seconds(rerr.RetryAfter()))
+	return true, render.Render(w, r, ErrTooManyRequests(rerr))
+}
+
+// byIP identifies requests by address of the client. It's the address of the
+// peer, so the app should be behind the proxy which overwrites it
+func byIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// byUser identifies requests by the user authenticated by UserIdentity middleware
+func byUser(r *http.Request) string {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		return ""
+	}
+	return strconv.Itoa(userID)
+}
+
+// byUsername identifies sign in requests by the username from the body, the
+// body is restored for the handler
+func byUsername(r *http.Request) string {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPeekedBody))
+	if err != nil {
+		return ""
+	}
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	var data SignInRequest
+	if err := json.Unmarshal(body, &data); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(data.Username))
+}
+
+// seconds formats the duration as number of seconds, rounded up
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
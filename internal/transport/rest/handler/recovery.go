@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+type RecoveryService interface {
+	ForgotPassword(email string) error
+	ResetPassword(token, password string) error
+	VerifyEmail(token string) error
+	ResendVerification(userID int) error
+}
+
+type RecoveryHandler struct {
+	service RecoveryService
+	log     *zap.Logger
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+func NewRecoveryHandler(service RecoveryService, log *zap.Logger) *RecoveryHandler {
+	return &RecoveryHandler{service, log}
+}
+
+func (fp *ForgotPasswordRequest) Bind(r *http.Request) error {
+	if fp.Email == "" {
+		return errors.New("missing required Email field")
+	}
+	return nil
+}
+
+func (rp *ResetPasswordRequest) Bind(r *http.Request) error {
+	if rp.Token == "" {
+		return errors.New("missing required Token field")
+	}
+	if rp.Password == "" {
+		return errors.New("missing required Password field")
+	}
+	return nil
+}
+
+func (ve *VerifyEmailRequest) Bind(r *http.Request) error {
+	if ve.Token == "" {
+		return errors.New("missing required Token field")
+	}
+	return nil
+}
+
+func (sr *StatusResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// forgotPassword always responds the same way, so it can't be used for
+// checking whether the email is registered
+func (h *RecoveryHandler) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	data := &ForgotPasswordRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.ForgotPassword(data.Email); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusAccepted)
+	if err := render.Render(w, r, &StatusResponse{Status: "if the email is registered, the reset link is sent"}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *RecoveryHandler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	data := &ResetPasswordRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.ResetPassword(data.Token, data.Password); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// verifyEmail accepts the token from the link sent by email as query param,
+// API clients can send it in the body as well
+func (h *RecoveryHandler) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	data := &VerifyEmailRequest{Token: r.URL.Query().Get("token")}
+	if data.Token == "" {
+		if err := render.Bind(r, data); err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+	}
+	if err := h.service.VerifyEmail(data.Token); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &StatusResponse{Status: "email is verified"}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *RecoveryHandler) resendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.ResendVerification(userID); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusAccepted)
+	if err := render.Render(w, r, &StatusResponse{Status: "verification link is sent"}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+type SettingsService interface {
+	GetSettings(userID int) (core.UserSettings, error)
+	UpdateSettings(userID int, s core.UserSettings) (core.UserSettings, error)
+}
+
+type SettingsHandler struct {
+	service SettingsService
+	log     *zap.Logger
+}
+
+// SettingsRequest is decoded over the current settings, so omitted fields keep
+// their values
+type SettingsRequest struct {
+	*core.UserSettings
+}
+
+type SettingsResponse struct {
+	*core.UserSettings
+}
+
+func NewSettingsHandler(service SettingsService, log *zap.Logger) *SettingsHandler {
+	return &SettingsHandler{service, log}
+}
+
+func (sr *SettingsRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+func (sr *SettingsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (h *SettingsHandler) getSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	settings, err := h.service.GetSettings(userID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &SettingsResponse{UserSettings: &settings}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *SettingsHandler) updateSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	settings, err := h.service.GetSettings(userID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &SettingsRequest{UserSettings: &settings}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	settings, err = h.service.UpdateSettings(userID, settings)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &SettingsResponse{UserSettings: &settings}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// sharePasswordHeader carries the password of the protected share link, it
+// isn't taken from the URL, so it doesn't get to logs and browser history
+const sharePasswordHeader = "X-Share-Password"
+
+// Key to use when setting the share link context.
+type ctxKeyShareLink string
+
+const shareLinkCtx ctxKeyShareLink = "shareLink"
+
+type ShareLinkService interface {
+	GetShareLinks(userID, listID int) ([]core.ShareLink, error)
+	GetShareLink(listID, linkID int) (core.ShareLink, error)
+	CreateShareLink(userID, listID int, password string, expiresAt *time.Time) (core.ShareLink, error)
+	DeleteShareLink(userID, listID int, l core.ShareLink) error
+	GetShareLinkByToken(token string) (core.ShareLink, error)
+	Unlock(l core.ShareLink, password string) bool
+	GetPublicList(l core.ShareLink) (core.PublicList, error)
+}
+
+type ShareLinkHandler struct {
+	service ShareLinkService
+	log     *zap.Logger
+}
+
+// CreateShareLinkRequest describes the new link, it doesn't expire and isn't
+// protected by default
+type CreateShareLinkRequest struct {
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ShareLinkResponse struct {
+	*core.ShareLink
+}
+
+type AllShareLinksResponse struct {
+	Data []core.ShareLink `json:"data"`
+}
+
+type PublicListResponse struct {
+	*core.PublicList
+}
+
+func NewShareLinkHandler(service ShareLinkService, log *zap.Logger) *ShareLinkHandler {
+	return &ShareLinkHandler{service, log}
+}
+
+func (cs *CreateShareLinkRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+func (sr *ShareLinkResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (as *AllShareLinksResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(as.Data) == 0 {
+		as.Data = make([]core.ShareLink, 0)
+	}
+	return nil
+}
+
+func (pr *PublicListResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// shareLinkCtx loads the share link of the list from the context
+func (h *ShareLinkHandler) shareLinkCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list, ok := r.Context().Value(listCtx).(core.Todolist)
+		if !ok {
+			if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		linkID, err := strconv.Atoi(chi.URLParam(r, "linkID"))
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		link, err := h.service.GetShareLink(list.ID, linkID)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		ctx := context.WithValue(r.Context(), shareLinkCtx, link)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *ShareLinkHandler) getShareLinks(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	links, err := h.service.GetShareLinks(userID, list.ID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllShareLinksResponse{Data: links}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// createShareLink responds with the token of the new link, it isn't shown again
+func (h *ShareLinkHandler) createShareLink(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &CreateShareLinkRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	link, err := h.service.CreateShareLink(userID, list.ID, data.Password, data.ExpiresAt)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(w, r, &ShareLinkResponse{ShareLink: &link}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *ShareLinkHandler) deleteShareLink(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	link, ok := r.Context().Value(shareLinkCtx).(core.ShareLink)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrShareLinkNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.DeleteShareLink(userID, list.ID, link); err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// getPublicList shows the shared list to anyone who has the token, the password
+// of the protected link is sent in the X-Share-Password header. Unknown,
+// expired and revoked links aren't distinguished
+func (h *ShareLinkHandler) getPublicList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	link, err := h.service.GetShareLinkByToken(chi.URLParam(r, "token"))
+	if err != nil {
+		if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if !h.service.Unlock(link, r.Header.Get(sharePasswordHeader)) {
+		if rErr := render.Render(w, r, ErrUnauthorized(ErrSharePassword)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, err := h.service.GetPublicList(link)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &PublicListResponse{PublicList: &list}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
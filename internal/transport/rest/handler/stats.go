@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+type StatsService interface {
+	GetStats(userID int, from, to string, loc *time.Location) (core.Stats, error)
+}
+
+type StatsHandler struct {
+	service StatsService
+	log     *zap.Logger
+}
+
+type StatsResponse struct {
+	*core.Stats
+}
+
+func NewStatsHandler(service StatsService, log *zap.Logger) *StatsHandler {
+	return &StatsHandler{service, log}
+}
+
+func (sr *StatsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// getStats returns the report for days between "from" and "to" query params,
+// days are taken in the "tz" time zone or in the user's time zone by default
+func (h *StatsHandler) getStats(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	query := r.URL.Query()
+	var loc *time.Location
+	if tz := query.Get("tz"); tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			if rErr := render.Render(w, r, ErrInvalidRequest(errors.New("unknown time zone"))); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+	}
+	stats, err := h.service.GetStats(userID, query.Get("from"), query.Get("to"), loc)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &StatsResponse{Stats: &stats}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+type StatusService interface {
+	GetStatuses(listID int) ([]core.ListStatus, error)
+	ReplaceStatuses(userID, listID int, statuses []core.ListStatus) ([]core.ListStatus, error)
+}
+
+type StatusHandler struct {
+	service StatusService
+	log     *zap.Logger
+}
+
+// ReplaceStatusesRequest describes all columns of the board in their order,
+// statuses without ID are created and missing ones are removed
+type ReplaceStatusesRequest struct {
+	Statuses []core.ListStatus `json:"statuses"`
+}
+
+type AllStatusesResponse struct {
+	Data []core.ListStatus `json:"data"`
+}
+
+func NewStatusHandler(service StatusService, log *zap.Logger) *StatusHandler {
+	return &StatusHandler{service, log}
+}
+
+func (rs *ReplaceStatusesRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+func (as *AllStatusesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(as.Data) == 0 {
+		as.Data = make([]core.ListStatus, 0)
+	}
+	return nil
+}
+
+func (h *StatusHandler) getStatuses(w http.ResponseWriter, r *http.Request) {
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	statuses, err := h.service.GetStatuses(list.ID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllStatusesResponse{Data: statuses}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// replaceStatuses saves the board's columns, empty statuses turn the board
+// back to the plain list
+func (h *StatusHandler) replaceStatuses(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &ReplaceStatusesRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	statuses, err := h.service.ReplaceStatuses(userID, list.ID, data.Statuses)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllStatusesResponse{Data: statuses}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// maxSyncMutations is a limit of mutations within a single batch
+const maxSyncMutations = 500
+
+var errInvalidSyncToken = errors.New("invalid sync token")
+
+type SyncService interface {
+	GetChanges(userID int, since int64) (core.SyncChanges, error)
+	ApplyMutations(userID int, mutations []core.SyncMutation) []core.SyncResult
+}
+
+type SyncHandler struct {
+	service SyncService
+	log     *zap.Logger
+}
+
+type SyncRequest struct {
+	Mutations []core.SyncMutation `json:"mutations"`
+}
+
+type SyncChangesResponse struct {
+	*core.SyncChanges
+}
+
+type SyncResultsResponse struct {
+	Results []core.SyncResult `json:"results"`
+}
+
+func NewSyncHandler(service SyncService, log *zap.Logger) *SyncHandler {
+	return &SyncHandler{service, log}
+}
+
+func (sr *SyncRequest) Bind(r *http.Request) error {
+	if len(sr.Mutations) == 0 {
+		return errors.New("missing required Mutations field")
+	}
+	if len(sr.Mutations) > maxSyncMutations {
+		return fmt.Errorf("batch can't contain more than %d mutations", maxSyncMutations)
+	}
+	return nil
+}
+
+func (sc *SyncChangesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(sc.Lists) == 0 {
+		sc.Lists = make([]core.Todolist, 0)
+	}
+	if len(sc.Todos) == 0 {
+		sc.Todos = make([]core.SyncTodo, 0)
+	}
+	if len(sc.DeletedLists) == 0 {
+		sc.DeletedLists = make([]core.Tombstone, 0)
+	}
+	if len(sc.DeletedTodos) == 0 {
+		sc.DeletedTodos = make([]core.Tombstone, 0)
+	}
+	return nil
+}
+
+func (sr *SyncResultsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// getChanges returns lists and todos changed since the token from the since
+// query param, the response's token has to be passed with the next request
+func (h *SyncHandler) getChanges(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	var since int64
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		since, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || since <= 0 {
+			if rErr := render.Render(w, r, ErrInvalidRequest(errInvalidSyncToken)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+	}
+	changes, err := h.service.GetChanges(userID, since)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &SyncChangesResponse{SyncChanges: &changes}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// applyMutations applies the batch of changes made by the client offline and
+// reports the outcome of each of them
+func (h *SyncHandler) applyMutations(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &SyncRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	results := h.service.ApplyMutations(userID, data.Mutations)
+	if err := render.Render(w, r, &SyncResultsResponse{Results: results}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -0,0 +1,360 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// Key to use when setting the template context.
+type ctxKeyTemplate string
+
+const templateCtx ctxKeyTemplate = "template"
+
+type TemplateService interface {
+	GetTemplates(userID int) ([]core.ListTemplate, error)
+	GetTemplate(userID, templateID int) (core.ListTemplate, error)
+	GetSharedTemplate(token string) (core.ListTemplate, error)
+	CreateTemplate(userID, listID int, t core.ListTemplate) (core.ListTemplate, error)
+	UpdateTemplate(userID int, t core.ListTemplate, data core.UpdateTemplateData) (core.ListTemplate, error)
+	DeleteTemplate(userID int, t core.ListTemplate) error
+	ShareTemplate(userID int, t core.ListTemplate) (core.ListTemplate, error)
+	UnshareTemplate(userID int, t core.ListTemplate) error
+	Instantiate(userID int, t core.ListTemplate, in core.TemplateInstance) (core.ListWithTodos, error)
+}
+
+type TemplateHandler struct {
+	service TemplateService
+	log     *zap.Logger
+}
+
+// CreateTemplateRequest saves the list as the template, title and description
+// of the list are used unless they're given
+type CreateTemplateRequest struct {
+	ListID      int    `json:"list_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+}
+
+type UpdateTemplateRequest struct {
+	*core.UpdateTemplateData
+}
+
+type InstantiateTemplateRequest struct {
+	*core.TemplateInstance
+}
+
+type TemplateResponse struct {
+	*core.ListTemplate
+}
+
+type AllTemplatesResponse struct {
+	Data []core.ListTemplate `json:"data"`
+}
+
+type InstanceResponse struct {
+	*core.ListWithTodos
+}
+
+func NewTemplateHandler(service TemplateService, log *zap.Logger) *TemplateHandler {
+	return &TemplateHandler{service, log}
+}
+
+func (ct *CreateTemplateRequest) Bind(r *http.Request) error {
+	if ct.ListID == 0 {
+		return errors.New("missing required ListID field")
+	}
+	return nil
+}
+
+func (ut *UpdateTemplateRequest) Bind(r *http.Request) error {
+	if ut.UpdateTemplateData == nil {
+		return errors.New("missing required Template fields")
+	}
+	return nil
+}
+
+func (it *InstantiateTemplateRequest) Bind(r *http.Request) error {
+	if it.TemplateInstance == nil {
+		it.TemplateInstance = &core.TemplateInstance{}
+	}
+	return nil
+}
+
+func (tr *TemplateResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (at *AllTemplatesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(at.Data) == 0 {
+		at.Data = make([]core.ListTemplate, 0)
+	}
+	return nil
+}
+
+func (ir *InstanceResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// templateCtx loads the template visible to the user, other templates aren't found
+func (h *TemplateHandler) templateCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(w, r)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		templateID, err := strconv.Atoi(chi.URLParam(r, "templateID"))
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		template, err := h.service.GetTemplate(userID, templateID)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		ctx := context.WithValue(r.Context(), templateCtx, template)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// sharedTemplateCtx loads the template by the token of its public link
+func (h *TemplateHandler) sharedTemplateCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template, err := h.service.GetSharedTemplate(chi.URLParam(r, "token"))
+		if err != nil {
+			if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		ctx := context.WithValue(r.Context(), templateCtx, template)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *TemplateHandler) getTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	templates, err := h.service.GetTemplates(userID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllTemplatesResponse{Data: templates}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *TemplateHandler) createTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &CreateTemplateRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	template, err := h.service.CreateTemplate(userID, data.ListID, core.ListTemplate{
+		Title:       data.Title,
+		Description: data.Description,
+		Visibility:  data.Visibility,
+	})
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(w, r, &TemplateResponse{ListTemplate: &template}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *TemplateHandler) getTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := r.Context().Value(templateCtx).(core.ListTemplate)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTemplateNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &TemplateResponse{ListTemplate: &template}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *TemplateHandler) updateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	template, ok := r.Context().Value(templateCtx).(core.ListTemplate)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTemplateNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &UpdateTemplateRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	template, err = h.service.UpdateTemplate(userID, template, *data.UpdateTemplateData)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &TemplateResponse{ListTemplate: &template}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *TemplateHandler) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	template, ok := r.Context().Value(templateCtx).(core.ListTemplate)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTemplateNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.DeleteTemplate(userID, template); err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// shareTemplate responds with the token of the new public link, it isn't
+// shown again
+func (h *TemplateHandler) shareTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	template, ok := r.Context().Value(templateCtx).(core.ListTemplate)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTemplateNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	template, err = h.service.ShareTemplate(userID, template)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &TemplateResponse{ListTemplate: &template}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *TemplateHandler) unshareTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	template, ok := r.Context().Value(templateCtx).(core.ListTemplate)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTemplateNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.UnshareTemplate(userID, template); err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// instantiate creates the list from the template in the context
+func (h *TemplateHandler) instantiate(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	template, ok := r.Context().Value(templateCtx).(core.ListTemplate)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTemplateNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &InstantiateTemplateRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, err := h.service.Instantiate(userID, template, *data.TemplateInstance)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(w, r, &InstanceResponse{ListWithTodos: &list}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -18,11 +18,13 @@ type ctxKeyTodo string
 const todoCtx ctxKeyTodo = "todo"
 
 type TodoItemService interface {
-	CreateTodo(listID int, todo core.TodoItem) (core.TodoItem, error)
+	CreateTodo(userID, listID int, todo core.TodoItem) (core.TodoItem, error)
 	GetAllTodos(listID int) ([]core.TodoItem, error)
+	GetAssignedTodos(userID int, done *bool) ([]core.AssignedTodo, error)
 	GetTodoByID(listID, todoID int) (core.TodoItem, error)
-	UpdateTodo(todoID int, data core.UpdateItemData) (core.TodoItem, error)
-	DeleteTodo(todoID int) error
+	UpdateTodo(userID, listID, todoID int, data core.UpdateItemData) (core.TodoItem, error)
+	MoveTodo(userID, listID, todoID, statusID int) (core.TodoItem, error)
+	DeleteTodo(userID, listID, todoID int) error
 }
 
 type TodoItemHandler struct {
@@ -38,12 +40,23 @@ type UpdateTodoRequest struct {
 	*core.UpdateItemData
 }
 
+// MoveTodoRequest describes the column of the board to move the todo to
+type MoveTodoRequest struct {
+	StatusID int `json:"status_id"`
+}
+
 type AllTodosResponse struct {
-	Data []core.TodoItem `json:"data"`
+	Data []*TodoResponse `json:"data"`
 }
 
+// TodoResponse is the todo together with number of its comments
 type TodoResponse struct {
 	*core.TodoItem
+	CommentCount int `json:"comment_count"`
+}
+
+type AssignedTodosResponse struct {
+	Data []core.AssignedTodo `json:"data"`
 }
 
 func NewTodoItemHandler(service TodoItemService, log *zap.Logger) *TodoItemHandler {
@@ -58,23 +71,47 @@ func (ct *CreateTodoRequest) Bind(r *http.Request) error {
 }
 
 func (ut *UpdateTodoRequest) Bind(r *http.Request) error {
-	if ut.Title == nil && ut.Description == nil {
-		return errors.New("you should provide one of Title or Description")
+	if ut.Title == nil && ut.Description == nil && ut.Done == nil && ut.Due == nil && ut.AssigneeID == nil &&
+		ut.Priority == nil && ut.Labels == nil && ut.Recurrence == nil && ut.StatusID == nil {
+		return errors.New("you should provide one of Title, Description, Done, Due, AssigneeID, Priority, Labels, Recurrence or StatusID")
 	}
 	return nil
 }
 
-func (at *AllTodosResponse) Render(w http.ResponseWriter, r *http.Request) error {
-	if len(at.Data) == 0 {
-		at.Data = make([]core.TodoItem, 0)
+func (mt *MoveTodoRequest) Bind(r *http.Request) error {
+	if mt.StatusID <= 0 {
+		return errors.New("missing required StatusID field")
 	}
 	return nil
 }
 
+func newTodoResponse(todo core.TodoItem) *TodoResponse {
+	return &TodoResponse{TodoItem: &todo, CommentCount: todo.CommentCount}
+}
+
+func newAllTodosResponse(todos []core.TodoItem) *AllTodosResponse {
+	data := make([]*TodoResponse, 0, len(todos))
+	for _, t := range todos {
+		data = append(data, newTodoResponse(t))
+	}
+	return &AllTodosResponse{Data: data}
+}
+
+func (at *AllTodosResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
 func (ct *TodoResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (at *AssignedTodosResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(at.Data) == 0 {
+		at.Data = make([]core.AssignedTodo, 0)
+	}
+	return nil
+}
+
 func (h *TodoItemHandler) todoCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		list, ok := r.Context().Value(listCtx).(core.Todolist)
@@ -118,12 +155,19 @@ func (h *TodoItemHandler) getAllTodos(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	if err := render.Render(w, r, &AllTodosResponse{Data: todos}); err != nil {
+	if err := render.Render(w, r, newAllTodosResponse(todos)); err != nil {
 		h.log.Error(ErrRenderResp.Error())
 	}
 }
 
 func (h *TodoItemHandler) createTodo(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
 	list, ok := r.Context().Value(listCtx).(core.Todolist)
 	if !ok {
 		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
@@ -138,15 +182,15 @@ func (h *TodoItemHandler) createTodo(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	todo, err := h.service.CreateTodo(list.ID, *data.TodoItem)
+	todo, err := h.service.CreateTodo(userID, list.ID, *data.TodoItem)
 	if err != nil {
-		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
 			h.log.Error(ErrRenderResp.Error())
 		}
 		return
 	}
 	render.Status(r, http.StatusCreated)
-	if err := render.Render(w, r, &TodoResponse{TodoItem: &todo}); err != nil {
+	if err := render.Render(w, r, newTodoResponse(todo)); err != nil {
 		h.log.Error(ErrRenderResp.Error())
 	}
 }
@@ -159,12 +203,24 @@ func (h *TodoItemHandler) getTodo(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	if err := render.Render(w, r, &TodoResponse{TodoItem: &todo}); err != nil {
-		h.log.Error(ErrRenderResp.Error())
-	}
+	h.renderTodo(w, r, todo)
 }
 
 func (h *TodoItemHandler) updateTodo(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
 	todo, ok := r.Context().Value(todoCtx).(core.TodoItem)
 	if !ok {
 		if err := render.Render(w, r, ErrInternalServer(ErrTodoNotFound)); err != nil {
@@ -179,19 +235,71 @@ func (h *TodoItemHandler) updateTodo(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	todo, err := h.service.UpdateTodo(todo.ID, *data.UpdateItemData)
+	todo, err = h.service.UpdateTodo(userID, list.ID, todo.ID, *data.UpdateItemData)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	h.renderTodo(w, r, todo)
+}
+
+// moveTodo moves the todo between columns of the list's board
+func (h *TodoItemHandler) moveTodo(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
 	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	todo, ok := r.Context().Value(todoCtx).(core.TodoItem)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrTodoNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &MoveTodoRequest{}
+	if err := render.Bind(r, data); err != nil {
 		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
 			h.log.Error(ErrRenderResp.Error())
 		}
 		return
 	}
-	if err := render.Render(w, r, &TodoResponse{TodoItem: &todo}); err != nil {
-		h.log.Error(ErrRenderResp.Error())
+	todo, err = h.service.MoveTodo(userID, list.ID, todo.ID, data.StatusID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
 	}
+	h.renderTodo(w, r, todo)
 }
 
 func (h *TodoItemHandler) deleteTodo(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	list, ok := r.Context().Value(listCtx).(core.Todolist)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrListNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
 	todo, ok := r.Context().Value(todoCtx).(core.TodoItem)
 	if !ok {
 		if err := render.Render(w, r, ErrInternalServer(ErrTodoNotFound)); err != nil {
@@ -199,12 +307,52 @@ func (h *TodoItemHandler) deleteTodo(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	err := h.service.DeleteTodo(todo.ID)
+	err = h.service.DeleteTodo(userID, list.ID, todo.ID)
 	if err != nil {
-		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
 			h.log.Error(ErrRenderResp.Error())
 		}
 		return
 	}
 	render.NoContent(w, r)
 }
+
+// renderTodo responds with the todo together with number of its comments
+func (h *TodoItemHandler) renderTodo(w http.ResponseWriter, r *http.Request, todo core.TodoItem) {
+	if err := render.Render(w, r, newTodoResponse(todo)); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// getAssignedTodos returns todos assigned to the user in all lists, the done
+// query parameter filters them by completion
+func (h *TodoItemHandler) getAssignedTodos(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	var done *bool
+	if raw := r.URL.Query().Get("done"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(errors.New("done should be a boolean"))); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		done = &value
+	}
+	todos, err := h.service.GetAssignedTodos(userID, done)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AssignedTodosResponse{Data: todos}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+type AccessTokenService interface {
+	CreateAccessToken(userID int, t core.AccessToken) (core.AccessToken, error)
+	GetAccessTokens(userID int) ([]core.AccessToken, error)
+	DeleteAccessToken(userID, tokenID int) error
+}
+
+type AccessTokenHandler struct {
+	service AccessTokenService
+	log     *zap.Logger
+}
+
+type CreateAccessTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type AccessTokenResponse struct {
+	*core.AccessToken
+}
+
+type AllAccessTokensResponse struct {
+	Data []core.AccessToken `json:"data"`
+}
+
+func NewAccessTokenHandler(service AccessTokenService, log *zap.Logger) *AccessTokenHandler {
+	return &AccessTokenHandler{service, log}
+}
+
+func (ct *CreateAccessTokenRequest) Bind(r *http.Request) error {
+	if ct.Name == "" {
+		return errors.New("missing required Name field")
+	}
+	if len(ct.Scopes) == 0 {
+		return errors.New("missing required Scopes field")
+	}
+	return nil
+}
+
+func (tr *AccessTokenResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (at *AllAccessTokensResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(at.Data) == 0 {
+		at.Data = make([]core.AccessToken, 0)
+	}
+	return nil
+}
+
+func (h *AccessTokenHandler) getAllTokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	tokens, err := h.service.GetAccessTokens(userID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllAccessTokensResponse{Data: tokens}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// createToken creates the token, its secret is returned only in this response
+func (h *AccessTokenHandler) createToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &CreateAccessTokenRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	token, err := h.service.CreateAccessToken(userID, core.AccessToken{
+		Name:      data.Name,
+		Scopes:    data.Scopes,
+		ExpiresAt: data.ExpiresAt,
+	})
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(w, r, &AccessTokenResponse{AccessToken: &token}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *AccessTokenHandler) deleteToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	tokenID, err := strconv.Atoi(chi.URLParam(r, "tokenID"))
+	if err != nil {
+		if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.DeleteAccessToken(userID, tokenID); err != nil {
+		if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
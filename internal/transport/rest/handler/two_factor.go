@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+)
+
+type VerifyChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorCodeRequest holds one-time password or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TOTPEnrollmentResponse struct {
+	*core.TOTPEnrollment
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (vc *VerifyChallengeRequest) Bind(r *http.Request) error {
+	if vc.ChallengeToken == "" {
+		return errors.New("missing required ChallengeToken field")
+	}
+	if vc.Code == "" {
+		return errors.New("missing required Code field")
+	}
+	return nil
+}
+
+func (tc *TwoFactorCodeRequest) Bind(r *http.Request) error {
+	if tc.Code == "" {
+		return errors.New("missing required Code field")
+	}
+	return nil
+}
+
+func (te *TOTPEnrollmentResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (rc *RecoveryCodesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// VerifyChallenge finishes the sign in of the user with two-factor authentication
+func (h *AuthHandler) VerifyChallenge(w http.ResponseWriter, r *http.Request) {
+	data := &VerifyChallengeRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	token, err := h.service.VerifyChallenge(data.ChallengeToken, data.Code)
+	if ok, rErr := renderRetryError(w, r, err); ok {
+		if rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err != nil {
+		if rErr := render.Render(w, r, ErrUnauthorized(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &SignInResponse{Token: token}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// enrollTOTP returns the new secret which has to be confirmed by confirmTOTP
+func (h *AuthHandler) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	enrollment, err := h.service.EnrollTOTP(userID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &TOTPEnrollmentResponse{TOTPEnrollment: &enrollment}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *AuthHandler) confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	h.withCode(w, r, h.service.ConfirmTOTP)
+}
+
+func (h *AuthHandler) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	h.withCode(w, r, h.service.RegenerateRecoveryCodes)
+}
+
+func (h *AuthHandler) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &TwoFactorCodeRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.DisableTOTP(userID, data.Code); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// withCode passes the code from the request to fn and responds with recovery codes
+func (h *AuthHandler) withCode(w http.ResponseWriter, r *http.Request, fn func(userID int, code string) ([]string, error)) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &TwoFactorCodeRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	codes, err := fn(userID, data.Code)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &RecoveryCodesResponse{RecoveryCodes: codes}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
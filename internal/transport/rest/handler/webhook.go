@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// Key to use when setting the webhook context.
+type ctxKeyWebhook string
+
+const webhookCtx ctxKeyWebhook = "webhook"
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 100
+)
+
+var ErrWebhookNotFound = errors.New("webhookID not found")
+
+type WebhookService interface {
+	CreateWebhook(userID int, w core.Webhook) (core.Webhook, error)
+	GetAllWebhooks(userID int) ([]core.Webhook, error)
+	GetWebhookByID(userID, webhookID int) (core.Webhook, error)
+	DeleteWebhook(webhookID int) error
+	GetDeliveries(webhookID, limit int) ([]core.WebhookDelivery, error)
+}
+
+type WebhookHandler struct {
+	service WebhookService
+	log     *zap.Logger
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type WebhookResponse struct {
+	*core.Webhook
+}
+
+type AllWebhooksResponse struct {
+	Data []core.Webhook `json:"data"`
+}
+
+type AllDeliveriesResponse struct {
+	Data []core.WebhookDelivery `json:"data"`
+}
+
+func NewWebhookHandler(service WebhookService, log *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{service, log}
+}
+
+func (cw *CreateWebhookRequest) Bind(r *http.Request) error {
+	if cw.URL == "" {
+		return errors.New("missing required URL field")
+	}
+	return nil
+}
+
+func (wr *WebhookResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (aw *AllWebhooksResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(aw.Data) == 0 {
+		aw.Data = make([]core.Webhook, 0)
+	}
+	return nil
+}
+
+func (ad *AllDeliveriesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(ad.Data) == 0 {
+		ad.Data = make([]core.WebhookDelivery, 0)
+	}
+	return nil
+}
+
+func (h *WebhookHandler) webhookCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(w, r)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		webhookID, err := strconv.Atoi(chi.URLParam(r, "webhookID"))
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		webhook, err := h.service.GetWebhookByID(userID, webhookID)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		ctx := context.WithValue(r.Context(), webhookCtx, webhook)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *WebhookHandler) getAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	webhooks, err := h.service.GetAllWebhooks(userID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllWebhooksResponse{Data: webhooks}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *WebhookHandler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &CreateWebhookRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	webhook, err := h.service.CreateWebhook(userID, core.Webhook{URL: data.URL, Events: data.Events})
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(w, r, &WebhookResponse{Webhook: &webhook}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *WebhookHandler) getWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := r.Context().Value(webhookCtx).(core.Webhook)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWebhookNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &WebhookResponse{Webhook: &webhook}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *WebhookHandler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := r.Context().Value(webhookCtx).(core.Webhook)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWebhookNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.DeleteWebhook(webhook.ID); err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (h *WebhookHandler) getDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := r.Context().Value(webhookCtx).(core.Webhook)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWebhookNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	limit, err := getLimit(r, defaultDeliveriesLimit, maxDeliveriesLimit)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	deliveries, err := h.service.GetDeliveries(webhook.ID, limit)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllDeliveriesResponse{Data: deliveries}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// getLimit returns value of the limit query param bounded by max
+func getLimit(r *http.Request, def, max int) (int, error) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("limit should be a positive number")
+	}
+	if limit > max {
+		limit = max
+	}
+	return limit, nil
+}
@@ -0,0 +1,484 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/vbetsun/todo-app/internal/core"
+	"go.uber.org/zap"
+)
+
+// Key to use when setting the workspace context.
+type ctxKeyWorkspace string
+
+const workspaceCtx ctxKeyWorkspace = "workspace"
+
+type WorkspaceService interface {
+	CreateWorkspace(userID int, w core.Workspace) (core.Workspace, error)
+	GetWorkspaces(userID int) ([]core.Workspace, error)
+	GetWorkspace(userID, workspaceID int) (core.Workspace, error)
+	UpdateWorkspace(userID, workspaceID int, data core.UpdateWorkspaceData) (core.Workspace, error)
+	DeleteWorkspace(userID, workspaceID int) error
+	GetMembers(userID, workspaceID int) ([]core.WorkspaceMember, error)
+	UpdateMemberRole(userID, workspaceID, memberID int, role string) error
+	RemoveMember(userID, workspaceID, memberID int) error
+	Invite(userID, workspaceID int, email, role string) (core.WorkspaceInvite, error)
+	GetInvites(userID, workspaceID int) ([]core.WorkspaceInvite, error)
+	RevokeInvite(userID, workspaceID, inviteID int) error
+	AcceptInvite(userID int, token string) (core.Workspace, error)
+}
+
+type WorkspaceHandler struct {
+	service WorkspaceService
+	log     *zap.Logger
+}
+
+type CreateWorkspaceRequest struct {
+	*core.Workspace
+}
+
+type UpdateWorkspaceRequest struct {
+	*core.UpdateWorkspaceData
+}
+
+type InviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type AcceptInviteRequest struct {
+	Token string `json:"token"`
+}
+
+type WorkspaceResponse struct {
+	*core.Workspace
+}
+
+type AllWorkspacesResponse struct {
+	Data []core.Workspace `json:"data"`
+}
+
+type AllMembersResponse struct {
+	Data []core.WorkspaceMember `json:"data"`
+}
+
+type InviteResponse struct {
+	*core.WorkspaceInvite
+}
+
+type AllInvitesResponse struct {
+	Data []core.WorkspaceInvite `json:"data"`
+}
+
+func NewWorkspaceHandler(service WorkspaceService, log *zap.Logger) *WorkspaceHandler {
+	return &WorkspaceHandler{service, log}
+}
+
+func (cw *CreateWorkspaceRequest) Bind(r *http.Request) error {
+	if cw.Workspace == nil || cw.Name == "" {
+		return errors.New("missing required Name field")
+	}
+	return nil
+}
+
+func (uw *UpdateWorkspaceRequest) Bind(r *http.Request) error {
+	if uw.UpdateWorkspaceData == nil || uw.Name == nil {
+		return errors.New("you should provide Name")
+	}
+	return nil
+}
+
+func (ir *InviteRequest) Bind(r *http.Request) error {
+	if ir.Email == "" {
+		return errors.New("missing required Email field")
+	}
+	return nil
+}
+
+func (ar *AcceptInviteRequest) Bind(r *http.Request) error {
+	if ar.Token == "" {
+		return errors.New("missing required Token field")
+	}
+	return nil
+}
+
+func (wr *WorkspaceResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (aw *AllWorkspacesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(aw.Data) == 0 {
+		aw.Data = make([]core.Workspace, 0)
+	}
+	return nil
+}
+
+func (am *AllMembersResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(am.Data) == 0 {
+		am.Data = make([]core.WorkspaceMember, 0)
+	}
+	return nil
+}
+
+func (ir *InviteResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (ai *AllInvitesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if len(ai.Data) == 0 {
+		ai.Data = make([]core.WorkspaceInvite, 0)
+	}
+	return nil
+}
+
+// workspaceCtx loads the workspace with the user's role, workspaces of other
+// users aren't found
+func (h *WorkspaceHandler) workspaceCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(w, r)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		workspaceID, err := strconv.Atoi(chi.URLParam(r, "wsID"))
+		if err != nil {
+			if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		ws, err := h.service.GetWorkspace(userID, workspaceID)
+		if err != nil {
+			if rErr := render.Render(w, r, ErrNotFound); rErr != nil {
+				h.log.Error(ErrRenderResp.Error())
+			}
+			return
+		}
+		ctx := context.WithValue(r.Context(), workspaceCtx, ws)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *WorkspaceHandler) getWorkspaces(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	workspaces, err := h.service.GetWorkspaces(userID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllWorkspacesResponse{Data: workspaces}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *WorkspaceHandler) createWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &CreateWorkspaceRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ws, err := h.service.CreateWorkspace(userID, *data.Workspace)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(w, r, &WorkspaceResponse{Workspace: &ws}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *WorkspaceHandler) getWorkspace(w http.ResponseWriter, r *http.Request) {
+	ws, ok := r.Context().Value(workspaceCtx).(core.Workspace)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWorkspaceNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &WorkspaceResponse{Workspace: &ws}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *WorkspaceHandler) updateWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ws, ok := r.Context().Value(workspaceCtx).(core.Workspace)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWorkspaceNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &UpdateWorkspaceRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ws, err = h.service.UpdateWorkspace(userID, ws.ID, *data.UpdateWorkspaceData)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &WorkspaceResponse{Workspace: &ws}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *WorkspaceHandler) deleteWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ws, ok := r.Context().Value(workspaceCtx).(core.Workspace)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWorkspaceNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.DeleteWorkspace(userID, ws.ID); err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (h *WorkspaceHandler) getMembers(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ws, ok := r.Context().Value(workspaceCtx).(core.Workspace)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWorkspaceNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	members, err := h.service.GetMembers(userID, ws.ID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllMembersResponse{Data: members}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *WorkspaceHandler) updateMember(w http.ResponseWriter, r *http.Request) {
+	data := &SetRoleRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	h.memberAction(w, r, func(userID, workspaceID, memberID int) error {
+		return h.service.UpdateMemberRole(userID, workspaceID, memberID, data.Role)
+	})
+}
+
+func (h *WorkspaceHandler) removeMember(w http.ResponseWriter, r *http.Request) {
+	h.memberAction(w, r, h.service.RemoveMember)
+}
+
+// memberAction applies the action to the member from the URL and responds
+// with no content
+func (h *WorkspaceHandler) memberAction(w http.ResponseWriter, r *http.Request, action func(userID, workspaceID, memberID int) error) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	memberID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ws, ok := r.Context().Value(workspaceCtx).(core.Workspace)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWorkspaceNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := action(userID, ws.ID, memberID); err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (h *WorkspaceHandler) getInvites(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ws, ok := r.Context().Value(workspaceCtx).(core.Workspace)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWorkspaceNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	invites, err := h.service.GetInvites(userID, ws.ID)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInternalServer)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &AllInvitesResponse{Data: invites}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+// createInvite emails the invitation, the token is returned only in this
+// response
+func (h *WorkspaceHandler) createInvite(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ws, ok := r.Context().Value(workspaceCtx).(core.Workspace)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWorkspaceNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &InviteRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	invite, err := h.service.Invite(userID, ws.ID, data.Email, data.Role)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrService(err, ErrInvalidRequest)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(w, r, &InviteResponse{WorkspaceInvite: &invite}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
+
+func (h *WorkspaceHandler) deleteInvite(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	inviteID, err := strconv.Atoi(chi.URLParam(r, "inviteID"))
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ws, ok := r.Context().Value(workspaceCtx).(core.Workspace)
+	if !ok {
+		if err := render.Render(w, r, ErrInternalServer(ErrWorkspaceNotFound)); err != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := h.service.RevokeInvite(userID, ws.ID, inviteID); err != nil {
+		if rErr := render.Render(w, r, ErrService(err, func(error) render.Renderer { return ErrNotFound })); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (h *WorkspaceHandler) acceptInvite(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(w, r)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInternalServer(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	data := &AcceptInviteRequest{}
+	if err := render.Bind(r, data); err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	ws, err := h.service.AcceptInvite(userID, data.Token)
+	if err != nil {
+		if rErr := render.Render(w, r, ErrInvalidRequest(err)); rErr != nil {
+			h.log.Error(ErrRenderResp.Error())
+		}
+		return
+	}
+	if err := render.Render(w, r, &WorkspaceResponse{Workspace: &ws}); err != nil {
+		h.log.Error(ErrRenderResp.Error())
+	}
+}
@@ -3,8 +3,11 @@ package rest
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/vbetsun/todo-app/internal/transport/rest/handler"
 )
 
 // Server represents API server of application
@@ -12,15 +15,20 @@ type Server struct {
 	httpServer *http.Server
 }
 
-// Run creates configuration for the server and starts it
-func (s *Server) Run(port string, handler http.Handler) error {
+// Run creates configuration for the server and starts it. Streams of events
+// aren't limited by the write timeout, they're stopped when the server shuts down
+func (s *Server) Run(port string, h http.Handler) error {
+	ctx, cancel := context.WithCancel(context.Background())
 	s.httpServer = &http.Server{
 		Addr:           ":" + port,
-		Handler:        handler,
+		Handler:        h,
 		MaxHeaderBytes: 1 << 20, // 1 Mb
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
+		BaseContext:    func(net.Listener) context.Context { return ctx },
+		ConnContext:    handler.ConnContext,
 	}
+	s.httpServer.RegisterOnShutdown(cancel)
 	return s.httpServer.ListenAndServe()
 }
 